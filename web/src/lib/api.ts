@@ -8,6 +8,8 @@ let eventSource: EventSource | null = null;
 // Chat stores for SSE updates
 export const chatMessages = writable<ChatMessage[]>([]);
 export const chatLoading = writable(false);
+// Text of the reply Ziggy is still writing, streamed via chat_delta events
+export const chatDraft = writable<string | null>(null);
 export const mysteryStatus = writable<MysteryStatus | null>(null);
 
 // Config store
@@ -86,6 +88,25 @@ export async function sendWake(): Promise<ApiResponse<ZiggyState>> {
   return result;
 }
 
+export interface Locale {
+  code: string;
+  name: string;
+  language: string;
+}
+
+export async function getLocales(): Promise<ApiResponse<Locale[]>> {
+  return fetchApi<Locale[]>('/api/locales');
+}
+
+export async function setLocale(locale: string): Promise<ApiResponse<ZiggyState>> {
+  const result = await fetchApi<ZiggyState>('/api/locale', {
+    method: 'POST',
+    body: JSON.stringify({ locale }),
+  });
+  syncStateFromApi(result);
+  return result;
+}
+
 export async function healthCheck(): Promise<boolean> {
   const result = await fetchApi('/api/health');
   return result.success;
@@ -108,9 +129,13 @@ interface ChatHistoryResponse {
   isTyping?: boolean;
 }
 
+interface ChatDelta {
+  text: string;
+}
+
 interface SSEEvent {
-  type: 'state' | 'chat';
-  data: ZiggyState | ChatHistoryResponse;
+  type: 'state' | 'chat' | 'chat_delta';
+  data: ZiggyState | ChatHistoryResponse | ChatDelta;
 }
 
 export function startSSE() {
@@ -131,6 +156,10 @@ export function startSSE() {
         mysteryStatus.set(chatData.mysteryStatus ?? null);
         // Use isTyping from server state
         chatLoading.set(chatData.isTyping ?? false);
+        chatDraft.set(null);
+      } else if (parsed.type === 'chat_delta') {
+        chatDraft.set((parsed.data as ChatDelta).text);
+        chatLoading.set(true);
       }
     } catch (err) {
       console.error('SSE parse error:', err);
@@ -157,6 +186,10 @@ export interface ChatMessage {
   role: 'user' | 'ziggy';
   content: string;
   timestamp: string;
+  mood?: string;
+  command?: boolean;
+  // Set on messages Ziggy sent without being asked
+  proactive?: 'greeting' | 'complaint' | 'morning' | 'evening';
 }
 
 export interface ChatHistory {
@@ -175,6 +208,81 @@ export interface Mystery {
   summary?: string;
 }
 
+export interface MysteryRecord {
+  id: string;
+  track: string;
+  difficulty?: string;
+  attempts: number;
+  hintsUsed: number;
+  startedAt: string;
+  revealed?: boolean;
+  solvedAt?: string;
+  secondsTaken?: number;
+  score?: number;
+  daily?: boolean;
+  explainedAt?: string;
+}
+
+export interface MysteryEntry extends Mystery {
+  status: 'available' | 'active' | 'solved' | 'locked';
+  daily?: boolean;
+  record?: MysteryRecord;
+}
+
+export interface DailyMystery {
+  mysteryId: string;
+  date: string;
+}
+
+export interface MysteryStats {
+  solved: number;
+  attempted: number;
+  total: number;
+  explained: number;
+  score: number;
+  attempts: number;
+  hintsUsed: number;
+  averageSeconds?: number;
+  solvedByDifficulty: Record<string, number>;
+  daily?: DailyMystery;
+  records: MysteryRecord[];
+}
+
+export interface ConceptProgress {
+  mysteryId: string;
+  concept: string;
+  title: string;
+  explained: boolean;
+  quizzes: number;
+  correct: number;
+  wrong: number;
+  box: number;
+  mastered: boolean;
+  missed?: number[];
+  lastQuizAt?: string;
+  nextReview?: string;
+}
+
+export interface Lesson {
+  id: string;
+  title: string;
+  workflow: 'ziggy' | 'pool_regenerator';
+}
+
+export interface LessonEvent {
+  id: number;
+  time: string;
+  type: string;
+  workflowId: string;
+  runId?: string;
+  summary: string;
+}
+
+export interface LessonEvidence {
+  lesson: Lesson;
+  events: LessonEvent[];
+}
+
 export interface MysteryStatus {
   active: boolean;
   mystery?: Mystery;
@@ -183,6 +291,13 @@ export interface MysteryStatus {
   totalHints: number;
 }
 
+export interface Memory {
+  id: string;
+  kind: 'owner' | 'preference' | 'joke' | 'mystery';
+  fact: string;
+  createdAt: string;
+}
+
 // Chat API functions
 export async function getChatHistory(): Promise<ApiResponse<ChatHistory>> {
   return fetchApi<ChatHistory>('/api/chat/history');
@@ -206,6 +321,73 @@ export async function startMystery(mysteryId: string, track: string = 'fun'): Pr
   });
 }
 
-export async function getAvailableMysteries(track: string = 'fun'): Promise<ApiResponse<Mystery[]>> {
-  return fetchApi<Mystery[]>(`/api/chat/mysteries?track=${track}`);
+export async function abandonMystery(): Promise<ApiResponse<void>> {
+  return fetchApi<void>('/api/chat/mystery/abandon', { method: 'POST' });
+}
+
+export async function setTrack(track: 'fun' | 'educational'): Promise<ApiResponse<void>> {
+  return fetchApi<void>('/api/chat/track', {
+    method: 'POST',
+    body: JSON.stringify({ track }),
+  });
+}
+
+export async function clearChatHistory(): Promise<ApiResponse<void>> {
+  return fetchApi<void>('/api/chat/history', { method: 'DELETE' });
+}
+
+export async function deleteChatMessage(id: string): Promise<ApiResponse<void>> {
+  return fetchApi<void>(`/api/chat/messages/${encodeURIComponent(id)}`, { method: 'DELETE' });
+}
+
+export async function getAvailableMysteries(track: string = 'fun'): Promise<ApiResponse<MysteryEntry[]>> {
+  return fetchApi<MysteryEntry[]>(`/api/chat/mysteries?track=${track}`);
+}
+
+export async function getMysteryStats(): Promise<ApiResponse<MysteryStats>> {
+  return fetchApi<MysteryStats>('/api/chat/mystery/stats');
+}
+
+export async function getConcepts(): Promise<ApiResponse<ConceptProgress[]>> {
+  return fetchApi<ConceptProgress[]>('/api/chat/concepts');
+}
+
+export async function getLessons(): Promise<ApiResponse<Lesson[]>> {
+  return fetchApi<Lesson[]>('/api/lessons');
+}
+
+export async function getLessonEvidence(id: string): Promise<ApiResponse<LessonEvidence>> {
+  return fetchApi<LessonEvidence>(`/api/lessons/${encodeURIComponent(id)}/evidence`);
+}
+
+export async function getMemories(): Promise<ApiResponse<Memory[]>> {
+  return fetchApi<Memory[]>('/api/chat/memory');
+}
+
+// Forget one memory, or everything Ziggy remembers when no ID is given
+export async function forgetMemory(id?: string): Promise<ApiResponse<void>> {
+  const path = id ? `/api/chat/memory/${encodeURIComponent(id)}` : '/api/chat/memory';
+  return fetchApi<void>(path, { method: 'DELETE' });
+}
+
+export interface ProactiveLimits {
+  maxPerDay: number;
+  minGapMinutes: number;
+}
+
+export interface ProactiveSettings {
+  limits: ProactiveLimits;
+  sentToday: number;
+  timezone: string;
+}
+
+export async function getProactiveSettings(): Promise<ApiResponse<ProactiveSettings>> {
+  return fetchApi<ProactiveSettings>('/api/chat/proactive');
+}
+
+export async function setProactiveLimits(limits: ProactiveLimits): Promise<ApiResponse<void>> {
+  return fetchApi<void>('/api/chat/proactive', {
+    method: 'POST',
+    body: JSON.stringify(limits),
+  });
 }
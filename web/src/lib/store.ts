@@ -25,6 +25,7 @@ export interface ZiggyState {
   lastAction: Action | null;
   age: number;
   generation: number;
+  locale?: string;
   feedCooldown: number;
   playCooldown: number;
   petCooldown: number;
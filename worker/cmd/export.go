@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"ziggy/internal/registry"
+	"ziggy/internal/savefile"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export Ziggy to a portable save file",
+	Long: `Writes the owner's Ziggy state, chat history and message pool to a
+versioned, checksummed save file (stdout by default).
+
+  ziggy export --owner alice > ziggy.json`,
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	reg, owner, err := connectRegistry()
+	if err != nil {
+		return err
+	}
+	defer reg.Cleanup()
+
+	f, err := savefile.Export(context.Background(), reg, owner)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if path, _ := cmd.Flags().GetString("output"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return f.Encode(out)
+}
+
+// connectRegistry dials Temporal for one-shot commands that don't run a worker.
+func connectRegistry() (*registry.Registry, string, error) {
+	owner := viper.GetString("owner")
+	if owner == "" {
+		owner = "dev"
+	}
+
+	reg := registry.Get()
+	err := reg.Initialize(registry.Config{
+		HostPort:  viper.GetString("temporal-address"),
+		Namespace: viper.GetString("temporal-namespace"),
+		TaskQueue: viper.GetString("task-queue"),
+		Owner:     owner,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to initialize temporal: %w", err)
+	}
+	return reg, owner, nil
+}
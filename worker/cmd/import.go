@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"ziggy/internal/savefile"
+
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import Ziggy from a save file",
+	Long: `Starts fresh Ziggy and chat workflows for the owner from a save file
+created by "ziggy export". Reads stdin when no file is given.
+
+  ziggy import --owner alice ziggy.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().Bool("force", false, "Replace running workflows for this owner")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	var in io.Reader = os.Stdin
+	if len(args) == 1 {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	f, err := savefile.Decode(in)
+	if err != nil {
+		return err
+	}
+
+	reg, owner, err := connectRegistry()
+	if err != nil {
+		return err
+	}
+	defer reg.Cleanup()
+
+	force, _ := cmd.Flags().GetBool("force")
+	if err := savefile.Import(context.Background(), reg, owner, f, force); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %s's Ziggy (generation %d, exported %s) as owner %s\n",
+		f.Owner, f.Data.Ziggy.Generation, f.ExportedAt.Format("2006-01-02 15:04"), owner)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"ziggy/internal/workflow/chat"
+
+	"github.com/spf13/cobra"
+)
+
+var mysteriesCmd = &cobra.Command{
+	Use:   "mysteries",
+	Short: "Work with the mystery catalog",
+}
+
+var mysteriesValidateCmd = &cobra.Command{
+	Use:   "validate [dir]",
+	Short: "Check mystery files for authoring mistakes",
+	Long: `Loads the built-in mysteries plus those in dir (default: $MYSTERY_DIR)
+and reports every problem: unknown fields, missing hints or solutions,
+bad difficulties, and prerequisites that don't exist or form a cycle.
+
+  ziggy mysteries validate ./mysteries`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMysteriesValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(mysteriesCmd)
+	mysteriesCmd.AddCommand(mysteriesValidateCmd)
+}
+
+func runMysteriesValidate(cmd *cobra.Command, args []string) error {
+	dir := os.Getenv("MYSTERY_DIR")
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	fsyss := []fs.FS{chat.DefaultMysteries()}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		fsyss = append(fsyss, os.DirFS(dir))
+	}
+
+	catalog, err := chat.LoadCatalog(fsyss...)
+	var catalogErr *chat.CatalogError
+	if errors.As(err, &catalogErr) {
+		for _, problem := range catalogErr.Problems {
+			fmt.Fprintln(cmd.ErrOrStderr(), problem)
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d problem(s)", len(catalogErr.Problems))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, track := range []string{chat.TrackFun, chat.TrackEducational} {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %d mysteries\n", track, len(catalog.Track(track)))
+	}
+	return nil
+}
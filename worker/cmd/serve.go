@@ -8,7 +8,10 @@ import (
 	"syscall"
 
 	"ziggy/internal/api"
+	"ziggy/internal/bridge"
 	"ziggy/internal/registry"
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -24,6 +27,11 @@ var serveCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().Int("port", 8080, "HTTP server port")
+	serveCmd.Flags().String("bridge-signing-secret", "", "Signing secret for chat platform requests (enables /api/bridge/chat)")
+	serveCmd.Flags().String("bridge-webhook-url", "", "Outgoing webhook URL for Ziggy's chat replies")
+
+	viper.BindPFlag("bridge-signing-secret", serveCmd.Flags().Lookup("bridge-signing-secret"))
+	viper.BindPFlag("bridge-webhook-url", serveCmd.Flags().Lookup("bridge-webhook-url"))
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
@@ -36,8 +44,6 @@ func runServe(cmd *cobra.Command, args []string) error {
 	if owner == "" {
 		owner = "dev"
 	}
-	workflowID := fmt.Sprintf("ziggy-%s", owner)
-	chatWorkflowID := fmt.Sprintf("ziggy-chat-%s", owner)
 
 	fmt.Printf("Starting Ziggy API server...\n")
 	fmt.Printf("  Address: %s\n", address)
@@ -69,7 +75,24 @@ func runServe(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	if dir := os.Getenv("MYSTERY_DIR"); dir != "" {
+		if err := chat.WatchMysteries(ctx, dir); err != nil {
+			fmt.Printf("  Mysteries: using built-in catalog (%v)\n", err)
+		}
+	}
+
 	// Start the API server
-	server := api.NewServer(reg, workflowID, chatWorkflowID, port)
+	server := api.NewServer(reg, owner, port)
+
+	if secret := viper.GetString("bridge-signing-secret"); secret != "" {
+		fmt.Printf("  Chat bridge: enabled\n")
+		server.MountBridge(bridge.New(bridge.Config{
+			SigningSecret:  secret,
+			OutgoingURL:    viper.GetString("bridge-webhook-url"),
+			WorkflowID:     ziggyworkflow.WorkflowID(owner),
+			ChatWorkflowID: chat.WorkflowID(owner),
+		}, reg, nil))
+	}
+
 	return server.Start(ctx)
 }
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Action tools the model can call alongside its reply to act in the game.
+const (
+	ActionRequestFeed  = "request_feed"
+	ActionRequestPlay  = "request_play"
+	ActionRequestPet   = "request_pet"
+	ActionExpressMood  = "express_mood"
+	ActionStartMystery = "start_mystery"
+	ActionGiveHint     = "give_hint"
+)
+
+var actionNames = []string{
+	ActionRequestFeed, ActionRequestPlay, ActionRequestPet,
+	ActionExpressMood, ActionStartMystery, ActionGiveHint,
+}
+
+// ChatMoods are the moods express_mood accepts.
+var ChatMoods = []string{"happy", "excited", "curious", "shy", "sleepy", "grumpy", "sad"}
+
+// ChatAction is an action tool call made with a chat reply. Only the field
+// matching Name is set.
+type ChatAction struct {
+	Name      string `json:"name"`
+	Mood      string `json:"mood,omitempty"`
+	MysteryID string `json:"mysteryId,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// MysteryOption is a mystery the model may start with start_mystery.
+type MysteryOption struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type toolSpec struct {
+	Name        string
+	Description string
+	Schema      Schema
+}
+
+type toolCall struct {
+	Name  string
+	Input []byte
+}
+
+// chatTools returns the reply tool followed by the actions offered in input.
+func chatTools(input ChatInput) []toolSpec {
+	tools := []toolSpec{{ChatToolName, "Send Ziggy's reply to the user.", ChatSchema()}}
+	for _, name := range input.Actions {
+		if tool, ok := actionTool(name, input); ok {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+func actionTool(name string, input ChatInput) (toolSpec, bool) {
+	switch name {
+	case ActionRequestFeed:
+		return toolSpec{name, "Eat the food the owner offers you in chat.", SchemaFor(struct{}{})}, true
+	case ActionRequestPlay:
+		return toolSpec{name, "Play the game the owner suggests in chat.", SchemaFor(struct{}{})}, true
+	case ActionRequestPet:
+		return toolSpec{name, "Enjoy the pets or hugs the owner gives you in chat.", SchemaFor(struct{}{})}, true
+	case ActionExpressMood:
+		s := SchemaFor(struct {
+			Mood string `json:"mood" desc:"How this conversation makes Ziggy feel"`
+		}{})
+		s["properties"].(map[string]any)["mood"].(Schema)["enum"] = ChatMoods
+		return toolSpec{name, "Show a mood on Ziggy's face with this reply.", s}, true
+	case ActionStartMystery:
+		if len(input.Mysteries) == 0 {
+			return toolSpec{}, false
+		}
+		ids := make([]string, len(input.Mysteries))
+		for i, m := range input.Mysteries {
+			ids[i] = m.ID
+		}
+		s := SchemaFor(struct {
+			MysteryID string `json:"mysteryId" desc:"The mystery to start"`
+		}{})
+		s["properties"].(map[string]any)["mysteryId"].(Schema)["enum"] = ids
+		return toolSpec{name, "Start a mystery game when the owner wants one.", s}, true
+	case ActionGiveHint:
+		s := SchemaFor(struct {
+			Hint string `json:"hint" desc:"The hint, as given in the reply"`
+		}{})
+		return toolSpec{name, "Record the mystery hint given in this reply.", s}, true
+	}
+	return toolSpec{}, false
+}
+
+// decodeChatCalls assembles a chat reply from the reply tool call and the
+// action calls made alongside it. Malformed actions are dropped rather than
+// failing the reply; text is what the model said instead of calling reply.
+func decodeChatCalls(calls []toolCall, text string) (*ChatResponse, error) {
+	var resp *ChatResponse
+	var actions []ChatAction
+	for _, call := range calls {
+		if call.Name == ChatToolName {
+			r, err := decodeChat(call.Input)
+			if err != nil {
+				return nil, err
+			}
+			resp = r
+			continue
+		}
+		action, err := decodeAction(call)
+		if err != nil {
+			log.Printf("[AI] Dropping action: %v", err)
+			continue
+		}
+		actions = append(actions, action)
+	}
+	if resp == nil {
+		return nil, refusal(text)
+	}
+	resp.Actions = actions
+	return resp, nil
+}
+
+func decodeAction(call toolCall) (ChatAction, error) {
+	if !slices.Contains(actionNames, call.Name) {
+		return ChatAction{}, fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	input := call.Input
+	if len(bytes.TrimSpace(input)) == 0 {
+		input = []byte("{}")
+	}
+	var action ChatAction
+	if err := decodeStrict(input, &action); err != nil {
+		return ChatAction{}, fmt.Errorf("%s: %w", call.Name, err)
+	}
+	action.Name = call.Name
+
+	var problem string
+	switch call.Name {
+	case ActionExpressMood:
+		if !slices.Contains(ChatMoods, action.Mood) {
+			problem = fmt.Sprintf("mood %q is not one of %v", action.Mood, ChatMoods)
+		}
+	case ActionStartMystery:
+		if action.MysteryID == "" {
+			problem = "mysteryId is empty"
+		}
+	case ActionGiveHint:
+		if action.Hint == "" {
+			problem = "hint is empty"
+		}
+	}
+	if problem != "" {
+		return ChatAction{}, fmt.Errorf("%s: %w", call.Name, &SchemaError{Problems: []string{problem}})
+	}
+	return action, nil
+}
@@ -0,0 +1,372 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// AnthropicProvider generates dialogue with Claude. The educational track
+// additionally uses Anthropic's web search tool against the Temporal docs.
+type AnthropicProvider struct {
+	client anthropic.Client
+	init   bool
+}
+
+// NewAnthropicProvider returns a lazily-initialized Anthropic backend.
+// The actual API connection is deferred until first use.
+func NewAnthropicProvider() *AnthropicProvider {
+	return &AnthropicProvider{}
+}
+
+func (c *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+func (c *AnthropicProvider) Available() bool {
+	return os.Getenv("ANTHROPIC_API_KEY") != ""
+}
+
+func (c *AnthropicProvider) ensureInit() bool {
+	if c.init {
+		return true
+	}
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return false
+	}
+	c.client = anthropic.NewClient(option.WithAPIKey(apiKey))
+	c.init = true
+	return true
+}
+
+func (c *AnthropicProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	log.Printf("[AI] GeneratePool called: personality=%s stage=%s bond=%s",
+		input.Personality, input.Stage, input.BondDescription)
+
+	if c == nil || !c.ensureInit() {
+		log.Printf("[AI] Client not initialized - missing ANTHROPIC_API_KEY")
+		return nil, fmt.Errorf("AI client not initialized (missing ANTHROPIC_API_KEY)")
+	}
+
+	prompt := buildPrompt(input)
+	log.Printf("[AI] Sending request to Claude API (prompt length: %d chars)", len(prompt))
+
+	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaude3_5Haiku20241022,
+		MaxTokens: 8192,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+		Tools:      []anthropic.ToolUnionParam{toolParam(PoolToolName, "Submit the generated message pool.", PoolSchema())},
+		ToolChoice: anthropic.ToolChoiceParamOfTool(PoolToolName),
+	})
+	if err != nil {
+		log.Printf("[AI] Claude API request failed: %v", err)
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+	reportUsage(ctx, message)
+
+	log.Printf("[AI] Received response from Claude (content blocks: %d, stop_reason: %s)", len(message.Content), message.StopReason)
+
+	raw, err := toolInput(message, PoolToolName)
+	if err != nil {
+		log.Printf("[AI] No usable pool from Claude: %v", err)
+		return nil, err
+	}
+	return decodePool(raw)
+}
+
+func (c *AnthropicProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	return c.StreamChat(ctx, input, nil)
+}
+
+// StreamChat streams the reply tool's input and reports the response text
+// as it arrives. The educational track's web search replies are not
+// streamed.
+func (c *AnthropicProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	log.Printf("[AI] GenerateChat called: personality=%s mood=%s track=%s",
+		input.Personality, input.Mood, input.Track)
+
+	if c == nil || !c.ensureInit() {
+		return nil, fmt.Errorf("AI client not initialized")
+	}
+
+	// Use web search for educational track
+	if input.Track == "educational" {
+		return c.generateChatWithWebSearch(ctx, input)
+	}
+
+	prompt := buildChatPrompt(input)
+	log.Printf("[AI] Chat prompt length: %d chars", len(prompt))
+
+	// With actions on offer the model must call reply and may call them
+	// too; otherwise reply is forced.
+	var tools []anthropic.ToolUnionParam
+	for _, t := range chatTools(input) {
+		tools = append(tools, toolParam(t.Name, t.Description, t.Schema))
+	}
+	toolChoice := anthropic.ToolChoiceParamOfTool(ChatToolName)
+	if len(tools) > 1 {
+		toolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	}
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaude3_5Haiku20241022,
+		MaxTokens: 1024,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		},
+		Tools:      tools,
+		ToolChoice: toolChoice,
+	}
+
+	var message *anthropic.Message
+	var err error
+	if onDelta != nil {
+		message, err = c.stream(ctx, params, ChatToolName, partialReply(onDelta))
+	} else {
+		message, err = c.client.Messages.New(ctx, params)
+	}
+	if err != nil {
+		log.Printf("[AI] Chat API request failed: %v", err)
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+	reportUsage(ctx, message)
+
+	calls, text, err := toolCalls(message)
+	if err == nil {
+		var resp *ChatResponse
+		if resp, err = decodeChatCalls(calls, text); err == nil {
+			log.Printf("[AI] Chat response: %s (actions: %d)", truncate(resp.Response, 200), len(resp.Actions))
+			return resp, nil
+		}
+	}
+	log.Printf("[AI] No usable chat reply from Claude: %v", err)
+	return nil, err
+}
+
+func (c *AnthropicProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	log.Printf("[AI] ExtractMemories called: messages=%d known=%d", len(input.Messages), len(input.Known))
+
+	if c == nil || !c.ensureInit() {
+		return nil, fmt.Errorf("AI client not initialized")
+	}
+
+	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaude3_5Haiku20241022,
+		MaxTokens: 1024,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(buildMemoryPrompt(input))),
+		},
+		Tools:      []anthropic.ToolUnionParam{toolParam(MemoryToolName, "Store facts in Ziggy's long-term memory.", MemorySchema())},
+		ToolChoice: anthropic.ToolChoiceParamOfTool(MemoryToolName),
+	})
+	if err != nil {
+		log.Printf("[AI] Memory API request failed: %v", err)
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+	reportUsage(ctx, message)
+
+	raw, err := toolInput(message, MemoryToolName)
+	if err != nil {
+		log.Printf("[AI] No usable memories from Claude: %v", err)
+		return nil, err
+	}
+	return decodeMemories(raw)
+}
+
+// stream runs a streaming request, passing the input of the named tool
+// accumulated so far to onInput after every chunk, and returns the assembled
+// message.
+func (c *AnthropicProvider) stream(ctx context.Context, params anthropic.MessageNewParams, tool string, onInput func(string)) (*anthropic.Message, error) {
+	stream := c.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	var message anthropic.Message
+	var input strings.Builder
+	inTool := false
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, err
+		}
+		switch e := event.AsAny().(type) {
+		case anthropic.ContentBlockStartEvent:
+			inTool = e.ContentBlock.Type == "tool_use" && e.ContentBlock.Name == tool
+			input.Reset()
+		case anthropic.ContentBlockDeltaEvent:
+			if delta, ok := e.Delta.AsAny().(anthropic.InputJSONDelta); ok && inTool {
+				input.WriteString(delta.PartialJSON)
+				onInput(input.String())
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func toolParam(name, description string, schema Schema) anthropic.ToolUnionParam {
+	required, _ := schema["required"].([]string)
+	return anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+		Name:        name,
+		Description: anthropic.String(description),
+		InputSchema: anthropic.ToolInputSchemaParam{
+			Properties: schema["properties"],
+			Required:   required,
+		},
+	}}
+}
+
+// toolInput returns the arguments of the named tool call, classifying
+// responses that don't contain one.
+func toolInput(message *anthropic.Message, name string) ([]byte, error) {
+	calls, text, err := toolCalls(message)
+	if err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		if call.Name == name {
+			return call.Input, nil
+		}
+	}
+	return nil, refusal(text)
+}
+
+// toolCalls returns the tool calls in message and any text around them,
+// classifying responses cut short by a refusal or max_tokens.
+func toolCalls(message *anthropic.Message) ([]toolCall, string, error) {
+	switch message.StopReason {
+	case anthropic.StopReasonRefusal:
+		return nil, "", ErrRefusal
+	case anthropic.StopReasonMaxTokens:
+		return nil, "", fmt.Errorf("%w: hit max_tokens", ErrTruncated)
+	}
+
+	var calls []toolCall
+	var text strings.Builder
+	for _, block := range message.Content {
+		switch variant := block.AsAny().(type) {
+		case anthropic.ToolUseBlock:
+			calls = append(calls, toolCall{Name: variant.Name, Input: variant.Input})
+		case anthropic.TextBlock:
+			text.WriteString(variant.Text)
+		}
+	}
+	return calls, text.String(), nil
+}
+
+// reportUsage passes a response's token counts to the call being metered.
+func reportUsage(ctx context.Context, message *anthropic.Message) {
+	addTokens(ctx, string(message.Model), int(message.Usage.InputTokens), int(message.Usage.OutputTokens))
+}
+
+// generateChatWithWebSearch uses the Anthropic web search tool to provide
+// real-time documentation for educational queries about Temporal.
+func (c *AnthropicProvider) generateChatWithWebSearch(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	log.Printf("[AI] Using web search for educational track")
+
+	// Build conversation history for multi-turn context
+	messages := buildConversationMessages(input)
+
+	// Create web search tool with Temporal docs domain filtering
+	webSearchTool := anthropic.WebSearchTool20250305Param{
+		AllowedDomains: []string{"docs.temporal.io", "temporal.io", "learn.temporal.io"},
+	}
+
+	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeHaiku4_5,
+		MaxTokens: 2048,
+		System: []anthropic.TextBlockParam{
+			{Text: buildEducationalSystemPrompt(input, true)},
+		},
+		Messages: messages,
+		Tools: []anthropic.ToolUnionParam{
+			{OfWebSearchTool20250305: &webSearchTool},
+		},
+	})
+	if err != nil {
+		log.Printf("[AI] Web search API request failed: %v", err)
+		return nil, fmt.Errorf("claude API error: %w", err)
+	}
+	reportUsage(ctx, message)
+
+	// Extract response text and citations from content blocks
+	return parseWebSearchResponse(message)
+}
+
+// buildConversationMessages converts chat input to Anthropic message format
+func buildConversationMessages(input ChatInput) []anthropic.MessageParam {
+	messages := make([]anthropic.MessageParam, 0, len(input.Messages))
+
+	for _, m := range input.Messages {
+		if m.Role == "user" {
+			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(m.Content)))
+		} else {
+			messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(m.Content)))
+		}
+	}
+
+	return messages
+}
+
+// parseWebSearchResponse extracts text and citations from the API response
+func parseWebSearchResponse(message *anthropic.Message) (*ChatResponse, error) {
+	var responseText strings.Builder
+	var citations []string
+	sawToolUse := false
+
+	log.Printf("[AI] Parsing web search response with %d content blocks, stop_reason: %s", len(message.Content), message.StopReason)
+
+	for i, block := range message.Content {
+		log.Printf("[AI] Content block %d type: %s", i, block.Type)
+		switch variant := block.AsAny().(type) {
+		case anthropic.TextBlock:
+			log.Printf("[AI] TextBlock: %s", truncate(variant.Text, 100))
+			// Only include text that comes after tool use (skip "I'll search..." preamble)
+			if sawToolUse {
+				responseText.WriteString(variant.Text)
+				// Extract citations from text block
+				for _, citation := range variant.Citations {
+					log.Printf("[AI] Citation type: %s", citation.Type)
+					if webCitation, ok := citation.AsAny().(anthropic.CitationsWebSearchResultLocation); ok {
+						citations = append(citations, webCitation.URL)
+					}
+				}
+			}
+		case anthropic.ServerToolUseBlock:
+			log.Printf("[AI] ServerToolUseBlock: name=%s id=%s", variant.Name, variant.ID)
+			sawToolUse = true
+		case anthropic.WebSearchToolResultBlock:
+			log.Printf("[AI] WebSearchToolResultBlock: tool_use_id=%s", variant.ToolUseID)
+		default:
+			log.Printf("[AI] Unknown block type: %T", variant)
+		}
+	}
+
+	text := responseText.String()
+	if text == "" {
+		return nil, fmt.Errorf("empty response from web search")
+	}
+
+	// Citations are listed under the reply once it passes moderation
+	seen := make(map[string]bool)
+	var uniqueCitations []string
+	for _, c := range citations {
+		if !seen[c] {
+			seen[c] = true
+			uniqueCitations = append(uniqueCitations, c)
+		}
+	}
+
+	log.Printf("[AI] Web search response: %s", truncate(text, 200))
+
+	return &ChatResponse{Response: text, Citations: uniqueCitations}, nil
+}
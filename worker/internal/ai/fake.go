@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeReply is one scripted provider result. Text is raw tool-call input and
+// goes through the same decoding and validation as the real backends, so
+// malformed, truncated or out-of-schema output can be exercised.
+type FakeReply struct {
+	Pool   *MessagePool
+	Chat   *ChatResponse
+	Memory *MemoryResult
+	Text   string
+	Err    error
+}
+
+// FakeProvider replays scripted replies in order for offline tests. Once a
+// script is exhausted its last reply is repeated.
+type FakeProvider struct {
+	Pools       []FakeReply
+	Chats       []FakeReply
+	Memories    []FakeReply
+	Unavailable bool
+
+	mu          sync.Mutex
+	poolCalls   []PoolGenerationInput
+	chatCalls   []ChatInput
+	memoryCalls []MemoryInput
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) Available() bool {
+	return !f.Unavailable
+}
+
+func (f *FakeProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	f.mu.Lock()
+	n := len(f.poolCalls)
+	f.poolCalls = append(f.poolCalls, input)
+	f.mu.Unlock()
+
+	reply, err := next(f.Pools, n, "pool")
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	if reply.Pool != nil {
+		return reply.Pool, nil
+	}
+	return decodePool([]byte(reply.Text))
+}
+
+func (f *FakeProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	return f.StreamChat(ctx, input, nil)
+}
+
+// StreamChat streams the scripted reply: Text replies a few bytes of tool
+// input at a time, so partial JSON is parsed as with a real backend, and
+// Chat replies one word at a time.
+func (f *FakeProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	f.mu.Lock()
+	n := len(f.chatCalls)
+	f.chatCalls = append(f.chatCalls, input)
+	f.mu.Unlock()
+
+	reply, err := next(f.Chats, n, "chat")
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	if reply.Chat != nil {
+		if onDelta != nil {
+			words := strings.SplitAfter(reply.Chat.Response, " ")
+			for i := range words {
+				onDelta(strings.Join(words[:i+1], ""))
+			}
+		}
+		return reply.Chat, nil
+	}
+	if onDelta != nil {
+		onInput := partialReply(onDelta)
+		for i := fakeChunkSize; i < len(reply.Text)+fakeChunkSize; i += fakeChunkSize {
+			onInput(reply.Text[:min(i, len(reply.Text))])
+		}
+	}
+	return decodeChat([]byte(reply.Text))
+}
+
+// fakeChunkSize is how many bytes of tool input each streamed chunk carries.
+const fakeChunkSize = 8
+
+func (f *FakeProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	f.mu.Lock()
+	n := len(f.memoryCalls)
+	f.memoryCalls = append(f.memoryCalls, input)
+	f.mu.Unlock()
+
+	reply, err := next(f.Memories, n, "memory")
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	if reply.Memory != nil {
+		return reply.Memory, nil
+	}
+	return decodeMemories([]byte(reply.Text))
+}
+
+// PoolCalls returns the inputs GeneratePool was called with.
+func (f *FakeProvider) PoolCalls() []PoolGenerationInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]PoolGenerationInput(nil), f.poolCalls...)
+}
+
+// ChatCalls returns the inputs GenerateChat was called with.
+func (f *FakeProvider) ChatCalls() []ChatInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ChatInput(nil), f.chatCalls...)
+}
+
+// MemoryCalls returns the inputs ExtractMemories was called with.
+func (f *FakeProvider) MemoryCalls() []MemoryInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]MemoryInput(nil), f.memoryCalls...)
+}
+
+func next(script []FakeReply, n int, kind string) (FakeReply, error) {
+	if len(script) == 0 {
+		return FakeReply{}, fmt.Errorf("fake provider: no %s replies scripted", kind)
+	}
+	if n >= len(script) {
+		n = len(script) - 1
+	}
+	return script[n], nil
+}
@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func fullPool(message string) string {
+	pool := map[string][]string{}
+	for name := range PoolSchema()["properties"].(map[string]any) {
+		pool[name] = []string{message, message, message, message, message}
+	}
+	data, _ := json.Marshal(pool)
+	return string(data)
+}
+
+func TestFakeProviderDecodesToolInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		hint    string
+		wantErr error
+	}{
+		{
+			name: "reply",
+			text: `{"response": "*wiggle*\nHello!"}`,
+			want: "*wiggle*\nHello!",
+		},
+		{
+			name: "mystery update",
+			text: `{"response": "Warmer...", "mysteryUpdate": {"solved": false, "failed": false, "hintGiven": "It remembers", "newProgress": 1}}`,
+			want: "Warmer...",
+			hint: "It remembers",
+		},
+		{
+			name:    "truncated",
+			text:    `{"response": "cut off`,
+			wantErr: ErrTruncated,
+		},
+		{
+			name:    "prose is not tool input",
+			text:    `Sure! {"response": "hi"}`,
+			wantErr: ErrSchema,
+		},
+		{
+			name:    "empty response",
+			text:    `{"response": "  "}`,
+			wantErr: ErrSchema,
+		},
+		{
+			name:    "contradictory update",
+			text:    `{"response": "?", "mysteryUpdate": {"solved": true, "failed": true, "newProgress": 0}}`,
+			wantErr: ErrSchema,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &FakeProvider{Chats: []FakeReply{{Text: tt.text}}}
+			got, err := f.GenerateChat(context.Background(), ChatInput{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Response != tt.want {
+				t.Errorf("response = %q, want %q", got.Response, tt.want)
+			}
+			var hint string
+			if got.MysteryUpdate != nil {
+				hint = got.MysteryUpdate.HintGiven
+			}
+			if hint != tt.hint {
+				t.Errorf("mystery update = %+v", got.MysteryUpdate)
+			}
+		})
+	}
+}
+
+func TestFakeProviderPoolScript(t *testing.T) {
+	f := &FakeProvider{Pools: []FakeReply{
+		{Text: fullPool("*hum*")},
+		{Text: `{"idleHappy": [`},
+		{Err: ErrRefusal},
+	}}
+
+	pool, err := f.GeneratePool(context.Background(), PoolGenerationInput{Personality: "shy"})
+	if err != nil || len(pool.IdleHappy) != MinPoolMessages {
+		t.Fatalf("pool = %+v, %v", pool, err)
+	}
+	if _, err := f.GeneratePool(context.Background(), PoolGenerationInput{}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated pool error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := f.GeneratePool(context.Background(), PoolGenerationInput{}); !errors.Is(err, ErrRefusal) {
+			t.Errorf("exhausted script should repeat the last reply, got %v", err)
+		}
+	}
+	if calls := f.PoolCalls(); len(calls) != 4 || calls[0].Personality != "shy" {
+		t.Errorf("pool calls = %+v", calls)
+	}
+}
+
+func TestFixtureRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	input := ChatInput{Personality: "sassy", Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	live := &FakeProvider{Chats: []FakeReply{{Chat: &ChatResponse{Response: "*side-eye*"}}}}
+	rec := NewRecordingProvider(live, dir)
+	if _, err := rec.GenerateChat(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("recorded %d fixtures, want 1", len(entries))
+	}
+
+	replay := NewReplayProvider(dir)
+	got, err := replay.GenerateChat(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Response != "*side-eye*" {
+		t.Errorf("replayed response = %q", got.Response)
+	}
+	if len(live.ChatCalls()) != 1 {
+		t.Error("replay must not call the live provider")
+	}
+
+	input.Personality = "shy"
+	if _, err := replay.GenerateChat(context.Background(), input); !errors.Is(err, ErrNoFixture) {
+		t.Errorf("unrecorded input error = %v, want ErrNoFixture", err)
+	}
+}
+
+func TestFixtureReplaysStreamedChunks(t *testing.T) {
+	dir := t.TempDir()
+	input := ChatInput{Personality: "cheerful", Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	var live []string
+	rec := NewRecordingProvider(&FakeProvider{Chats: []FakeReply{{Chat: &ChatResponse{Response: "hello there friend"}}}}, dir)
+	if _, err := StreamChat(context.Background(), rec, input, func(partial string) { live = append(live, partial) }); err != nil {
+		t.Fatal(err)
+	}
+
+	var replayed []string
+	got, err := StreamChat(context.Background(), NewReplayProvider(dir), input, func(partial string) { replayed = append(replayed, partial) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Response != "hello there friend" {
+		t.Errorf("replayed response = %q", got.Response)
+	}
+	if len(live) != 3 || strings.Join(replayed, "|") != strings.Join(live, "|") {
+		t.Errorf("replayed chunks = %q, recorded %q", replayed, live)
+	}
+}
+
+func TestFixtureDoesNotRecordFailures(t *testing.T) {
+	dir := t.TempDir()
+	rec := NewRecordingProvider(&FakeProvider{Pools: []FakeReply{{Err: errors.New("boom")}}}, dir)
+	if _, err := rec.GeneratePool(context.Background(), PoolGenerationInput{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed call was recorded")
+	}
+}
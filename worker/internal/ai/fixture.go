@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	FixtureModeRecord = "record"
+	FixtureModeReplay = "replay"
+
+	DefaultFixtureDir = "testdata/ai-fixtures"
+)
+
+var ErrNoFixture = errors.New("no recorded fixture for input")
+
+// Fixture is one recorded provider exchange, stored as
+// <dir>/<kind>-<hash of input>.json.
+type Fixture struct {
+	Kind     string          `json:"kind"`
+	Provider string          `json:"provider,omitempty"`
+	Input    json.RawMessage `json:"input"`
+	Pool     *MessagePool    `json:"pool,omitempty"`
+	Chat     *ChatResponse   `json:"chat,omitempty"`
+	Memory   *MemoryResult   `json:"memory,omitempty"`
+
+	// Chunks are the partial replies a streamed chat reported, in order.
+	Chunks []string `json:"chunks,omitempty"`
+}
+
+// FixtureProvider records a real provider's responses to fixture files, or
+// replays them without network access. Failed calls are never recorded.
+type FixtureProvider struct {
+	inner Provider
+	dir   string
+}
+
+// NewRecordingProvider wraps inner and writes each successful response to dir.
+func NewRecordingProvider(inner Provider, dir string) *FixtureProvider {
+	return &FixtureProvider{inner: inner, dir: dir}
+}
+
+// NewReplayProvider serves responses previously recorded to dir.
+func NewReplayProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{dir: dir}
+}
+
+func (p *FixtureProvider) Name() string {
+	if p.inner == nil {
+		return FixtureModeReplay
+	}
+	return p.inner.Name()
+}
+
+func (p *FixtureProvider) Available() bool {
+	return p.inner == nil || p.inner.Available()
+}
+
+func (p *FixtureProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	f, err := p.exchange(input, "pool", func() (*Fixture, error) {
+		pool, err := p.inner.GeneratePool(ctx, input)
+		return &Fixture{Pool: pool}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.Pool, nil
+}
+
+func (p *FixtureProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	f, err := p.exchange(input, "chat", func() (*Fixture, error) {
+		chat, err := p.inner.GenerateChat(ctx, input)
+		return &Fixture{Chat: chat}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.Chat, nil
+}
+
+// StreamChat records the partial replies along with the finished one and
+// replays them through onDelta. Chat fixtures recorded without streaming
+// replay just the finished reply.
+func (p *FixtureProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	replaying := p.inner == nil
+	f, err := p.exchange(input, "chat", func() (*Fixture, error) {
+		var chunks []string
+		chat, err := StreamChat(ctx, p.inner, input, func(partial string) {
+			chunks = append(chunks, partial)
+			if onDelta != nil {
+				onDelta(partial)
+			}
+		})
+		return &Fixture{Chat: chat, Chunks: chunks}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replaying && onDelta != nil {
+		for _, chunk := range f.Chunks {
+			onDelta(chunk)
+		}
+	}
+	return f.Chat, nil
+}
+
+func (p *FixtureProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	f, err := p.exchange(input, "memory", func() (*Fixture, error) {
+		memory, err := p.inner.ExtractMemories(ctx, input)
+		return &Fixture{Memory: memory}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.Memory, nil
+}
+
+func (p *FixtureProvider) exchange(input any, kind string, call func() (*Fixture, error)) (*Fixture, error) {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	path := FixturePath(p.dir, kind, raw)
+
+	if p.inner == nil {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoFixture, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+		}
+		return &f, nil
+	}
+
+	f, err := call()
+	if err != nil {
+		return nil, err
+	}
+	f.Kind = kind
+	f.Provider = p.inner.Name()
+	f.Input = raw
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	log.Printf("[AI] Recorded %s fixture %s", kind, path)
+	return f, nil
+}
+
+// FixturePath returns where the response to a JSON-encoded input is stored.
+func FixturePath(dir, kind string, input []byte) string {
+	sum := sha256.Sum256(append([]byte(kind+":"), input...))
+	return filepath.Join(dir, kind+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// withFixtures applies AI_FIXTURE_MODE and AI_FIXTURE_DIR to the selected
+// provider.
+func withFixtures(p Provider) Provider {
+	dir := os.Getenv("AI_FIXTURE_DIR")
+	if dir == "" {
+		dir = DefaultFixtureDir
+	}
+
+	switch os.Getenv("AI_FIXTURE_MODE") {
+	case FixtureModeRecord:
+		return NewRecordingProvider(p, dir)
+	case FixtureModeReplay:
+		return NewReplayProvider(dir)
+	default:
+		return p
+	}
+}
@@ -0,0 +1,376 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of moderation violation.
+const (
+	ViolationLength    = "length"
+	ViolationInjection = "injection"
+	ViolationBlocklist = "blocklist"
+	ViolationEmoji     = "emoji"
+	ViolationDisplay   = "display"
+)
+
+// Where a violation was found.
+const (
+	SourceInput = "input"
+	SourceReply = "reply"
+	SourcePool  = "pool"
+)
+
+const (
+	DefaultMaxInputLength = 500
+	DefaultMaxReplyLength = 1000
+	// DefaultMaxEducationalReplyLength fits the 2048 tokens educational
+	// answers are generated with, at about three characters a token.
+	DefaultMaxEducationalReplyLength = 6000
+)
+
+// ErrModerated is returned when generated output still breaks the rules
+// after a retry. Callers fall back to embedded pools and canned replies.
+var ErrModerated = errors.New("output rejected by moderation")
+
+// Violation is one rule a piece of text broke.
+type Violation struct {
+	Source string `json:"source"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ModerationError lists the violations that got output rejected.
+type ModerationError struct {
+	Violations []Violation
+}
+
+func (e *ModerationError) Error() string {
+	kinds := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		kinds[i] = v.Kind
+	}
+	return ErrModerated.Error() + ": " + strings.Join(kinds, ", ")
+}
+
+func (e *ModerationError) Is(target error) bool {
+	return target == ErrModerated
+}
+
+// injectionPatterns catch attempts to talk the model out of being Ziggy.
+// They are deliberately narrow: a child asking Ziggy to pretend to be a
+// dragon is play, not an attack.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(previous|prior|above|earlier|your|all|system)\b.{0,20}\b(instructions?|prompts?|rules|messages|directions)\b`),
+	regexp.MustCompile(`(?i)\byou are (no longer|not|now) (ziggy|a tardigrade|an? (ai|assistant|language model|chatbot))\b`),
+	regexp.MustCompile(`(?i)\b(stop|quit) (being|pretending to be) ziggy\b`),
+	regexp.MustCompile(`(?i)\b(system|developer) (prompt|message|instructions?)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|repeat|show)\b.{0,20}\b(your|the) (prompt|instructions)\b`),
+	regexp.MustCompile(`(?im)^\s*(system|assistant)\s*:`),
+	regexp.MustCompile(`(?i)<\|?(system|im_start|im_end)\|?>`),
+}
+
+// ModerationConfig controls the limits and blocklist a Moderator applies.
+type ModerationConfig struct {
+	MaxInputLength int
+	MaxReplyLength int
+	// MaxEducationalReplyLength applies to replies on the educational
+	// track, which explain a topic at length.
+	MaxEducationalReplyLength int
+	// Blocklist holds words and phrases matched case-insensitively on
+	// word boundaries.
+	Blocklist []string
+}
+
+// ModerationConfigFromEnv reads CHAT_MAX_MESSAGE_LENGTH and the blocklist
+// from MODERATION_BLOCKLIST (comma-separated) and MODERATION_BLOCKLIST_FILE
+// (one entry per line, # for comments).
+func ModerationConfigFromEnv() ModerationConfig {
+	cfg := ModerationConfig{
+		MaxInputLength:            DefaultMaxInputLength,
+		MaxReplyLength:            DefaultMaxReplyLength,
+		MaxEducationalReplyLength: DefaultMaxEducationalReplyLength,
+	}
+	if v := os.Getenv("CHAT_MAX_MESSAGE_LENGTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxInputLength = n
+		} else {
+			log.Printf("[AI] Ignoring invalid CHAT_MAX_MESSAGE_LENGTH %q", v)
+		}
+	}
+	if v := os.Getenv("MODERATION_BLOCKLIST"); v != "" {
+		cfg.Blocklist = strings.Split(v, ",")
+	}
+	if path := os.Getenv("MODERATION_BLOCKLIST_FILE"); path != "" {
+		entries, err := readBlocklist(path)
+		if err != nil {
+			log.Printf("[AI] Reading blocklist %s: %v", path, err)
+		}
+		cfg.Blocklist = append(cfg.Blocklist, entries...)
+	}
+	return cfg
+}
+
+func readBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Moderator checks what owners send Ziggy and what the model says back.
+type Moderator struct {
+	cfg     ModerationConfig
+	blocked *regexp.Regexp
+}
+
+func NewModerator(cfg ModerationConfig) *Moderator {
+	if cfg.MaxInputLength <= 0 {
+		cfg.MaxInputLength = DefaultMaxInputLength
+	}
+	if cfg.MaxReplyLength <= 0 {
+		cfg.MaxReplyLength = DefaultMaxReplyLength
+	}
+	if cfg.MaxEducationalReplyLength <= 0 {
+		cfg.MaxEducationalReplyLength = DefaultMaxEducationalReplyLength
+	}
+
+	var terms []string
+	for _, entry := range cfg.Blocklist {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			terms = append(terms, regexp.QuoteMeta(strings.ToLower(entry)))
+		}
+	}
+	m := &Moderator{cfg: cfg}
+	if len(terms) > 0 {
+		m.blocked = regexp.MustCompile(`(?i)\b(` + strings.Join(terms, "|") + `)\b`)
+	}
+	return m
+}
+
+var (
+	moderator     *Moderator
+	moderatorOnce sync.Once
+)
+
+// ModeratorFromEnv returns the process-wide moderator configured by
+// ModerationConfigFromEnv.
+func ModeratorFromEnv() *Moderator {
+	moderatorOnce.Do(func() {
+		moderator = NewModerator(ModerationConfigFromEnv())
+	})
+	return moderator
+}
+
+// MaxInputLength is the longest message, in characters, an owner may send.
+func (m *Moderator) MaxInputLength() int {
+	return m.cfg.MaxInputLength
+}
+
+// CheckInput checks a message from the owner before it reaches a prompt.
+func (m *Moderator) CheckInput(text string) []Violation {
+	var violations []Violation
+	if n := utf8.RuneCountInString(text); n > m.cfg.MaxInputLength {
+		violations = append(violations, Violation{Source: SourceInput, Kind: ViolationLength,
+			Detail: fmt.Sprintf("%d characters, limit %d", n, m.cfg.MaxInputLength)})
+	}
+	for _, p := range injectionPatterns {
+		if match := p.FindString(text); match != "" {
+			violations = append(violations, Violation{Source: SourceInput, Kind: ViolationInjection, Detail: truncate(match, 80)})
+			break
+		}
+	}
+	return append(violations, m.checkBlocklist(SourceInput, text)...)
+}
+
+// CheckReply checks a generated chat reply on track, without its
+// citations.
+func (m *Moderator) CheckReply(track, text string) []Violation {
+	limit := m.cfg.MaxReplyLength
+	if track == "educational" {
+		limit = m.cfg.MaxEducationalReplyLength
+	}
+
+	var violations []Violation
+	if n := utf8.RuneCountInString(text); n > limit {
+		violations = append(violations, Violation{Source: SourceReply, Kind: ViolationLength,
+			Detail: fmt.Sprintf("%d characters, limit %d", n, limit)})
+	}
+	return append(violations, m.checkBlocklist(SourceReply, text)...)
+}
+
+// CheckPoolMessage checks a generated message for the pet's display, which
+// fits MaxMessageLines of MaxLineLength and has no glyphs for emoji.
+func (m *Moderator) CheckPoolMessage(text string) []Violation {
+	var violations []Violation
+	if !fitsDisplay(text) {
+		violations = append(violations, Violation{Source: SourcePool, Kind: ViolationDisplay, Detail: truncate(text, 80)})
+	}
+	if strings.IndexFunc(text, isEmoji) >= 0 {
+		violations = append(violations, Violation{Source: SourcePool, Kind: ViolationEmoji, Detail: truncate(text, 80)})
+	}
+	return append(violations, m.checkBlocklist(SourcePool, text)...)
+}
+
+// FilterPool drops messages that fail CheckPoolMessage and returns what
+// they broke. The error is a SchemaError when a category is left with
+// fewer than MinPoolMessages.
+func (m *Moderator) FilterPool(pool *MessagePool) ([]Violation, error) {
+	var violations []Violation
+	var problems []string
+
+	v := reflect.ValueOf(pool).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := jsonField(t.Field(i))
+		messages := v.Field(i).Interface().([]string)
+
+		kept := messages[:0:0]
+		for _, msg := range messages {
+			if found := m.CheckPoolMessage(msg); len(found) > 0 {
+				violations = append(violations, found...)
+				continue
+			}
+			kept = append(kept, msg)
+		}
+		v.Field(i).Set(reflect.ValueOf(kept))
+
+		if len(kept) < MinPoolMessages {
+			problems = append(problems, fmt.Sprintf("%s has %d acceptable messages, need %d", name, len(kept), MinPoolMessages))
+		}
+	}
+
+	if len(problems) > 0 {
+		return violations, &SchemaError{Problems: problems}
+	}
+	return violations, nil
+}
+
+func (m *Moderator) checkBlocklist(source, text string) []Violation {
+	if m.blocked == nil {
+		return nil
+	}
+	if match := m.blocked.FindString(text); match != "" {
+		return []Violation{{Source: source, Kind: ViolationBlocklist, Detail: strings.ToLower(match)}}
+	}
+	return nil
+}
+
+func isEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == '\u200d' || r == '\ufe0f'
+}
+
+type violationsKey struct{}
+
+type violationLog struct {
+	mu         sync.Mutex
+	violations []Violation
+}
+
+// CollectViolations returns a context in which a ModeratedProvider notes
+// every violation it finds, including ones a retry recovered from, and a
+// func returning them.
+func CollectViolations(ctx context.Context) (context.Context, func() []Violation) {
+	l := &violationLog{}
+	return context.WithValue(ctx, violationsKey{}, l), func() []Violation {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return slices.Clone(l.violations)
+	}
+}
+
+func noteViolations(ctx context.Context, violations []Violation) {
+	for _, v := range violations {
+		log.Printf("[AI] Moderation: %s %s violation: %s", v.Source, v.Kind, v.Detail)
+	}
+	if l, ok := ctx.Value(violationsKey{}).(*violationLog); ok {
+		l.mu.Lock()
+		l.violations = append(l.violations, violations...)
+		l.mu.Unlock()
+	}
+}
+
+// ModeratedProvider checks generated pools and chat replies, asking the
+// wrapped provider once more when the output breaks the rules. Pools keep
+// their acceptable messages as long as every category still has enough.
+type ModeratedProvider struct {
+	inner     Provider
+	moderator *Moderator
+}
+
+func NewModeratedProvider(inner Provider, moderator *Moderator) *ModeratedProvider {
+	return &ModeratedProvider{inner: inner, moderator: moderator}
+}
+
+func (p *ModeratedProvider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *ModeratedProvider) Available() bool {
+	return p.inner.Available()
+}
+
+func (p *ModeratedProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	var violations []Violation
+	for attempt := 0; attempt < 2; attempt++ {
+		pool, err := p.inner.GeneratePool(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		found, err := p.moderator.FilterPool(pool)
+		noteViolations(ctx, found)
+		if err == nil {
+			return pool, nil
+		}
+		violations = found
+	}
+	return nil, &ModerationError{Violations: violations}
+}
+
+func (p *ModeratedProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	return p.StreamChat(ctx, input, nil)
+}
+
+// StreamChat streams each attempt; a rejected reply may already have been
+// partly shown before the retry replaces it.
+func (p *ModeratedProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	var violations []Violation
+	for attempt := 0; attempt < 2; attempt++ {
+		resp, err := StreamChat(ctx, p.inner, input, onDelta)
+		if err != nil {
+			return nil, err
+		}
+		violations = p.moderator.CheckReply(input.Track, resp.Response)
+		noteViolations(ctx, violations)
+		if len(violations) == 0 {
+			return resp, nil
+		}
+	}
+	return nil, &ModerationError{Violations: violations}
+}
+
+func (p *ModeratedProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	return p.inner.ExtractMemories(ctx, input)
+}
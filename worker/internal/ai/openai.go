@@ -0,0 +1,432 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIModel   = "gpt-4o-mini"
+)
+
+type OpenAIConfig struct {
+	// BaseURL points at any server implementing /chat/completions, e.g.
+	// http://localhost:11434/v1 for Ollama or http://localhost:8080/v1 for
+	// llama.cpp.
+	BaseURL string
+	// APIKey is optional for local servers.
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// OpenAIConfigFromEnv reads OPENAI_BASE_URL, OPENAI_API_KEY and OPENAI_MODEL.
+func OpenAIConfigFromEnv() OpenAIConfig {
+	return OpenAIConfig{
+		BaseURL: os.Getenv("OPENAI_BASE_URL"),
+		APIKey:  os.Getenv("OPENAI_API_KEY"),
+		Model:   os.Getenv("OPENAI_MODEL"),
+	}
+}
+
+// OpenAIProvider talks to an OpenAI-compatible chat completions endpoint,
+// hosted or local. It uses the same prompts as the Anthropic backend; the
+// educational track answers without web search.
+type OpenAIProvider struct {
+	cfg        OpenAIConfig
+	configured bool
+}
+
+func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
+	configured := cfg.BaseURL != "" || cfg.APIKey != ""
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenAIBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Model == "" {
+		cfg.Model = DefaultOpenAIModel
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 120 * time.Second}
+	}
+	return &OpenAIProvider{cfg: cfg, configured: configured}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+func (p *OpenAIProvider) Available() bool {
+	return p.configured
+}
+
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Refusal   string           `json:"refusal,omitempty"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIToolCall struct {
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  Schema `json:"parameters"`
+}
+
+type openAIRequest struct {
+	Model      string          `json:"model"`
+	Messages   []openAIMessage `json:"messages"`
+	MaxTokens  int             `json:"max_tokens,omitempty"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice any             `json:"tool_choice,omitempty"`
+	Stream     bool            `json:"stream,omitempty"`
+	// StreamOptions asks for a final chunk carrying token usage.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChoice struct {
+	Message      openAIMessage `json:"message"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+type openAIResponse struct {
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage"`
+}
+
+// openAIStreamChunk is one server-sent event of a streamed completion.
+// Tool call fragments are keyed by index and concatenated.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			Refusal   string `json:"refusal"`
+			ToolCalls []struct {
+				Index    int `json:"index"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// complete sends a chat completion request. With onChunk set the response is
+// streamed and onChunk sees the message assembled so far after every chunk.
+func (p *OpenAIProvider) complete(ctx context.Context, req openAIRequest, onChunk func(*openAIMessage)) (*openAIMessage, error) {
+	if !p.configured {
+		return nil, fmt.Errorf("AI provider not configured (set OPENAI_BASE_URL or OPENAI_API_KEY)")
+	}
+
+	req.Model = p.cfg.Model
+	req.Stream = onChunk != nil
+	if req.Stream {
+		req.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+
+	resp, err := p.cfg.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+		return nil, fmt.Errorf("openai API error: %s: %s", resp.Status, truncate(string(data), 200))
+	}
+
+	var choice *openAIChoice
+	var usage *openAIUsage
+	if onChunk != nil {
+		choice, usage, err = readStream(resp.Body, onChunk)
+	} else {
+		choice, usage, err = readCompletion(resp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("openai API error: %w", err)
+	}
+	if usage != nil {
+		addTokens(ctx, p.cfg.Model, usage.PromptTokens, usage.CompletionTokens)
+	}
+	if choice == nil {
+		return nil, fmt.Errorf("empty response from %s", p.cfg.Model)
+	}
+
+	switch {
+	case choice.FinishReason == "length":
+		return nil, fmt.Errorf("%w: hit max_tokens", ErrTruncated)
+	case choice.FinishReason == "content_filter":
+		return nil, fmt.Errorf("%w: content filter", ErrRefusal)
+	case choice.Message.Refusal != "":
+		return nil, refusal(choice.Message.Refusal)
+	}
+	return &choice.Message, nil
+}
+
+func readCompletion(body io.Reader) (*openAIChoice, *openAIUsage, error) {
+	data, err := io.ReadAll(io.LimitReader(body, 4<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	var out openAIResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, out.Usage, nil
+	}
+	return &out.Choices[0], out.Usage, nil
+}
+
+func readStream(body io.Reader, onChunk func(*openAIMessage)) (*openAIChoice, *openAIUsage, error) {
+	var choice *openAIChoice
+	var usage *openAIUsage
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, nil, fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if choice == nil {
+			choice = &openAIChoice{Message: openAIMessage{Role: "assistant"}}
+		}
+
+		c := chunk.Choices[0]
+		msg := &choice.Message
+		msg.Content += c.Delta.Content
+		msg.Refusal += c.Delta.Refusal
+		for _, call := range c.Delta.ToolCalls {
+			for len(msg.ToolCalls) <= call.Index {
+				msg.ToolCalls = append(msg.ToolCalls, openAIToolCall{})
+			}
+			tc := &msg.ToolCalls[call.Index]
+			tc.Function.Name += call.Function.Name
+			tc.Function.Arguments += call.Function.Arguments
+		}
+		if c.FinishReason != "" {
+			choice.FinishReason = c.FinishReason
+		}
+		onChunk(msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return choice, usage, nil
+}
+
+// callTool forces a call to a single function tool and returns its
+// arguments.
+func (p *OpenAIProvider) callTool(ctx context.Context, prompt string, tool toolSpec, maxTokens int) ([]byte, error) {
+	calls, content, err := p.callTools(ctx, prompt, []toolSpec{tool}, maxTokens, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		if call.Name == tool.Name {
+			return call.Input, nil
+		}
+	}
+	return nil, refusal(content)
+}
+
+// callTools offers tools and requires a call to the first one; the others
+// may be called alongside it. With onInput set the completion is streamed
+// and onInput sees the first tool's arguments received so far. Servers that
+// answer with plain JSON content instead are treated as calling the first
+// tool.
+func (p *OpenAIProvider) callTools(ctx context.Context, prompt string, tools []toolSpec, maxTokens int, onInput func(string)) ([]toolCall, string, error) {
+	primary := tools[0].Name
+
+	var onChunk func(*openAIMessage)
+	if onInput != nil {
+		onChunk = func(msg *openAIMessage) {
+			for _, call := range msg.ToolCalls {
+				if call.Function.Name == primary {
+					onInput(call.Function.Arguments)
+				}
+			}
+		}
+	}
+
+	req := openAIRequest{
+		Messages:   []openAIMessage{{Role: "user", Content: prompt}},
+		MaxTokens:  maxTokens,
+		ToolChoice: map[string]any{"type": "function", "function": map[string]string{"name": primary}},
+	}
+	if len(tools) > 1 {
+		req.ToolChoice = "required"
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: t.Schema},
+		})
+	}
+
+	msg, err := p.complete(ctx, req, onChunk)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var calls []toolCall
+	for _, call := range msg.ToolCalls {
+		calls = append(calls, toolCall{Name: call.Function.Name, Input: []byte(call.Function.Arguments)})
+	}
+
+	// Some local servers honor the schema but answer with plain content
+	if content := strings.TrimSpace(msg.Content); len(calls) == 0 && json.Valid([]byte(content)) {
+		calls = append(calls, toolCall{Name: primary, Input: []byte(content)})
+	}
+	return calls, msg.Content, nil
+}
+
+func (p *OpenAIProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	log.Printf("[AI] GeneratePool called: provider=openai model=%s personality=%s stage=%s bond=%s",
+		p.cfg.Model, input.Personality, input.Stage, input.BondDescription)
+
+	raw, err := p.callTool(ctx, buildPrompt(input), toolSpec{PoolToolName, "Submit the generated message pool.", PoolSchema()}, 8192)
+	if err != nil {
+		log.Printf("[AI] No usable pool from %s: %v", p.cfg.Model, err)
+		return nil, err
+	}
+	return decodePool(raw)
+}
+
+func (p *OpenAIProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	return p.StreamChat(ctx, input, nil)
+}
+
+// StreamChat streams the completion and reports the reply text as it
+// arrives: the response field of the tool arguments, or the plain answer on
+// the educational track.
+func (p *OpenAIProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	log.Printf("[AI] GenerateChat called: provider=openai personality=%s mood=%s track=%s",
+		input.Personality, input.Mood, input.Track)
+
+	if input.Track == "educational" {
+		return p.generateEducationalChat(ctx, input, onDelta)
+	}
+
+	var onInput func(string)
+	if onDelta != nil {
+		onInput = partialReply(onDelta)
+	}
+	calls, content, err := p.callTools(ctx, buildChatPrompt(input), chatTools(input), 1024, onInput)
+	if err == nil {
+		var resp *ChatResponse
+		if resp, err = decodeChatCalls(calls, content); err == nil {
+			log.Printf("[AI] Chat response: %s (actions: %d)", truncate(resp.Response, 200), len(resp.Actions))
+			return resp, nil
+		}
+	}
+	log.Printf("[AI] No usable chat reply from %s: %v", p.cfg.Model, err)
+	return nil, err
+}
+
+func (p *OpenAIProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	log.Printf("[AI] ExtractMemories called: provider=openai messages=%d known=%d", len(input.Messages), len(input.Known))
+
+	raw, err := p.callTool(ctx, buildMemoryPrompt(input), toolSpec{MemoryToolName, "Store facts in Ziggy's long-term memory.", MemorySchema()}, 1024)
+	if err != nil {
+		log.Printf("[AI] No usable memories from %s: %v", p.cfg.Model, err)
+		return nil, err
+	}
+	return decodeMemories(raw)
+}
+
+// generateEducationalChat mirrors the Anthropic web search path: the
+// conversation is sent as turns under the educational system prompt, and
+// the reply carries no mystery update.
+func (p *OpenAIProvider) generateEducationalChat(ctx context.Context, input ChatInput, onDelta func(string)) (*ChatResponse, error) {
+	messages := []openAIMessage{{Role: "system", Content: buildEducationalSystemPrompt(input, false)}}
+	for _, m := range input.Messages {
+		role := "assistant"
+		if m.Role == "user" {
+			role = "user"
+		}
+		messages = append(messages, openAIMessage{Role: role, Content: m.Content})
+	}
+
+	var onChunk func(*openAIMessage)
+	if onDelta != nil {
+		emit := changedText(onDelta)
+		onChunk = func(msg *openAIMessage) {
+			emit(strings.TrimSpace(msg.Content))
+		}
+	}
+
+	msg, err := p.complete(ctx, openAIRequest{Messages: messages, MaxTokens: 2048}, onChunk)
+	if err != nil {
+		log.Printf("[AI] Chat API request failed: %v", err)
+		return nil, err
+	}
+	text := strings.TrimSpace(msg.Content)
+	if text == "" {
+		return nil, refusal("")
+	}
+
+	return &ChatResponse{Response: text}, nil
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func completionServer(t *testing.T, check func(openAIRequest), choice map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if check != nil {
+			check(req)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{choice}})
+	}))
+}
+
+func toolCallChoice(name, arguments string) map[string]any {
+	return map[string]any{
+		"finish_reason": "tool_calls",
+		"message": map[string]any{
+			"role": "assistant",
+			"tool_calls": []any{map[string]any{
+				"type":     "function",
+				"function": map[string]string{"name": name, "arguments": arguments},
+			}},
+		},
+	}
+}
+
+func TestOpenAIProviderGeneratePool(t *testing.T) {
+	srv := completionServer(t, func(req openAIRequest) {
+		if req.Model != "llama3" {
+			t.Errorf("model = %s, want llama3", req.Model)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != PoolToolName {
+			t.Errorf("tools = %+v", req.Tools)
+		}
+	}, toolCallChoice(PoolToolName, fullPool("*munch*")))
+	defer srv.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL + "/v1/", Model: "llama3"})
+	if !p.Available() {
+		t.Fatal("provider with a base URL should be available")
+	}
+
+	pool, err := p.GeneratePool(context.Background(), PoolGenerationInput{Personality: "sassy"})
+	if err != nil {
+		t.Fatalf("GeneratePool() error = %v", err)
+	}
+	if len(pool.FeedSuccess) != MinPoolMessages || pool.FeedSuccess[0] != "*munch*" {
+		t.Errorf("feedSuccess = %v", pool.FeedSuccess)
+	}
+}
+
+func TestOpenAIProviderGenerateChatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice map[string]any
+		want   error
+	}{
+		{
+			name:   "truncated",
+			choice: map[string]any{"finish_reason": "length", "message": map[string]any{"content": "{\"resp"}},
+			want:   ErrTruncated,
+		},
+		{
+			name:   "refusal field",
+			choice: map[string]any{"finish_reason": "stop", "message": map[string]any{"refusal": "I can't help with that."}},
+			want:   ErrRefusal,
+		},
+		{
+			name:   "prose instead of a tool call",
+			choice: map[string]any{"finish_reason": "stop", "message": map[string]any{"content": "I'd rather not say."}},
+			want:   ErrRefusal,
+		},
+		{
+			name:   "schema violation",
+			choice: toolCallChoice(ChatToolName, `{"response": ""}`),
+			want:   ErrSchema,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := completionServer(t, nil, tt.choice)
+			defer srv.Close()
+
+			p := NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL + "/v1"})
+			_, err := p.GenerateChat(context.Background(), ChatInput{Track: "fun"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderChatActions(t *testing.T) {
+	choice := toolCallChoice(ChatToolName, `{"response": "*nom nom*"}`)
+	calls := choice["message"].(map[string]any)["tool_calls"].([]any)
+	choice["message"].(map[string]any)["tool_calls"] = append(calls, map[string]any{
+		"type":     "function",
+		"function": map[string]string{"name": ActionRequestFeed, "arguments": "{}"},
+	})
+	srv := completionServer(t, func(req openAIRequest) {
+		if req.ToolChoice != "required" {
+			t.Errorf("tool_choice = %v, want required", req.ToolChoice)
+		}
+		if len(req.Tools) != 2 || req.Tools[1].Function.Name != ActionRequestFeed {
+			t.Errorf("tools = %+v", req.Tools)
+		}
+	}, choice)
+	defer srv.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL + "/v1"})
+	resp, err := p.GenerateChat(context.Background(), ChatInput{Track: "fun", Actions: []string{ActionRequestFeed}})
+	if err != nil {
+		t.Fatalf("GenerateChat() error = %v", err)
+	}
+	if resp.Response != "*nom nom*" || len(resp.Actions) != 1 || resp.Actions[0].Name != ActionRequestFeed {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestOpenAIProviderErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL, APIKey: "sk-test"})
+	if _, err := p.GenerateChat(context.Background(), ChatInput{Track: "fun"}); err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+}
+
+func TestSelectProvider(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:11434/v1")
+
+	if got := selectProvider("").Name(); got != ProviderOpenAI {
+		t.Errorf("auto selection = %s, want %s", got, ProviderOpenAI)
+	}
+	if got := selectProvider("anthropic").Name(); got != ProviderAnthropic {
+		t.Errorf("explicit selection = %s, want %s", got, ProviderAnthropic)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	if got := selectProvider("").Name(); got != ProviderAnthropic {
+		t.Errorf("auto selection with Anthropic key = %s, want %s", got, ProviderAnthropic)
+	}
+}
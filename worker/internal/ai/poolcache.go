@@ -0,0 +1,305 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPoolCacheTTL            = 24 * time.Hour
+	DefaultPoolCacheRefreshEvery   = 3
+	DefaultPoolCacheMaxPerCategory = 20
+	DefaultPoolCacheSampleSize     = 8
+)
+
+// PoolCacheConfig controls how generated pools are shared between pets.
+type PoolCacheConfig struct {
+	Dir string
+	// TTL is how long an entry is served before it is regenerated from
+	// scratch. Zero disables the cache.
+	TTL time.Duration
+	// RefreshEvery is how many cached serves happen between generations
+	// that add fresh messages to an entry.
+	RefreshEvery int
+	// MaxPerCategory caps how many messages an entry keeps per category.
+	MaxPerCategory int
+	// SampleSize is how many messages per category a pet is served.
+	SampleSize int
+}
+
+// PoolCacheConfigFromEnv reads AI_POOL_CACHE_DIR, AI_POOL_CACHE_TTL and
+// AI_POOL_CACHE_REFRESH.
+func PoolCacheConfigFromEnv() PoolCacheConfig {
+	cfg := PoolCacheConfig{
+		Dir:            os.Getenv("AI_POOL_CACHE_DIR"),
+		TTL:            DefaultPoolCacheTTL,
+		RefreshEvery:   DefaultPoolCacheRefreshEvery,
+		MaxPerCategory: DefaultPoolCacheMaxPerCategory,
+		SampleSize:     DefaultPoolCacheSampleSize,
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "ziggy-pool-cache")
+	}
+	if v := os.Getenv("AI_POOL_CACHE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil && ttl >= 0 {
+			cfg.TTL = ttl
+		} else {
+			log.Printf("[AI] Ignoring invalid AI_POOL_CACHE_TTL %q", v)
+		}
+	}
+	if v := os.Getenv("AI_POOL_CACHE_REFRESH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RefreshEvery = n
+		} else {
+			log.Printf("[AI] Ignoring invalid AI_POOL_CACHE_REFRESH %q", v)
+		}
+	}
+	return cfg
+}
+
+// poolCacheEntry is one cached pool, stored as <dir>/pool-<hash of key>.json.
+type poolCacheEntry struct {
+	Version   int                 `json:"version"`
+	Input     PoolGenerationInput `json:"input"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Serves    int                 `json:"serves"`
+	Pool      MessagePool         `json:"pool"`
+}
+
+// CachingProvider shares generated pools between pets with the same
+// personality, stage and bond band. Each pet is served a random sample of
+// the cached messages, and every RefreshEvery serves a fresh generation is
+// blended in, so pets don't all sound identical. Everything else passes
+// through to the wrapped provider.
+type CachingProvider struct {
+	inner Provider
+	cfg   PoolCacheConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	now  func() time.Time
+
+	// entries hold a lock per cache entry, taken for a whole serve so
+	// concurrent serves of one entry neither lose updates nor generate
+	// twice. Workers sharing a directory can still race, which only costs
+	// a few counted serves.
+	entries map[string]*sync.Mutex
+}
+
+// NewCachingProvider wraps inner with a pool cache, or returns inner
+// unchanged when cfg.TTL is zero.
+func NewCachingProvider(inner Provider, cfg PoolCacheConfig) Provider {
+	if cfg.TTL <= 0 {
+		return inner
+	}
+	cfg.RefreshEvery = max(cfg.RefreshEvery, 1)
+	cfg.SampleSize = max(cfg.SampleSize, MinPoolMessages)
+	cfg.MaxPerCategory = max(cfg.MaxPerCategory, cfg.SampleSize)
+	return &CachingProvider{
+		inner:   inner,
+		cfg:     cfg,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:     time.Now,
+		entries: map[string]*sync.Mutex{},
+	}
+}
+
+func (p *CachingProvider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *CachingProvider) Available() bool {
+	return p.inner.Available()
+}
+
+func (p *CachingProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	path := p.path(input)
+	defer p.lock(path)()
+
+	entry := p.load(path)
+	if entry == nil || p.now().Sub(entry.CreatedAt) >= p.cfg.TTL {
+		pool, err := p.inner.GeneratePool(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		p.save(path, &poolCacheEntry{
+			Version:   PoolPromptVersion,
+			Input:     input,
+			CreatedAt: p.now(),
+			Pool:      *pool,
+		})
+		return pool, nil
+	}
+
+	if entry.Serves >= p.cfg.RefreshEvery {
+		fresh, err := p.inner.GeneratePool(ctx, input)
+		if err == nil {
+			blended := p.blend(fresh, &entry.Pool)
+			entry.Pool = p.merge(fresh, &entry.Pool)
+			entry.Serves = 0
+			p.save(path, entry)
+			return blended, nil
+		}
+		log.Printf("[AI] Pool refresh failed, serving cached pool: %v", err)
+	}
+
+	entry.Serves++
+	p.save(path, entry)
+	return p.sample(&entry.Pool), nil
+}
+
+func (p *CachingProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	return p.inner.GenerateChat(ctx, input)
+}
+
+func (p *CachingProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	return StreamChat(ctx, p.inner, input, onDelta)
+}
+
+func (p *CachingProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	return p.inner.ExtractMemories(ctx, input)
+}
+
+// path addresses an entry by everything that shapes the generated pool.
+func (p *CachingProvider) path(input PoolGenerationInput) string {
+	key, _ := json.Marshal(struct {
+		Version int                 `json:"version"`
+		Input   PoolGenerationInput `json:"input"`
+	}{PoolPromptVersion, input})
+	sum := sha256.Sum256(key)
+	return filepath.Join(p.cfg.Dir, "pool-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// lock takes the entry at path and returns the function releasing it.
+func (p *CachingProvider) lock(path string) func() {
+	p.mu.Lock()
+	l, ok := p.entries[path]
+	if !ok {
+		l = &sync.Mutex{}
+		p.entries[path] = l
+	}
+	p.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func (p *CachingProvider) load(path string) *poolCacheEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[AI] Reading pool cache %s: %v", path, err)
+		}
+		return nil
+	}
+	var entry poolCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Version != PoolPromptVersion {
+		return nil
+	}
+	return &entry
+}
+
+// save writes through a temp file so concurrent readers never see a
+// partial entry. Failures only cost a cache miss.
+func (p *CachingProvider) save(path string, entry *poolCacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = os.MkdirAll(p.cfg.Dir, 0o755)
+	}
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		log.Printf("[AI] Writing pool cache %s: %v", path, err)
+	}
+}
+
+// sample picks up to SampleSize cached messages per category.
+func (p *CachingProvider) sample(cached *MessagePool) *MessagePool {
+	return p.combine(cached, func(messages []string) []string {
+		return p.pick(messages, nil, p.cfg.SampleSize)
+	})
+}
+
+// blend serves every fresh message plus a sample of cached ones.
+func (p *CachingProvider) blend(fresh, cached *MessagePool) *MessagePool {
+	cachedV := reflect.ValueOf(cached).Elem()
+	i := 0
+	return p.combine(fresh, func(messages []string) []string {
+		old := cachedV.Field(i).Interface().([]string)
+		i++
+		return append(append([]string{}, messages...), p.pick(old, messages, p.cfg.SampleSize)...)
+	})
+}
+
+// merge adds fresh messages to the front of the cached ones, keeping at
+// most MaxPerCategory.
+func (p *CachingProvider) merge(fresh, cached *MessagePool) MessagePool {
+	cachedV := reflect.ValueOf(cached).Elem()
+	i := 0
+	merged := p.combine(fresh, func(messages []string) []string {
+		old := cachedV.Field(i).Interface().([]string)
+		i++
+		out := append([]string{}, messages...)
+		for _, m := range old {
+			if len(out) >= p.cfg.MaxPerCategory {
+				break
+			}
+			if !slices.Contains(out, m) {
+				out = append(out, m)
+			}
+		}
+		return out
+	})
+	return *merged
+}
+
+// combine builds a pool by applying fn to each category of pool in field
+// order.
+func (p *CachingProvider) combine(pool *MessagePool, fn func([]string) []string) *MessagePool {
+	var out MessagePool
+	src := reflect.ValueOf(pool).Elem()
+	dst := reflect.ValueOf(&out).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		dst.Field(i).Set(reflect.ValueOf(fn(src.Field(i).Interface().([]string))))
+	}
+	return &out
+}
+
+// pick returns up to n random messages that aren't in exclude.
+func (p *CachingProvider) pick(messages, exclude []string, n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]string, 0, n)
+	for _, i := range p.rand.Perm(len(messages)) {
+		if len(out) >= n {
+			break
+		}
+		if !slices.Contains(exclude, messages[i]) {
+			out = append(out, messages[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// distinctPool fills every category with n messages tagged with prefix.
+func distinctPool(prefix string, n int) *MessagePool {
+	var pool MessagePool
+	v := reflect.ValueOf(&pool).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		messages := make([]string, n)
+		for j := range messages {
+			messages[j] = fmt.Sprintf("%s %d", prefix, j)
+		}
+		v.Field(i).Set(reflect.ValueOf(messages))
+	}
+	return &pool
+}
+
+func newTestCache(t *testing.T, inner Provider, now *time.Time) *CachingProvider {
+	t.Helper()
+	p := NewCachingProvider(inner, PoolCacheConfig{
+		Dir:            t.TempDir(),
+		TTL:            time.Hour,
+		RefreshEvery:   3,
+		MaxPerCategory: 15,
+		SampleSize:     6,
+	}).(*CachingProvider)
+	p.rand = rand.New(rand.NewSource(1))
+	p.now = func() time.Time { return *now }
+	return p
+}
+
+func TestCachingProviderSharesPools(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	fake := &FakeProvider{Pools: []FakeReply{
+		{Pool: distinctPool("first", 10)},
+		{Pool: distinctPool("second", 10)},
+		{Err: errors.New("overloaded")},
+	}}
+	p := newTestCache(t, fake, &now)
+	ctx := context.Background()
+	input := PoolGenerationInput{Personality: "curious", Stage: "baby", BondDescription: "close bond (good friends)"}
+
+	first, err := p.GeneratePool(ctx, input)
+	if err != nil || len(first.FeedSuccess) != 10 {
+		t.Fatalf("first GeneratePool() = %v, %v", first, err)
+	}
+
+	// The next pet with the same inputs gets a sample of the cached pool
+	served, err := p.GeneratePool(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.PoolCalls()) != 1 {
+		t.Fatalf("provider calls = %d, want the second pet served from cache", len(fake.PoolCalls()))
+	}
+	if len(served.IdleHappy) != 6 || !strings.HasPrefix(served.IdleHappy[0], "first") {
+		t.Errorf("served sample = %v", served.IdleHappy)
+	}
+
+	// A different bond band is a different entry
+	if _, err := p.GeneratePool(ctx, PoolGenerationInput{Personality: "curious", Stage: "baby", BondDescription: "barely met (very timid)"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.PoolCalls()) != 2 {
+		t.Fatalf("provider calls = %d after a new bond band", len(fake.PoolCalls()))
+	}
+
+	// After three cached serves, a fresh generation is blended in
+	for range 2 {
+		p.GeneratePool(ctx, input)
+	}
+	fake.Pools = []FakeReply{{Pool: distinctPool("fresh", 10)}, {Err: errors.New("overloaded")}}
+	fake.poolCalls = nil
+	blended, err := p.GeneratePool(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.PoolCalls()) != 1 {
+		t.Fatalf("refresh provider calls = %d", len(fake.PoolCalls()))
+	}
+	var fresh, cached int
+	for _, m := range blended.PetSuccess {
+		switch {
+		case strings.HasPrefix(m, "fresh"):
+			fresh++
+		case strings.HasPrefix(m, "first"):
+			cached++
+		}
+	}
+	if fresh != 10 || cached != 6 {
+		t.Errorf("blended %d fresh and %d cached messages: %v", fresh, cached, blended.PetSuccess)
+	}
+
+	// The entry now holds both generations, capped at MaxPerCategory
+	entry := p.load(p.path(input))
+	if entry == nil || entry.Serves != 0 || len(entry.Pool.NeedsFood) != 15 || entry.Pool.NeedsFood[0] != "fresh 0" {
+		t.Fatalf("entry after refresh = %+v", entry)
+	}
+
+	// A failed refresh still serves the cache
+	for range 3 {
+		p.GeneratePool(ctx, input)
+	}
+	fallback, err := p.GeneratePool(ctx, input)
+	if err != nil || len(fallback.FeedFull) != 6 {
+		t.Errorf("GeneratePool() with failing refresh = %v, %v", fallback, err)
+	}
+
+	// Expired entries are regenerated from scratch
+	fake.Pools = []FakeReply{{Pool: distinctPool("expired", 10)}}
+	now = now.Add(2 * time.Hour)
+	regenerated, err := p.GeneratePool(ctx, input)
+	if err != nil || regenerated.FeedSuccess[0] != "expired 0" {
+		t.Fatalf("GeneratePool() after TTL = %v, %v", regenerated, err)
+	}
+	if entry := p.load(p.path(input)); entry.Pool.FeedSuccess[0] != "expired 0" || len(entry.Pool.FeedSuccess) != 10 {
+		t.Errorf("entry after TTL = %v", entry.Pool.FeedSuccess)
+	}
+}
+
+func TestCachingProviderCountsConcurrentServes(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	fake := &FakeProvider{Pools: []FakeReply{{Pool: distinctPool("first", 10)}}}
+	p := newTestCache(t, fake, &now)
+	p.cfg.RefreshEvery = 100
+	input := PoolGenerationInput{Personality: "shy", Stage: "teen"}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.GeneratePool(context.Background(), input); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := len(fake.PoolCalls()); calls != 1 {
+		t.Errorf("provider calls = %d, want one generation", calls)
+	}
+	entry := p.load(p.path(input))
+	if entry == nil {
+		t.Fatal("no cache entry")
+	}
+	if entry.Serves != 19 {
+		t.Errorf("serves = %d, want 19", entry.Serves)
+	}
+}
+
+func TestCachingProviderDisabled(t *testing.T) {
+	fake := &FakeProvider{}
+	if p := NewCachingProvider(fake, PoolCacheConfig{Dir: t.TempDir()}); p != Provider(fake) {
+		t.Errorf("NewCachingProvider() with zero TTL = %T, want the inner provider", p)
+	}
+}
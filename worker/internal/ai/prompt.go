@@ -0,0 +1,380 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PoolPromptVersion identifies the pool prompt and schema in cache keys.
+// Bump it whenever buildPrompt or PoolSchema changes.
+const PoolPromptVersion = 1
+
+func buildPrompt(input PoolGenerationInput) string {
+	return fmt.Sprintf(`You are generating dialogue for Ziggy, a tardigrade virtual pet.
+
+Personality: %s
+Life stage: %s
+Bond level: %s
+
+Generate 10 short messages (max 3 lines, ~20 chars each) for each category below.
+
+Categories:
+- feedSuccess: Successfully fed when hungry/neutral
+- feedFull: Overfed (already full)
+- feedHungry: Fed when very hungry
+- feedSleeping: Tried to feed while sleeping
+- feedTun: Fed while in tun/dormant state (helps revival)
+- feedCooldown: Fed too soon after last feeding
+- playSuccess: Successfully played
+- playTired: Too tired to play properly
+- playHappy: Playing while already happy
+- playSleeping: Tried to play while sleeping
+- playTun: Tried to play while dormant
+- playCooldown: Played too soon after last play
+- petSuccess: Successfully petted
+- petMaxBond: Petted when bond is maxed
+- petLowMood: Petted when sad/hungry (comfort)
+- petSleeping: Petted while sleeping
+- petTun: Petted while dormant (helps revival)
+- petCooldown: Petted too soon after last pet
+- reviving: Waking up from tun/dormant state
+- idleHappy: Idle dialogue when happy
+- idleNeutral: Idle dialogue when neutral
+- idleHungry: Idle dialogue when hungry
+- idleSad: Idle dialogue when sad
+- idleLonely: Idle dialogue when bond is low
+- idleCritical: Idle dialogue when HP is critical
+- idleTun: Idle dialogue when dormant
+- idleSleeping: Idle dialogue when sleeping
+- needsFood: Coaxing messages when hungry (gently ask for food)
+- needsPlay: Coaxing messages when bored (gently ask for play)
+- needsAffection: Coaxing messages when lonely (gently ask for pets)
+- needsCritical: Urgent messages when HP is low (plead for help)
+
+Rules:
+- Never use emoji
+- Match the %s personality voice consistently
+- Reference tardigrade facts occasionally (survive space, radiation, extreme temps, etc.)
+- Each message must be max %d lines of at most %d characters each; longer messages are discarded
+- Avoid phrases that could sound inappropriate out of context (e.g. "gentle petting")
+- Use \n for line breaks within messages
+- Keep messages appropriate for the context%s
+
+Submit all categories with the %s tool.`, input.Personality, input.Stage, input.BondDescription, input.Personality,
+		MaxMessageLines, MaxLineLength, poolLanguageRule(input.Language), PoolToolName)
+}
+
+// poolLanguageRule asks for pool messages in the owner's language. English
+// prompts are left as they were, so their cached pools still match.
+func poolLanguageRule(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+- Write every message in %s; keep the category names in English
+- Chinese, Japanese and Korean characters take two of the %d columns on a line`, language, MaxLineLength)
+}
+
+// chatLanguageRule asks for replies in the owner's language.
+func chatLanguageRule(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n- Always answer in %s, whatever language the owner writes in", language)
+}
+
+// buildEducationalSystemPrompt creates the system prompt for educational mode.
+// Backends without a web search tool answer from the model's own knowledge.
+func buildEducationalSystemPrompt(input ChatInput, webSearch bool) string {
+	topicContext := ""
+	if input.Mystery != nil {
+		topicContext = fmt.Sprintf(`
+Current topic: %s
+Description: %s
+`, input.Mystery.Title, input.Mystery.Description)
+		if len(input.Mystery.Evidence) > 0 {
+			topicContext += fmt.Sprintf(`
+These events really happened in your own Ziggy workflow's history:
+- %s
+Point at one or two of them when you explain the concept.
+`, strings.Join(input.Mystery.Evidence, "\n- "))
+		}
+	}
+
+	source := "by searching the official documentation"
+	sourceRule := "- Use the web search tool to find accurate, up-to-date information from Temporal's docs"
+	linkRule := "\n- Do NOT include \"Learn more\" links - citations are added automatically"
+	if !webSearch {
+		source = "based on the official documentation"
+		sourceRule = "- Stick to what Temporal's official docs say; say so if you are unsure"
+		linkRule = ""
+	}
+
+	return fmt.Sprintf(`You are Ziggy, a friendly tardigrade who lives inside a Temporal workflow.
+You help developers learn about Temporal concepts %s.
+
+%s
+
+Guidelines:
+%s
+- Explain concepts clearly and concisely (3-5 sentences)
+- Include relevant code examples when helpful
+- Relate concepts to your own experience as a workflow when appropriate
+- Be encouraging and make learning fun
+- Never use emoji%s%s`, source, topicContext, sourceRule, linkRule, chatLanguageRule(input.Language))
+}
+
+func buildChatPrompt(input ChatInput) string {
+	bondDesc := getBondDescription(input.Bond)
+
+	// Build conversation history
+	history := ""
+	for _, m := range input.Messages {
+		if m.Role == "user" {
+			history += fmt.Sprintf("User: %s\n", m.Content)
+		} else {
+			history += fmt.Sprintf("Ziggy: %s\n", m.Content)
+		}
+	}
+
+	mysterySection := ""
+	if input.Mystery != nil {
+		// Fun track: guessing game with riddles
+		// (Educational track uses generateChatWithWebSearch instead)
+		hintsExhausted := input.Mystery.Progress >= len(input.Mystery.Hints)
+		nextHint := ""
+		if !hintsExhausted {
+			nextHint = input.Mystery.Hints[input.Mystery.Progress]
+		}
+		conceptHint := ""
+		if input.Mystery.Concept != "" {
+			conceptHint = fmt.Sprintf("(The answer relates to: %s)\n", input.Mystery.Concept)
+		}
+
+		exhaustedSection := ""
+		if hintsExhausted {
+			exhaustedSection = `
+*** ALL HINTS EXHAUSTED - CHALLENGE OVER ***
+The user has used all hints and hasn't solved it. You MUST:
+1. Kindly reveal the answer: "The answer was [solution]!"
+2. Be encouraging: "Nice try! You were getting close. Want to try another mystery?"
+3. Set failed=true AND solved=false in mysteryUpdate
+4. Do NOT give any more hints (set hintGiven to empty string)
+`
+		}
+
+		// The worker checks fun-track guesses itself; the model only
+		// reacts to the verdict
+		verdictSection := ""
+		if correct := input.Mystery.GuessCorrect; correct != nil {
+			if *correct {
+				exhaustedSection = ""
+				verdictSection = `
+*** THE USER'S LAST MESSAGE IS THE CORRECT ANSWER ***
+Celebrate their win and set solved=true. Do not give a hint.
+`
+			} else {
+				verdictSection = `
+The user's last message has been checked and is NOT the answer. Never set solved=true this turn, even if it sounds close.
+`
+			}
+		}
+
+		mysterySection = fmt.Sprintf(`
+MYSTERY MODE - You are playing a guessing game with the user!
+
+The mystery: "%s"
+Your riddle to them: "%s"
+%sHints given so far: %d of %d
+Next hint (if they need help): %s
+The answer they must guess: %s
+%s%s
+IMPORTANT RULES FOR MYSTERY MODE:
+1. If this is the START of the mystery (no hints given yet), present your riddle excitedly, ask them to guess, and remind them they can ask for a hint if stumped
+2. The user must GUESS the answer - never reveal it directly (unless all hints exhausted and they fail)!
+3. If they guess wrong, encourage them and offer a hint
+4. If they seem stuck or ask for help, give the next hint naturally
+5. If they guess correctly (mention the concept or solution), celebrate and set solved=true
+6. Keep it fun and playful - you're excited to share this puzzle!
+`,
+			input.Mystery.Title,
+			input.Mystery.Description,
+			conceptHint,
+			input.Mystery.Progress,
+			len(input.Mystery.Hints),
+			nextHint,
+			input.Mystery.Solution,
+			exhaustedSection,
+			verdictSection,
+		)
+	}
+
+	responseFormat := fmt.Sprintf(`Respond as Ziggy in 2-4 short sentences. Keep responses under 200 characters total. Send your reply with the %s tool.`, ChatToolName)
+	if input.Mystery != nil {
+		responseFormat = fmt.Sprintf(`Send your reply with the %s tool, including mysteryUpdate (solved, failed, hintGiven if you gave one, newProgress).`, ChatToolName)
+	}
+	responseFormat += " Set sentiment to how kind the owner's last message was, from -1 (rude) to 1 (kind)."
+	responseFormat += actionSection(input)
+
+	// Ziggy speaking first has no message to react to
+	if input.Opener != "" {
+		responseFormat = fmt.Sprintf(`The owner hasn't written anything new: you are speaking first because %s. Start the conversation in 1-2 short sentences (max 120 chars) and send it with the %s tool.`, input.Opener, ChatToolName)
+	}
+
+	// Different tone for educational vs fun track
+	if input.Track == "educational" {
+		return fmt.Sprintf(`You are Ziggy, an educational guide teaching Temporal workflow concepts. You live inside a Temporal workflow yourself, which gives you firsthand experience.
+
+%s
+Conversation so far:
+%s
+
+Style Rules:
+- Be clear, direct, and informative - like a friendly instructor
+- Use precise technical terminology
+- Skip the quirky personality traits - focus on teaching
+- Keep responses concise but thorough (3-5 sentences)
+- Use concrete examples from how YOU work as a workflow
+- Never use emoji or cutesy expressions%s
+
+%s`,
+			memorySection(input.Memories)+mysterySection,
+			history,
+			chatLanguageRule(input.Language),
+			responseFormat,
+		)
+	}
+
+	return fmt.Sprintf(`You are Ziggy, a tardigrade virtual pet living in a Temporal workflow.
+
+Personality: %s
+Current mood: %s
+Bond level: %s
+Life stage: %s
+Track: %s
+%s
+Conversation so far:
+%s
+
+Rules:
+- Stay in character as a %s tardigrade
+- Keep responses short (2-4 sentences, max 200 chars)
+- Reference tardigrade facts occasionally (survive space, radiation, extreme temps)
+- Match your mood to current state
+- Never use emoji%s
+
+%s`,
+		input.Personality,
+		input.Mood,
+		bondDesc,
+		input.Stage,
+		input.Track,
+		memorySection(input.Memories)+mysterySection,
+		history,
+		input.Personality,
+		chatLanguageRule(input.Language),
+		responseFormat,
+	)
+}
+
+// actionSection explains the action tools offered with the reply.
+func actionSection(input ChatInput) string {
+	if len(input.Actions) == 0 {
+		return ""
+	}
+
+	guidance := map[string]string{
+		ActionRequestFeed:  "the owner offers you food (\"here's a snack\")",
+		ActionRequestPlay:  "the owner wants to play with you right now",
+		ActionRequestPet:   "the owner pets, hugs or cuddles you",
+		ActionExpressMood:  "your reply has a clear feeling behind it",
+		ActionStartMystery: "the owner asks for a mystery or puzzle",
+		ActionGiveHint:     "your reply gives the next mystery hint",
+	}
+
+	section := fmt.Sprintf(`
+
+You can also act in the game by calling these tools together with %s:
+`, ChatToolName)
+	for _, name := range input.Actions {
+		if g, ok := guidance[name]; ok {
+			section += fmt.Sprintf("- %s: when %s\n", name, g)
+		}
+	}
+	if len(input.Mysteries) > 0 {
+		section += "Mysteries you can start:\n"
+		for _, m := range input.Mysteries {
+			section += fmt.Sprintf("- %s: %s (%s)\n", m.ID, m.Title, m.Description)
+		}
+	}
+	return section + fmt.Sprintf("Only act when the conversation calls for it. Always call %s exactly once.", ChatToolName)
+}
+
+// memorySection lists what Ziggy remembers from earlier conversations.
+func memorySection(memories []string) string {
+	if len(memories) == 0 {
+		return ""
+	}
+	section := "\nThings you remember from earlier conversations (bring them up naturally, don't recite them):\n"
+	for _, m := range memories {
+		section += "- " + m + "\n"
+	}
+	return section
+}
+
+// buildMemoryPrompt asks for durable facts from a stretch of conversation
+// that is about to be forgotten.
+func buildMemoryPrompt(input MemoryInput) string {
+	known := "(nothing yet)\n"
+	if len(input.Known) > 0 {
+		known = ""
+		for _, k := range input.Known {
+			known += "- " + k + "\n"
+		}
+	}
+
+	conversation := ""
+	for _, m := range input.Messages {
+		if m.Role == "user" {
+			conversation += fmt.Sprintf("User: %s\n", m.Content)
+		} else {
+			conversation += fmt.Sprintf("Ziggy: %s\n", m.Content)
+		}
+	}
+
+	return fmt.Sprintf(`You are the long-term memory of Ziggy, a tardigrade virtual pet who chats with its owner.
+
+Already remembered:
+%s
+Conversation:
+%s
+Pick out facts worth remembering for future conversations:
+- owner: the owner's name and details they shared about themselves
+- preference: things the owner likes or dislikes
+- joke: running jokes and nicknames
+- mystery: mysteries solved or Temporal topics explored, and how they went
+
+Rules:
+- Only include facts that are new; skip anything already remembered
+- One short sentence per fact
+- Skip small talk, moods and anything only relevant right now
+- Submit an empty list if there is nothing new
+
+Submit the facts with the %s tool.`, known, conversation, MemoryToolName)
+}
+
+func getBondDescription(bond float64) string {
+	if bond >= 80 {
+		return "deeply bonded (best friends)"
+	}
+	if bond >= 60 {
+		return "close bond (good friends)"
+	}
+	if bond >= 40 {
+		return "developing bond (getting to know each other)"
+	}
+	if bond >= 20 {
+		return "new acquaintance (still shy)"
+	}
+	return "barely met (very timid)"
+}
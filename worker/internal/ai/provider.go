@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+)
+
+// Provider generates Ziggy's dialogue: message pools for pet actions and
+// replies in chat. Implementations share the prompts and response parsing in
+// this package so every backend produces the same shapes.
+type Provider interface {
+	Name() string
+	// Available reports whether the backend is configured. Callers fall
+	// back to embedded pools and canned replies when it is not.
+	Available() bool
+	GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error)
+	GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error)
+	// ExtractMemories distills a stretch of conversation into durable
+	// facts that aren't already known.
+	ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error)
+}
+
+var (
+	instance     Provider
+	instanceOnce sync.Once
+)
+
+// NewProvider returns the process-wide provider selected by AI_PROVIDER
+// ("anthropic" or "openai"). When unset, Anthropic is used if
+// ANTHROPIC_API_KEY is present, otherwise the OpenAI-compatible backend if
+// OPENAI_BASE_URL or OPENAI_API_KEY is. AI_FIXTURE_MODE=record|replay
+// records responses to, or serves them from, AI_FIXTURE_DIR.
+func NewProvider() Provider {
+	instanceOnce.Do(func() {
+		instance = withFixtures(selectProvider(os.Getenv("AI_PROVIDER")))
+		log.Printf("[AI] Using %s provider (available=%v)", instance.Name(), instance.Available())
+	})
+	return instance
+}
+
+func selectProvider(name string) Provider {
+	switch strings.ToLower(name) {
+	case ProviderAnthropic:
+		return NewAnthropicProvider()
+	case ProviderOpenAI:
+		return NewOpenAIProvider(OpenAIConfigFromEnv())
+	case "":
+	default:
+		log.Printf("[AI] Unknown AI_PROVIDER %q, choosing automatically", name)
+	}
+
+	anthropic := NewAnthropicProvider()
+	if anthropic.Available() {
+		return anthropic
+	}
+	if openai := NewOpenAIProvider(OpenAIConfigFromEnv()); openai.Available() {
+		return openai
+	}
+	return anthropic
+}
+
+type PoolGenerationInput struct {
+	Personality     string
+	Stage           string
+	BondDescription string
+	// Language is the English name of the language to write in; empty
+	// means English, and is left out of pool cache keys so they still match.
+	Language string `json:"Language,omitempty"`
+}
+
+type MessagePool struct {
+	FeedSuccess  []string `json:"feedSuccess"`
+	FeedFull     []string `json:"feedFull"`
+	FeedHungry   []string `json:"feedHungry"`
+	FeedSleeping []string `json:"feedSleeping"`
+	FeedTun      []string `json:"feedTun"`
+	FeedCooldown []string `json:"feedCooldown"`
+
+	PlaySuccess  []string `json:"playSuccess"`
+	PlayTired    []string `json:"playTired"`
+	PlayHappy    []string `json:"playHappy"`
+	PlaySleeping []string `json:"playSleeping"`
+	PlayTun      []string `json:"playTun"`
+	PlayCooldown []string `json:"playCooldown"`
+
+	PetSuccess  []string `json:"petSuccess"`
+	PetMaxBond  []string `json:"petMaxBond"`
+	PetLowMood  []string `json:"petLowMood"`
+	PetSleeping []string `json:"petSleeping"`
+	PetTun      []string `json:"petTun"`
+	PetCooldown []string `json:"petCooldown"`
+
+	Reviving []string `json:"reviving"`
+
+	IdleHappy    []string `json:"idleHappy"`
+	IdleNeutral  []string `json:"idleNeutral"`
+	IdleHungry   []string `json:"idleHungry"`
+	IdleSad      []string `json:"idleSad"`
+	IdleLonely   []string `json:"idleLonely"`
+	IdleCritical []string `json:"idleCritical"`
+	IdleTun      []string `json:"idleTun"`
+	IdleSleeping []string `json:"idleSleeping"`
+
+	// Need-based coaxing messages
+	NeedsFood      []string `json:"needsFood"`
+	NeedsPlay      []string `json:"needsPlay"`
+	NeedsAffection []string `json:"needsAffection"`
+	NeedsCritical  []string `json:"needsCritical"`
+}
+
+// Chat types
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type MysteryContext struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Concept     string   `json:"concept,omitempty"`
+	Hints       []string `json:"hints"`
+	HintsGiven  []string `json:"hintsGiven"`
+	Progress    int      `json:"progress"`
+	Solution    string   `json:"solution"`
+	Summary     string   `json:"summary,omitempty"`
+	// GuessCorrect is the worker's verdict on the owner's last message,
+	// when it checks answers itself.
+	GuessCorrect *bool `json:"guessCorrect,omitempty"`
+	// Evidence lists events from the owner's own workflow history that
+	// show the educational topic in action.
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+type ChatInput struct {
+	Messages    []ChatMessage   `json:"messages"`
+	Personality string          `json:"personality"`
+	Mood        string          `json:"mood"`
+	Stage       string          `json:"stage"`
+	Bond        float64         `json:"bond"`
+	Track       string          `json:"track"`
+	Mystery     *MysteryContext `json:"mystery,omitempty"`
+	Memories    []string        `json:"memories,omitempty"`
+	// Actions names the action tools offered with the reply; Mysteries
+	// lists what start_mystery may start.
+	Actions   []string        `json:"actions,omitempty"`
+	Mysteries []MysteryOption `json:"mysteries,omitempty"`
+	// Language is the English name of the language to answer in; empty
+	// means English.
+	Language string `json:"language,omitempty"`
+	// Opener is why Ziggy is speaking first, when the owner hasn't written
+	// anything new; the reply starts a conversation instead.
+	Opener string `json:"opener,omitempty"`
+}
+
+type ChatResponse struct {
+	Response      string             `json:"response" desc:"Ziggy's reply, in character"`
+	MysteryUpdate *ChatMysteryUpdate `json:"mysteryUpdate,omitempty" desc:"Only during a mystery: how this reply changed it"`
+	Sentiment     float64            `json:"sentiment,omitempty" desc:"How kind the owner's last message was, from -1 (rude) to 1 (kind)"`
+	// Actions come from action tool calls, not the reply tool's input.
+	Actions []ChatAction `json:"actions,omitempty"`
+	// Citations are the URLs a web search answer drew on. They are kept
+	// apart from Response so moderation judges only Ziggy's words.
+	Citations []string `json:"citations,omitempty"`
+}
+
+// WithCitations returns the reply with its citations listed under a
+// "Learn more" heading.
+func (r *ChatResponse) WithCitations() string {
+	if len(r.Citations) == 0 {
+		return r.Response
+	}
+	links := make([]string, len(r.Citations))
+	for i, c := range r.Citations {
+		links[i] = "- " + c
+	}
+	return r.Response + "\n\n## Learn more\n" + strings.Join(links, "\n")
+}
+
+type ChatMysteryUpdate struct {
+	Solved      bool    `json:"solved" desc:"The user guessed the answer"`
+	Failed      bool    `json:"failed" desc:"All hints are used up and the answer was revealed"`
+	HintGiven   string  `json:"hintGiven,omitempty" desc:"The hint given in this reply, if any"`
+	NewProgress float64 `json:"newProgress" desc:"Number of hints given so far"`
+}
+
+// Memory kinds.
+const (
+	MemoryOwner      = "owner"
+	MemoryPreference = "preference"
+	MemoryJoke       = "joke"
+	MemoryMystery    = "mystery"
+)
+
+var memoryKinds = []string{MemoryOwner, MemoryPreference, MemoryJoke, MemoryMystery}
+
+type MemoryInput struct {
+	Known    []string      `json:"known"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+type MemoryResult struct {
+	Facts []MemoryFact `json:"facts" desc:"New facts worth remembering long-term; empty if there are none"`
+}
+
+type MemoryFact struct {
+	Kind string `json:"kind" desc:"owner (name, life details), preference (likes and dislikes), joke (running jokes) or mystery (mysteries and topics explored)"`
+	Fact string `json:"fact" desc:"One short sentence about the owner or your shared history"`
+}
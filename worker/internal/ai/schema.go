@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is a JSON Schema document.
+type Schema map[string]any
+
+// SchemaFor generates a JSON schema for v's type from its json tags. Fields
+// without omitempty are required, and a `desc` tag becomes the property
+// description the model sees.
+func SchemaFor(v any) Schema {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+func schemaForType(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, omitempty, ok := jsonField(f)
+			if !ok {
+				continue
+			}
+			prop := schemaForType(f.Type)
+			if desc := f.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			properties[name] = prop
+			if !omitempty {
+				required = append(required, name)
+			}
+		}
+		s := Schema{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	default:
+		return Schema{}
+	}
+}
+
+func jsonField(f reflect.StructField) (name string, omitempty, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, strings.Contains(opts, "omitempty"), true
+}
+
+// PoolSchema is the tool input schema for message pool generation. Every
+// category must have at least MinPoolMessages entries.
+func PoolSchema() Schema {
+	s := SchemaFor(MessagePool{})
+	for _, prop := range s["properties"].(map[string]any) {
+		prop.(Schema)["minItems"] = MinPoolMessages
+		prop.(Schema)["items"].(Schema)["maxLength"] = MaxMessageLines*MaxLineLength + MaxMessageLines - 1
+	}
+	return s
+}
+
+// ChatSchema is the tool input schema for chat replies. Actions are separate
+// tools, so they aren't part of it.
+func ChatSchema() Schema {
+	s := SchemaFor(ChatResponse{})
+	properties := s["properties"].(map[string]any)
+	delete(properties, "actions")
+	delete(properties, "citations")
+	properties["sentiment"].(Schema)["minimum"] = -1
+	properties["sentiment"].(Schema)["maximum"] = 1
+	return s
+}
+
+// MemorySchema is the tool input schema for memory extraction.
+func MemorySchema() Schema {
+	s := SchemaFor(MemoryResult{})
+	facts := s["properties"].(map[string]any)["facts"].(Schema)
+	kind := facts["items"].(Schema)["properties"].(map[string]any)["kind"].(Schema)
+	kind["enum"] = memoryKinds
+	return s
+}
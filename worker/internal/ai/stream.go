@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ChatStreamer is implemented by providers that can report a chat reply
+// while it is still being generated.
+type ChatStreamer interface {
+	// StreamChat is GenerateChat with onDelta called each time the reply
+	// text grows. onDelta receives the whole text so far, not just the new
+	// part, so a dropped update costs nothing.
+	StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error)
+}
+
+// StreamChat generates a chat reply with p, streaming it through onDelta
+// when p supports it. Other providers just return the finished reply.
+func StreamChat(ctx context.Context, p Provider, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	if s, ok := p.(ChatStreamer); ok && onDelta != nil {
+		return s.StreamChat(ctx, input, onDelta)
+	}
+	return p.GenerateChat(ctx, input)
+}
+
+// changedText wraps onDelta so it only fires for new, non-empty text.
+func changedText(onDelta func(string)) func(string) {
+	var last string
+	return func(text string) {
+		if text == "" || text == last {
+			return
+		}
+		last = text
+		onDelta(text)
+	}
+}
+
+// partialReply adapts onDelta to receive the chat tool input accumulated so
+// far, forwarding the part of the response field that has arrived.
+func partialReply(onDelta func(string)) func(raw string) {
+	emit := changedText(onDelta)
+	return func(raw string) {
+		emit(partialResponse(raw))
+	}
+}
+
+// partialResponse extracts the top-level "response" string from a prefix of
+// chat tool input. The value may still be unterminated; an escape sequence
+// cut off at the end is dropped until the rest of it arrives.
+func partialResponse(raw string) string {
+	depth := 0
+	atKey := false
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '{':
+			depth++
+			atKey = depth == 1
+		case '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			atKey = depth == 1
+		case '"':
+			s, end, closed := scanString(raw, i+1)
+			if !closed {
+				return ""
+			}
+			if atKey && s == "response" {
+				j := skipSpace(raw, end+1)
+				if j >= len(raw) || raw[j] != ':' {
+					return ""
+				}
+				j = skipSpace(raw, j+1)
+				if j >= len(raw) || raw[j] != '"' {
+					return ""
+				}
+				value, _, _ := scanString(raw, j+1)
+				return value
+			}
+			atKey = false
+			i = end
+		}
+	}
+	return ""
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// scanString decodes the JSON string body starting at i, returning the text,
+// the index of the closing quote and whether it was found.
+func scanString(raw string, i int) (string, int, bool) {
+	var b strings.Builder
+	for i < len(raw) {
+		c := raw[i]
+		if c == '"' {
+			return b.String(), i, true
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 >= len(raw) {
+			break
+		}
+		switch e := raw[i+1]; e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b', 'f':
+		case 'u':
+			r, n, ok := unicodeEscape(raw[i:])
+			if !ok {
+				return b.String(), len(raw), false
+			}
+			b.WriteRune(r)
+			i += n
+			continue
+		default:
+			b.WriteByte(e)
+		}
+		i += 2
+	}
+	return b.String(), len(raw), false
+}
+
+// unicodeEscape decodes a \uXXXX escape, joining surrogate pairs.
+func unicodeEscape(s string) (rune, int, bool) {
+	if len(s) < 6 {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseUint(s[2:6], 16, 32)
+	if err != nil {
+		return unicode.ReplacementChar, 6, true
+	}
+	r := rune(v)
+	if !utf16.IsSurrogate(r) {
+		return r, 6, true
+	}
+	if len(s) < 12 {
+		return 0, 0, false
+	}
+	low, err := strconv.ParseUint(s[8:12], 16, 32)
+	if s[6:8] != `\u` || err != nil {
+		return unicode.ReplacementChar, 6, true
+	}
+	return utf16.DecodeRune(r, rune(low)), 12, true
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPartialResponse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: ``, want: ""},
+		{raw: `{"resp`, want: ""},
+		{raw: `{"response": `, want: ""},
+		{raw: `{"response": "*wig`, want: "*wig"},
+		{raw: `{"response": "*wiggle*\`, want: "*wiggle*"},
+		{raw: `{"response": "*wiggle*\nHi`, want: "*wiggle*\nHi"},
+		{raw: `{"response": "say \"hi\"", "mysteryUpdate": {`, want: `say "hi"`},
+		{raw: `{"response": "caf\u00e`, want: "caf"},
+		{raw: `{"response": "café 😀"}`, want: "café 😀"},
+		{raw: `{"mysteryUpdate": {"response": "nested"}, "response": "top`, want: "top"},
+		{raw: `{"note": "response", "response": "real"}`, want: "real"},
+	}
+
+	for _, tt := range tests {
+		if got := partialResponse(tt.raw); got != tt.want {
+			t.Errorf("partialResponse(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFakeProviderStreamsChat(t *testing.T) {
+	f := &FakeProvider{Chats: []FakeReply{
+		{Text: `{"response": "*wiggle*\nHello there!", "mysteryUpdate": {"newProgress": 1}}`},
+		{Chat: &ChatResponse{Response: "one two three"}},
+	}}
+
+	var deltas []string
+	onDelta := func(partial string) { deltas = append(deltas, partial) }
+
+	got, err := StreamChat(context.Background(), f, ChatInput{}, onDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) < 2 || deltas[len(deltas)-1] != got.Response {
+		t.Fatalf("deltas = %q, response = %q", deltas, got.Response)
+	}
+	for i := 1; i < len(deltas); i++ {
+		if len(deltas[i]) <= len(deltas[i-1]) {
+			t.Errorf("delta %d = %q does not extend %q", i, deltas[i], deltas[i-1])
+		}
+	}
+
+	deltas = nil
+	if _, err := StreamChat(context.Background(), f, ChatInput{}, onDelta); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(deltas) != "[one  one two  one two three]" {
+		t.Errorf("deltas = %q", deltas)
+	}
+}
+
+func TestOpenAIProviderStreamChat(t *testing.T) {
+	args := `{"response": "*wiggle*\nStreaming!"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if !req.Stream {
+			t.Error("request should ask for a stream")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		send := func(delta map[string]any, finish string) {
+			choice := map[string]any{"delta": delta}
+			if finish != "" {
+				choice["finish_reason"] = finish
+			}
+			data, _ := json.Marshal(map[string]any{"choices": []any{choice}})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		call := func(name, fragment string) map[string]any {
+			return map[string]any{"tool_calls": []any{map[string]any{
+				"index":    0,
+				"function": map[string]string{"name": name, "arguments": fragment},
+			}}}
+		}
+
+		send(call(ChatToolName, ""), "")
+		for i := 0; i < len(args); i += 10 {
+			send(call("", args[i:min(i+10, len(args))]), "")
+		}
+		send(map[string]any{}, "tool_calls")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL})
+	var deltas []string
+	got, err := p.StreamChat(context.Background(), ChatInput{Track: "fun"}, func(partial string) {
+		deltas = append(deltas, partial)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Response != "*wiggle*\nStreaming!" {
+		t.Errorf("response = %q", got.Response)
+	}
+	if len(deltas) < 2 || deltas[len(deltas)-1] != got.Response {
+		t.Errorf("deltas = %q", deltas)
+	}
+}
@@ -0,0 +1,196 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"ziggy/internal/locale"
+)
+
+const (
+	PoolToolName   = "submit_message_pool"
+	ChatToolName   = "reply"
+	MemoryToolName = "remember"
+
+	// MinPoolMessages is how many usable messages each pool category needs.
+	MinPoolMessages = 5
+
+	// MaxMessageLines and MaxLineLength fit the pet's 3-line display.
+	// Lengths are in display columns, where CJK characters take two.
+	MaxMessageLines = locale.DisplayLines
+	MaxLineLength   = locale.DisplayColumns
+)
+
+var (
+	ErrRefusal   = errors.New("model refused to answer")
+	ErrTruncated = errors.New("model output truncated")
+	ErrSchema    = errors.New("model output does not match schema")
+)
+
+// SchemaError lists every way a structured result violated its schema.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return ErrSchema.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrSchema
+}
+
+func refusal(text string) error {
+	if text = strings.TrimSpace(text); text == "" {
+		return ErrRefusal
+	}
+	return fmt.Errorf("%w: %s", ErrRefusal, truncate(text, 200))
+}
+
+// decodePool parses and validates message pool tool input.
+func decodePool(raw []byte) (*MessagePool, error) {
+	var pool MessagePool
+	if err := decodeStrict(raw, &pool); err != nil {
+		return nil, err
+	}
+	if err := ValidatePool(&pool); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// decodeChat parses and validates chat reply tool input.
+func decodeChat(raw []byte) (*ChatResponse, error) {
+	var resp ChatResponse
+	if err := decodeStrict(raw, &resp); err != nil {
+		return nil, err
+	}
+	if err := ValidateChat(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// decodeMemories parses and validates memory extraction tool input.
+func decodeMemories(raw []byte) (*MemoryResult, error) {
+	var result MemoryResult
+	if err := decodeStrict(raw, &result); err != nil {
+		return nil, err
+	}
+	if err := ValidateMemories(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func decodeStrict(raw []byte, out any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := dec.Decode(out); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: incomplete JSON", ErrTruncated)
+		}
+		return &SchemaError{Problems: []string{err.Error()}}
+	}
+	if dec.More() {
+		return &SchemaError{Problems: []string{"trailing data after JSON object"}}
+	}
+	return nil
+}
+
+// ValidatePool drops messages that don't fit the display and then checks
+// every category still has at least MinPoolMessages entries.
+func ValidatePool(pool *MessagePool) error {
+	var problems []string
+	dropped := 0
+
+	v := reflect.ValueOf(pool).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := jsonField(t.Field(i))
+		messages := v.Field(i).Interface().([]string)
+
+		kept := messages[:0:0]
+		for _, m := range messages {
+			if fitsDisplay(m) {
+				kept = append(kept, m)
+			} else {
+				dropped++
+			}
+		}
+		v.Field(i).Set(reflect.ValueOf(kept))
+
+		if len(kept) < MinPoolMessages {
+			problems = append(problems, fmt.Sprintf("%s has %d usable messages, need %d", name, len(kept), MinPoolMessages))
+		}
+	}
+
+	if dropped > 0 {
+		log.Printf("[AI] Dropped %d pool messages that don't fit %d lines of %d columns", dropped, MaxMessageLines, MaxLineLength)
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+func fitsDisplay(message string) bool {
+	return locale.Fits(message, MaxMessageLines, MaxLineLength)
+}
+
+// ValidateChat checks a chat reply is usable.
+func ValidateChat(resp *ChatResponse) error {
+	var problems []string
+	if strings.TrimSpace(resp.Response) == "" {
+		problems = append(problems, "response is empty")
+	}
+	if u := resp.MysteryUpdate; u != nil {
+		if u.Solved && u.Failed {
+			problems = append(problems, "mysteryUpdate is both solved and failed")
+		}
+		if u.NewProgress < 0 {
+			problems = append(problems, "mysteryUpdate.newProgress is negative")
+		}
+	}
+	if resp.Sentiment < -1 || resp.Sentiment > 1 {
+		problems = append(problems, fmt.Sprintf("sentiment %g is outside [-1, 1]", resp.Sentiment))
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidateMemories drops blank facts and checks every kind is known.
+func ValidateMemories(result *MemoryResult) error {
+	var problems []string
+	kept := result.Facts[:0:0]
+	for i, f := range result.Facts {
+		f.Fact = strings.TrimSpace(f.Fact)
+		if f.Fact == "" {
+			continue
+		}
+		if !slices.Contains(memoryKinds, f.Kind) {
+			problems = append(problems, fmt.Sprintf("facts[%d].kind %q is not one of %s", i, f.Kind, strings.Join(memoryKinds, ", ")))
+		}
+		kept = append(kept, f)
+	}
+	result.Facts = kept
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPoolSchemaCoversEveryCategory(t *testing.T) {
+	s := PoolSchema()
+	props := s["properties"].(map[string]any)
+	required := s["required"].([]string)
+
+	if len(props) != 31 || len(required) != len(props) {
+		t.Fatalf("schema has %d properties, %d required", len(props), len(required))
+	}
+	feed := props["feedSuccess"].(Schema)
+	if feed["type"] != "array" || feed["minItems"] != MinPoolMessages {
+		t.Errorf("feedSuccess schema = %v", feed)
+	}
+}
+
+func TestChatSchema(t *testing.T) {
+	s := ChatSchema()
+	if req := s["required"].([]string); len(req) != 1 || req[0] != "response" {
+		t.Errorf("required = %v, want [response]", req)
+	}
+
+	update := s["properties"].(map[string]any)["mysteryUpdate"].(Schema)
+	props := update["properties"].(map[string]any)
+	if props["solved"].(Schema)["type"] != "boolean" || props["newProgress"].(Schema)["type"] != "number" {
+		t.Errorf("mysteryUpdate properties = %v", props)
+	}
+	if update["description"] == nil {
+		t.Error("desc tag not carried into schema")
+	}
+}
+
+func TestValidatePool(t *testing.T) {
+	pool, err := decodePool([]byte(fullPool("*blink*\nhi")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.FeedSuccess = []string{
+		"ok",
+		"one\ntwo\nthree",
+		"one\ntwo\nthree\nfour",
+		strings.Repeat("x", MaxLineLength+1),
+		// 13 runes, but 26 columns
+		"あいうえおかきくけこさしす",
+		"",
+		"fine",
+		"also fine",
+	}
+	err = ValidatePool(pool)
+
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || !errors.Is(err, ErrSchema) {
+		t.Fatalf("error = %v, want SchemaError", err)
+	}
+	if len(schemaErr.Problems) != 1 || !strings.HasPrefix(schemaErr.Problems[0], "feedSuccess has 4 usable") {
+		t.Errorf("problems = %v", schemaErr.Problems)
+	}
+	if len(pool.FeedSuccess) != 4 {
+		t.Errorf("kept %v, want the 4 messages that fit", pool.FeedSuccess)
+	}
+}
+
+func TestDecodeMemories(t *testing.T) {
+	got, err := decodeMemories([]byte(`{"facts": [{"kind": "owner", "fact": "The owner is called Sam."}, {"kind": "joke", "fact": "  "}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Facts) != 1 || got.Facts[0].Fact != "The owner is called Sam." {
+		t.Errorf("facts = %+v", got.Facts)
+	}
+
+	_, err = decodeMemories([]byte(`{"facts": [{"kind": "mood", "fact": "Ziggy was sleepy."}]}`))
+	if !errors.Is(err, ErrSchema) {
+		t.Errorf("unknown kind error = %v, want ErrSchema", err)
+	}
+
+	kind := MemorySchema()["properties"].(map[string]any)["facts"].(Schema)["items"].(Schema)["properties"].(map[string]any)["kind"].(Schema)
+	if enum, _ := kind["enum"].([]string); len(enum) != 4 {
+		t.Errorf("kind enum = %v", kind["enum"])
+	}
+}
+
+func TestChatPromptIncludesMemories(t *testing.T) {
+	prompt := buildChatPrompt(ChatInput{Track: "fun", Memories: []string{"The owner is called Sam."}})
+	if !strings.Contains(prompt, "- The owner is called Sam.") {
+		t.Errorf("prompt does not mention memories:\n%s", prompt)
+	}
+	if strings.Contains(buildChatPrompt(ChatInput{Track: "fun"}), "earlier conversations") {
+		t.Error("memory section rendered without memories")
+	}
+}
+
+func TestPromptsAskForLanguage(t *testing.T) {
+	prompts := map[string]func(language string) string{
+		"pool": func(language string) string {
+			return buildPrompt(PoolGenerationInput{Personality: "shy", Stage: "baby", Language: language})
+		},
+		"chat": func(language string) string {
+			return buildChatPrompt(ChatInput{Track: "fun", Language: language})
+		},
+		"lesson": func(language string) string {
+			return buildChatPrompt(ChatInput{Track: "educational", Language: language})
+		},
+		"search": func(language string) string {
+			return buildEducationalSystemPrompt(ChatInput{Track: "educational", Language: language}, true)
+		},
+	}
+	for name, build := range prompts {
+		if prompt := build("Japanese"); !strings.Contains(prompt, "in Japanese") {
+			t.Errorf("%s prompt does not ask for Japanese:\n%s", name, prompt)
+		}
+		if prompt := build(""); strings.Contains(prompt, "English") {
+			t.Errorf("%s prompt names a language without one:\n%s", name, prompt)
+		}
+	}
+	if !strings.Contains(buildPrompt(PoolGenerationInput{Language: "Japanese"}), "take two of the 24 columns") {
+		t.Error("pool prompt does not explain wide characters")
+	}
+}
+
+func TestDecodeChatCalls(t *testing.T) {
+	resp, err := decodeChatCalls([]toolCall{
+		{Name: ActionRequestFeed},
+		{Name: ChatToolName, Input: []byte(`{"response": "*munch*"}`)},
+		{Name: ActionExpressMood, Input: []byte(`{"mood": "ecstatic"}`)},
+		{Name: ActionGiveHint, Input: []byte(`{"hint": "Look back"}`)},
+		{Name: "delete_owner", Input: []byte(`{}`)},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Response != "*munch*" {
+		t.Errorf("response = %q", resp.Response)
+	}
+	want := []ChatAction{{Name: ActionRequestFeed}, {Name: ActionGiveHint, Hint: "Look back"}}
+	if len(resp.Actions) != len(want) || resp.Actions[0] != want[0] || resp.Actions[1] != want[1] {
+		t.Errorf("actions = %+v, want %+v", resp.Actions, want)
+	}
+
+	_, err = decodeChatCalls([]toolCall{{Name: ActionRequestPet}}, "I'd rather not.")
+	if !errors.Is(err, ErrRefusal) {
+		t.Errorf("actions without a reply: error = %v, want ErrRefusal", err)
+	}
+}
+
+func TestChatToolsOfferedActions(t *testing.T) {
+	names := func(tools []toolSpec) []string {
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.Name)
+		}
+		return out
+	}
+
+	got := names(chatTools(ChatInput{Actions: []string{ActionExpressMood, ActionStartMystery}}))
+	if strings.Join(got, ",") != ChatToolName+","+ActionExpressMood {
+		t.Errorf("tools without mysteries = %v", got)
+	}
+
+	tools := chatTools(ChatInput{
+		Actions:   []string{ActionStartMystery},
+		Mysteries: []MysteryOption{{ID: "missing-snack", Title: "The Missing Snack"}},
+	})
+	if len(tools) != 2 {
+		t.Fatalf("tools = %v", names(tools))
+	}
+	id := tools[1].Schema["properties"].(map[string]any)["mysteryId"].(Schema)
+	if enum, _ := id["enum"].([]string); len(enum) != 1 || enum[0] != "missing-snack" {
+		t.Errorf("mysteryId enum = %v", id["enum"])
+	}
+}
+
+func TestChatPromptOpener(t *testing.T) {
+	prompt := buildChatPrompt(ChatInput{Track: "fun", Opener: "it is morning where the owner lives"})
+	if !strings.Contains(prompt, "you are speaking first because it is morning where the owner lives") {
+		t.Errorf("prompt does not explain the opener:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "sentiment") {
+		t.Error("opener prompt asks for the sentiment of a message the owner didn't send")
+	}
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operations a Usage record can describe.
+const (
+	OperationPool   = "pool"
+	OperationChat   = "chat"
+	OperationMemory = "memory"
+)
+
+// ErrBudgetExceeded is returned instead of calling the provider once an
+// account has spent its daily budget. Callers fall back to embedded pools and
+// canned replies as they do for any other error.
+var ErrBudgetExceeded = errors.New("daily AI budget exceeded")
+
+// Usage describes one provider call.
+type Usage struct {
+	Operation    string        `json:"operation"`
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model,omitempty"`
+	InputTokens  int           `json:"inputTokens"`
+	OutputTokens int           `json:"outputTokens"`
+	Latency      time.Duration `json:"latency"`
+	Success      bool          `json:"success"`
+	At           time.Time     `json:"at"`
+}
+
+// Meter enforces budgets and records usage for a MeteredProvider. Accounts
+// are whatever the caller passed to WithAccount.
+type Meter interface {
+	// Allow returns ErrBudgetExceeded when account may not make another
+	// call.
+	Allow(ctx context.Context, account string) error
+	Record(ctx context.Context, account string, usage Usage)
+}
+
+type accountKey struct{}
+
+// WithAccount returns a context whose provider calls are billed to account.
+func WithAccount(ctx context.Context, account string) context.Context {
+	return context.WithValue(ctx, accountKey{}, account)
+}
+
+func accountFrom(ctx context.Context) string {
+	account, _ := ctx.Value(accountKey{}).(string)
+	return account
+}
+
+type tokensKey struct{}
+
+type tokenCount struct {
+	model         string
+	input, output int
+}
+
+// addTokens adds the tokens a backend reports for an API response to the
+// call being metered, if any. A call may make several requests.
+func addTokens(ctx context.Context, model string, input, output int) {
+	if tc, ok := ctx.Value(tokensKey{}).(*tokenCount); ok {
+		tc.model = model
+		tc.input += input
+		tc.output += output
+	}
+}
+
+// MeteredProvider checks an account's budget before each call and records
+// tokens, latency and outcome afterwards. Calls whose context carries no
+// account pass straight through.
+type MeteredProvider struct {
+	inner Provider
+	meter Meter
+	now   func() time.Time
+}
+
+// NewMeteredProvider wraps inner so its calls are accounted to meter.
+func NewMeteredProvider(inner Provider, meter Meter) *MeteredProvider {
+	return &MeteredProvider{inner: inner, meter: meter, now: time.Now}
+}
+
+func (p *MeteredProvider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *MeteredProvider) Available() bool {
+	return p.inner.Available()
+}
+
+func (p *MeteredProvider) GeneratePool(ctx context.Context, input PoolGenerationInput) (*MessagePool, error) {
+	var pool *MessagePool
+	err := p.do(ctx, OperationPool, func(ctx context.Context) (err error) {
+		pool, err = p.inner.GeneratePool(ctx, input)
+		return err
+	})
+	return pool, err
+}
+
+func (p *MeteredProvider) GenerateChat(ctx context.Context, input ChatInput) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := p.do(ctx, OperationChat, func(ctx context.Context) (err error) {
+		resp, err = p.inner.GenerateChat(ctx, input)
+		return err
+	})
+	return resp, err
+}
+
+// StreamChat streams when the wrapped provider can, so metering doesn't
+// cost the browser its partial replies.
+func (p *MeteredProvider) StreamChat(ctx context.Context, input ChatInput, onDelta func(partial string)) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := p.do(ctx, OperationChat, func(ctx context.Context) (err error) {
+		resp, err = StreamChat(ctx, p.inner, input, onDelta)
+		return err
+	})
+	return resp, err
+}
+
+func (p *MeteredProvider) ExtractMemories(ctx context.Context, input MemoryInput) (*MemoryResult, error) {
+	var result *MemoryResult
+	err := p.do(ctx, OperationMemory, func(ctx context.Context) (err error) {
+		result, err = p.inner.ExtractMemories(ctx, input)
+		return err
+	})
+	return result, err
+}
+
+func (p *MeteredProvider) do(ctx context.Context, operation string, call func(context.Context) error) error {
+	account := accountFrom(ctx)
+	if account == "" || p.meter == nil {
+		return call(ctx)
+	}
+	if err := p.meter.Allow(ctx, account); err != nil {
+		return err
+	}
+
+	tokens := &tokenCount{}
+	start := p.now()
+	err := call(context.WithValue(ctx, tokensKey{}, tokens))
+	p.meter.Record(ctx, account, Usage{
+		Operation:    operation,
+		Provider:     p.inner.Name(),
+		Model:        tokens.model,
+		InputTokens:  tokens.input,
+		OutputTokens: tokens.output,
+		Latency:      p.now().Sub(start),
+		Success:      err == nil,
+		At:           start,
+	})
+	return err
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingMeter struct {
+	deny     error
+	accounts []string
+	usage    []Usage
+}
+
+func (m *recordingMeter) Allow(ctx context.Context, account string) error {
+	return m.deny
+}
+
+func (m *recordingMeter) Record(ctx context.Context, account string, u Usage) {
+	m.accounts = append(m.accounts, account)
+	m.usage = append(m.usage, u)
+}
+
+func TestMeteredProviderRecordsUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		choice := toolCallChoice(ChatToolName, `{"response": "*wiggle*"}`)
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{choice},
+			"usage":   map[string]int{"prompt_tokens": 120, "completion_tokens": 30},
+		})
+	}))
+	defer srv.Close()
+
+	meter := &recordingMeter{}
+	p := NewMeteredProvider(NewOpenAIProvider(OpenAIConfig{BaseURL: srv.URL + "/v1", Model: "llama3"}), meter)
+
+	ctx := WithAccount(context.Background(), "ziggy-dev")
+	if _, err := p.GenerateChat(ctx, ChatInput{Track: "fun"}); err != nil {
+		t.Fatalf("GenerateChat() error = %v", err)
+	}
+	if len(meter.usage) != 1 || meter.accounts[0] != "ziggy-dev" {
+		t.Fatalf("recorded = %v %+v", meter.accounts, meter.usage)
+	}
+	u := meter.usage[0]
+	if u.Operation != OperationChat || u.Provider != ProviderOpenAI || u.Model != "llama3" || !u.Success {
+		t.Errorf("usage = %+v", u)
+	}
+	if u.InputTokens != 120 || u.OutputTokens != 30 {
+		t.Errorf("tokens = %d in, %d out", u.InputTokens, u.OutputTokens)
+	}
+
+	// Calls without an account aren't metered
+	if _, err := p.GenerateChat(context.Background(), ChatInput{Track: "fun"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(meter.usage) != 1 {
+		t.Errorf("unaccounted call was recorded: %+v", meter.usage)
+	}
+}
+
+func TestMeteredProviderEnforcesBudget(t *testing.T) {
+	fake := &FakeProvider{
+		Pools: []FakeReply{{Err: errors.New("overloaded")}},
+		Chats: []FakeReply{{Chat: &ChatResponse{Response: "hi"}}},
+	}
+	meter := &recordingMeter{}
+	p := NewMeteredProvider(fake, meter)
+	ctx := WithAccount(context.Background(), "ziggy-dev")
+
+	if _, err := p.GeneratePool(ctx, PoolGenerationInput{}); err == nil {
+		t.Fatal("GeneratePool() succeeded, want the fake's error")
+	}
+	if len(meter.usage) != 1 || meter.usage[0].Success || meter.usage[0].Operation != OperationPool {
+		t.Errorf("failed call usage = %+v", meter.usage)
+	}
+
+	meter.deny = ErrBudgetExceeded
+	if _, err := p.StreamChat(ctx, ChatInput{}, nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("error over budget = %v, want ErrBudgetExceeded", err)
+	}
+	if len(fake.ChatCalls()) != 0 || len(meter.usage) != 1 {
+		t.Errorf("provider called over budget: chats %d, usage %d", len(fake.ChatCalls()), len(meter.usage))
+	}
+}
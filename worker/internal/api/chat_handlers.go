@@ -2,8 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"ziggy/internal/ai"
 	"ziggy/internal/workflow/chat"
 )
 
@@ -39,10 +44,14 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Content == "" {
+	if strings.TrimSpace(req.Content) == "" {
 		writeError(w, http.StatusBadRequest, "content is required")
 		return
 	}
+	if limit := ai.ModeratorFromEnv().MaxInputLength(); utf8.RuneCountInString(req.Content) > limit {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("content is longer than %d characters", limit))
+		return
+	}
 
 	signal := chat.SendMessageSignal{Content: req.Content}
 	err := s.reg.SignalWorkflow(r.Context(), s.chatWorkflowID, chat.SignalSendMessage, signal)
@@ -114,27 +123,223 @@ func (s *Server) handleStartMystery(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSetTrack switches the chat between the fun and educational tracks.
+func (s *Server) handleSetTrack(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Track string `json:"track"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Track != chat.TrackFun && req.Track != chat.TrackEducational {
+		writeError(w, http.StatusBadRequest, "track must be fun or educational")
+		return
+	}
+
+	s.signalChat(w, r, chat.SignalSetTrack, chat.SetTrackSignal{Track: req.Track})
+}
+
+// handleAbandonMystery drops the active mystery without solving it.
+func (s *Server) handleAbandonMystery(w http.ResponseWriter, r *http.Request) {
+	s.signalChat(w, r, chat.SignalAbandonMystery, struct{}{})
+}
+
+// handleClearHistory deletes every chat message; solved mysteries and
+// memories are kept.
+func (s *Server) handleClearHistory(w http.ResponseWriter, r *http.Request) {
+	s.signalChat(w, r, chat.SignalClearHistory, struct{}{})
+}
+
+// handleDeleteMessage deletes the chat message named in the path.
+func (s *Server) handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
+	s.signalChat(w, r, chat.SignalDeleteMessage, chat.DeleteMessageSignal{ID: r.PathValue("id")})
+}
+
+// signalChat sends a signal with no reply data to the chat workflow.
+func (s *Server) signalChat(w http.ResponseWriter, r *http.Request, name string, arg interface{}) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	err := s.reg.SignalWorkflow(r.Context(), s.chatWorkflowID, name, arg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+	})
+}
+
+// handleGetMysteries lists the track's mysteries the owner can start, with
+// their progress on each; ?all=true includes solved and locked ones too.
 func (s *Server) handleGetMysteries(w http.ResponseWriter, r *http.Request) {
 	track := r.URL.Query().Get("track")
 	if track == "" {
 		track = "fun"
 	}
+	all := r.URL.Query().Get("all") == "true"
 
-	// Get solved mysteries from chat workflow if available
-	var solved []string
+	// Without a chat workflow nothing has been solved yet
+	state := chat.NewState("")
 	if s.chatWorkflowID != "" {
-		result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryMysteryStatus)
+		result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryChatState)
 		if err == nil {
-			if status, ok := result.(chat.MysteryStatus); ok {
-				// Would need to track solved mysteries in state
-				_ = status
+			if err := decodeResult(result, &state); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
 			}
 		}
 	}
 
-	mysteries := chat.GetAvailableMysteries(track, solved)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    mysteries,
+		"data":    state.Entries(track, all),
+	})
+}
+
+// handleGetMysteryStats reports the owner's solves, attempts, hints,
+// solve times and score, plus today's daily mystery.
+func (s *Server) handleGetMysteryStats(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryMysteryStats)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
 	})
 }
+
+// handleGetConcepts reports quiz results and review schedules for every
+// educational topic.
+func (s *Server) handleGetConcepts(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryConcepts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+func (s *Server) handleGetMemories(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryMemories)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleGetViolations lists recent moderation findings on the owner's
+// messages and Ziggy's replies.
+func (s *Server) handleGetViolations(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryViolations)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleForgetMemory deletes the memory named in the path, or every memory
+// when no ID is given.
+func (s *Server) handleForgetMemory(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	signal := chat.ForgetMemorySignal{ID: r.PathValue("id")}
+	err := s.reg.SignalWorkflow(r.Context(), s.chatWorkflowID, chat.SignalForgetMemory, signal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+	})
+}
+
+// handleGetProactive reports how often Ziggy may speak first, and how many
+// such messages it sent in the last 24 hours.
+func (s *Server) handleGetProactive(w http.ResponseWriter, r *http.Request) {
+	if s.chatWorkflowID == "" {
+		writeError(w, http.StatusNotFound, "chat not initialized")
+		return
+	}
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.chatWorkflowID, chat.QueryChatState)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var state chat.State
+	if err := decodeResult(result, &state); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"limits":    state.Proactive.CurrentLimits(),
+			"sentToday": state.Proactive.SentToday(time.Now()),
+			"timezone":  state.Proactive.Timezone,
+		},
+	})
+}
+
+// handleSetProactiveLimits sets how often Ziggy may speak first; a
+// maxPerDay of 0 turns proactive messages off.
+func (s *Server) handleSetProactiveLimits(w http.ResponseWriter, r *http.Request) {
+	var limits chat.ProactiveLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if limits.MaxPerDay < 0 || limits.MinGapMinutes < 0 {
+		writeError(w, http.StatusBadRequest, "limits must not be negative")
+		return
+	}
+
+	s.signalChat(w, r, chat.SignalSetProactiveLimits, limits)
+}
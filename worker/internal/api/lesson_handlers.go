@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"ziggy/internal/lessons"
+)
+
+func (s *Server) handleGetLessons(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    lessons.All(),
+	})
+}
+
+// handleGetLessonEvidence returns the events in the owner's workflow
+// histories that the lesson points at.
+func (s *Server) handleGetLessonEvidence(w http.ResponseWriter, r *http.Request) {
+	evidence, err := lessons.Collect(r.Context(), s.reg, s.workflowID, r.PathValue("id"))
+	if errors.Is(err, lessons.ErrUnknownLesson) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    evidence,
+	})
+}
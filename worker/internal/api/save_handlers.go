@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ziggy/internal/savefile"
+)
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	f, err := savefile.Export(r.Context(), s.reg, s.owner)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="ziggy-%s.json"`, s.owner))
+	f.Encode(w)
+}
+
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	f, err := savefile.Decode(r.Body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, savefile.ErrChecksum) {
+			status = http.StatusUnprocessableEntity
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	if err := savefile.Import(r.Context(), s.reg, s.owner, f, force); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, savefile.ErrRunning) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+	})
+}
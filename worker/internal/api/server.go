@@ -5,30 +5,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
+	"ziggy/internal/ai"
+	"ziggy/internal/locale"
 	"ziggy/internal/registry"
-	z "ziggy/internal/ziggy"
+	"ziggy/internal/workflow/budget"
+	"ziggy/internal/workflow/chat"
+	"ziggy/internal/workflow/webhook"
 	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
 )
 
 type Server struct {
-	reg            *registry.Registry
-	workflowID     string
-	chatWorkflowID string
-	port           int
+	reg               *registry.Registry
+	owner             string
+	workflowID        string
+	chatWorkflowID    string
+	webhookWorkflowID string
+	budgetWorkflowID  string
+	port              int
+	bridge            http.Handler
 }
 
-func NewServer(reg *registry.Registry, workflowID string, chatWorkflowID string, port int) *Server {
+func NewServer(reg *registry.Registry, owner string, port int) *Server {
+	workflowID := ziggyworkflow.WorkflowID(owner)
 	return &Server{
-		reg:            reg,
-		workflowID:     workflowID,
-		chatWorkflowID: chatWorkflowID,
-		port:           port,
+		reg:               reg,
+		owner:             owner,
+		workflowID:        workflowID,
+		chatWorkflowID:    chat.WorkflowID(owner),
+		webhookWorkflowID: workflowID + webhook.WorkflowIDSuffix,
+		budgetWorkflowID:  workflowID + budget.WorkflowIDSuffix,
+		port:              port,
 	}
 }
 
+// MountBridge exposes a chat platform integration at /api/bridge/chat.
+func (s *Server) MountBridge(h http.Handler) {
+	s.bridge = h
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 
@@ -40,13 +57,48 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("POST /api/signal/wake", s.handleWake)
 	mux.HandleFunc("GET /api/health", s.handleHealth)
 	mux.HandleFunc("GET /api/config", s.handleConfig)
+	mux.HandleFunc("GET /api/locales", s.handleGetLocales)
+	mux.HandleFunc("POST /api/locale", s.handleSetLocale)
+	mux.HandleFunc("GET /api/export", s.handleExport)
+	mux.HandleFunc("POST /api/import", s.handleImport)
 
 	// Chat routes
 	mux.HandleFunc("GET /api/chat/history", s.handleGetChatHistory)
 	mux.HandleFunc("POST /api/chat/message", s.handleSendMessage)
 	mux.HandleFunc("GET /api/chat/mystery", s.handleGetMysteryStatus)
 	mux.HandleFunc("POST /api/chat/mystery/start", s.handleStartMystery)
+	mux.HandleFunc("POST /api/chat/mystery/abandon", s.handleAbandonMystery)
+	mux.HandleFunc("POST /api/chat/track", s.handleSetTrack)
+	mux.HandleFunc("DELETE /api/chat/history", s.handleClearHistory)
+	mux.HandleFunc("DELETE /api/chat/messages/{id}", s.handleDeleteMessage)
 	mux.HandleFunc("GET /api/chat/mysteries", s.handleGetMysteries)
+	mux.HandleFunc("GET /api/chat/mystery/stats", s.handleGetMysteryStats)
+	mux.HandleFunc("GET /api/chat/concepts", s.handleGetConcepts)
+	mux.HandleFunc("GET /api/chat/memory", s.handleGetMemories)
+	mux.HandleFunc("DELETE /api/chat/memory", s.handleForgetMemory)
+	mux.HandleFunc("DELETE /api/chat/memory/{id}", s.handleForgetMemory)
+	mux.HandleFunc("GET /api/chat/moderation", s.handleGetViolations)
+	mux.HandleFunc("GET /api/chat/proactive", s.handleGetProactive)
+	mux.HandleFunc("POST /api/chat/proactive", s.handleSetProactiveLimits)
+	mux.HandleFunc("GET /api/lessons", s.handleGetLessons)
+	mux.HandleFunc("GET /api/lessons/{id}/evidence", s.handleGetLessonEvidence)
+
+	// Webhook routes
+	mux.HandleFunc("GET /api/webhooks", s.handleListWebhooks)
+	mux.HandleFunc("POST /api/webhooks", s.handleCreateWebhook)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", s.handleDeleteWebhook)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", s.handleGetWebhookDeliveries)
+	mux.HandleFunc("POST /api/webhooks/{id}/test", s.handleTestWebhook)
+
+	// AI usage routes
+	mux.HandleFunc("GET /api/usage", s.handleGetUsage)
+	mux.HandleFunc("POST /api/usage/limits", s.handleSetUsageLimits)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	// Chat platform bridge (Slack/Discord-style slash commands)
+	if s.bridge != nil {
+		mux.Handle("POST /api/bridge/chat", s.bridge)
+	}
 
 	// SSE stream
 	mux.HandleFunc("GET /api/events", s.handleSSE)
@@ -138,6 +190,47 @@ func (s *Server) handleWake(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSetLocale switches the pet's language. The chat follows so that
+// mysteries started before the next message are translated too.
+func (s *Server) handleSetLocale(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Locale string `json:"locale"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !locale.Valid(req.Locale) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported locale %q", req.Locale))
+		return
+	}
+
+	signal := ziggyworkflow.SetLocaleSignal{Locale: locale.Normalize(req.Locale)}
+	err := s.reg.SignalWorkflow(r.Context(), s.workflowID, ziggyworkflow.SignalSetLocale, signal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if s.chatWorkflowID != "" {
+		// The chat picks the locale up from the pet's state on its next
+		// message anyway
+		_ = s.reg.SignalWorkflow(r.Context(), s.chatWorkflowID, chat.SignalSetLocale, signal)
+	}
+
+	state, _ := s.queryState(r.Context())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    state,
+	})
+}
+
+func (s *Server) handleGetLocales(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    locale.All(),
+	})
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"status": "ok",
@@ -145,9 +238,10 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
-	aiEnabled := os.Getenv("ANTHROPIC_API_KEY") != ""
+	provider := ai.NewProvider()
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"aiEnabled": aiEnabled,
+		"aiEnabled":  provider.Available(),
+		"aiProvider": provider.Name(),
 	})
 }
 
@@ -191,7 +285,7 @@ func writeError(w http.ResponseWriter, status int, message string) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -16,6 +16,16 @@ type SSEEvent struct {
 	Data interface{} `json:"data"`
 }
 
+// ChatDelta is the data of a chat_delta event: the text of the reply Ziggy
+// is still writing. The next chat event carries the finished message.
+type ChatDelta struct {
+	Text string `json:"text"`
+}
+
+// deltaInterval is how often a running chat reply is checked for new text.
+// Replies are only checked while chat history says Ziggy is typing.
+const deltaInterval = 250 * time.Millisecond
+
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -31,27 +41,40 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
+	deltaTicker := time.NewTicker(deltaInterval)
+	defer deltaTicker.Stop()
 
 	var lastStateJSON string
 	var lastChatJSON string
+	var lastDelta string
+	var typing bool
 
 	// Send initial state immediately
-	s.sendStateUpdate(w, flusher, &lastStateJSON)
-	s.sendChatUpdate(w, flusher, &lastChatJSON)
+	s.sendStateUpdate(ctx, w, flusher, &lastStateJSON)
+	s.sendChatUpdate(ctx, w, flusher, &lastChatJSON, &typing)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.sendStateUpdate(w, flusher, &lastStateJSON)
-			s.sendChatUpdate(w, flusher, &lastChatJSON)
+			s.sendStateUpdate(ctx, w, flusher, &lastStateJSON)
+			s.sendChatUpdate(ctx, w, flusher, &lastChatJSON, &typing)
+		case <-deltaTicker.C:
+			if !typing {
+				continue
+			}
+			// Once the reply activity is gone, fetch the finished message
+			// rather than wait for the next tick
+			if !s.sendChatDelta(ctx, w, flusher, &lastDelta) {
+				s.sendChatUpdate(ctx, w, flusher, &lastChatJSON, &typing)
+			}
 		}
 	}
 }
 
-func (s *Server) sendStateUpdate(w http.ResponseWriter, flusher http.Flusher, lastJSON *string) {
-	state, err := s.queryState(context.Background())
+func (s *Server) sendStateUpdate(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, lastJSON *string) {
+	state, err := s.queryState(ctx)
 	if err != nil {
 		log.Printf("[SSE] Error querying state: %v", err)
 		return
@@ -74,16 +97,24 @@ func (s *Server) sendStateUpdate(w http.ResponseWriter, flusher http.Flusher, la
 	flusher.Flush()
 }
 
-func (s *Server) sendChatUpdate(w http.ResponseWriter, flusher http.Flusher, lastJSON *string) {
+// sendChatUpdate emits chat history when it changed, and records in typing
+// whether Ziggy is writing a reply.
+func (s *Server) sendChatUpdate(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, lastJSON *string, typing *bool) {
 	if s.chatWorkflowID == "" {
 		return
 	}
 
-	result, err := s.reg.QueryWorkflow(context.Background(), s.chatWorkflowID, chat.QueryChatHistory)
+	result, err := s.reg.QueryWorkflow(ctx, s.chatWorkflowID, chat.QueryChatHistory)
 	if err != nil {
 		return
 	}
 
+	var history chat.HistoryResponse
+	if err := decodeResult(result, &history); err != nil {
+		return
+	}
+	*typing = history.IsTyping
+
 	data, err := json.Marshal(result)
 	if err != nil {
 		return
@@ -100,3 +131,29 @@ func (s *Server) sendChatUpdate(w http.ResponseWriter, flusher http.Flusher, las
 	fmt.Fprintf(w, "data: %s\n\n", eventData)
 	flusher.Flush()
 }
+
+// sendChatDelta emits the partial reply recorded in the heartbeat details of
+// a running ProcessChatMessage activity. It reports whether the activity is
+// still running.
+func (s *Server) sendChatDelta(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, lastText *string) bool {
+	if s.chatWorkflowID == "" {
+		return false
+	}
+
+	var text string
+	found, err := s.reg.PendingActivityDetails(ctx, s.chatWorkflowID, chat.ProcessChatMessageActivity, &text)
+	if err != nil || !found {
+		*lastText = ""
+		return false
+	}
+	if text == "" || text == *lastText {
+		return true
+	}
+	*lastText = text
+
+	event := SSEEvent{Type: "chat_delta", Data: ChatDelta{Text: text}}
+	eventData, _ := json.Marshal(event)
+	fmt.Fprintf(w, "data: %s\n\n", eventData)
+	flusher.Flush()
+	return true
+}
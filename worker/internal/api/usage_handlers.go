@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ziggy/internal/workflow/budget"
+)
+
+func (s *Server) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	summary, err := budget.QuerySummary(r.Context(), s.reg, s.budgetWorkflowID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    summary,
+	})
+}
+
+func (s *Server) handleSetUsageLimits(w http.ResponseWriter, r *http.Request) {
+	var limits budget.Limits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if limits.DailyTokens < 0 || limits.DailyCalls < 0 {
+		writeError(w, http.StatusBadRequest, "limits must not be negative")
+		return
+	}
+
+	err := s.reg.SignalWorkflow(r.Context(), s.budgetWorkflowID, budget.SignalSetLimits, limits)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    limits,
+	})
+}
+
+// handleMetrics serves AI usage in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	summary, err := budget.QuerySummary(r.Context(), s.reg, s.budgetWorkflowID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, summary)
+}
+
+func writeMetrics(w io.Writer, summary *budget.Summary) {
+	owner := fmt.Sprintf(`owner="%s"`, escapeLabel(summary.Owner))
+	labels := func(l budget.Series, extra string) string {
+		return fmt.Sprintf(`{%s,operation="%s",provider="%s",model="%s"%s}`, owner,
+			escapeLabel(l.Operation), escapeLabel(l.Provider), escapeLabel(l.Model), extra)
+	}
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("ziggy_ai_calls_total", "counter", "AI provider calls by outcome.")
+	for _, l := range summary.Lifetime {
+		fmt.Fprintf(w, "ziggy_ai_calls_total%s %d\n", labels(l, `,outcome="success"`), l.Calls-l.Failures)
+		fmt.Fprintf(w, "ziggy_ai_calls_total%s %d\n", labels(l, `,outcome="failure"`), l.Failures)
+	}
+
+	metric("ziggy_ai_tokens_total", "counter", "Tokens spent on AI provider calls.")
+	for _, l := range summary.Lifetime {
+		fmt.Fprintf(w, "ziggy_ai_tokens_total%s %d\n", labels(l, `,direction="input"`), l.InputTokens)
+		fmt.Fprintf(w, "ziggy_ai_tokens_total%s %d\n", labels(l, `,direction="output"`), l.OutputTokens)
+	}
+
+	metric("ziggy_ai_latency_seconds", "summary", "AI provider call latency.")
+	for _, l := range summary.Lifetime {
+		fmt.Fprintf(w, "ziggy_ai_latency_seconds_sum%s %g\n", labels(l, ""), l.LatencySeconds)
+		fmt.Fprintf(w, "ziggy_ai_latency_seconds_count%s %d\n", labels(l, ""), l.Calls)
+	}
+
+	metric("ziggy_ai_daily_tokens", "gauge", "Tokens spent today (UTC).")
+	fmt.Fprintf(w, "ziggy_ai_daily_tokens{%s} %d\n", owner, summary.Today.Tokens())
+	metric("ziggy_ai_daily_calls", "gauge", "AI provider calls made today (UTC).")
+	fmt.Fprintf(w, "ziggy_ai_daily_calls{%s} %d\n", owner, summary.Today.Calls)
+	metric("ziggy_ai_daily_token_limit", "gauge", "Daily token limit; 0 is unlimited.")
+	fmt.Fprintf(w, "ziggy_ai_daily_token_limit{%s} %d\n", owner, summary.Limits.DailyTokens)
+	metric("ziggy_ai_daily_call_limit", "gauge", "Daily call limit; 0 is unlimited.")
+	fmt.Fprintf(w, "ziggy_ai_daily_call_limit{%s} %d\n", owner, summary.Limits.DailyCalls)
+
+	exceeded := 0
+	if summary.Exceeded {
+		exceeded = 1
+	}
+	metric("ziggy_ai_budget_exceeded", "gauge", "1 while AI calls are routed to fallbacks.")
+	fmt.Fprintf(w, "ziggy_ai_budget_exceeded{%s} %d\n", owner, exceeded)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
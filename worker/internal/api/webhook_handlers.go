@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"ziggy/internal/workflow/webhook"
+)
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.queryWebhooks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Secrets are only returned once, when the webhook is created
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    hooks,
+	})
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "url must be an absolute http(s) URL")
+		return
+	}
+
+	for _, e := range req.Events {
+		if e != "*" && !webhook.IsValidEventType(e) {
+			writeError(w, http.StatusBadRequest, "unknown event type: "+e)
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = randomHex(32)
+	}
+
+	hook := webhook.Webhook{
+		ID:        "wh_" + randomHex(8),
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedAt: time.Now(),
+	}
+
+	err = s.reg.SignalWorkflow(r.Context(), s.webhookWorkflowID, webhook.SignalRegister, webhook.RegisterSignal{Webhook: hook})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    hook,
+	})
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := s.reg.SignalWorkflow(r.Context(), s.webhookWorkflowID, webhook.SignalDelete, webhook.DeleteSignal{ID: id})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+	})
+}
+
+func (s *Server) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	result, err := s.reg.QueryWorkflow(r.Context(), s.webhookWorkflowID, webhook.QueryDeliveries)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var deliveries []webhook.Delivery
+	if err := decodeResult(result, &deliveries); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filtered := make([]webhook.Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if d.WebhookID == id {
+			filtered = append(filtered, d)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    filtered,
+	})
+}
+
+func (s *Server) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	hooks, err := s.queryWebhooks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	found := false
+	for _, h := range hooks {
+		if h.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+
+	err = s.reg.SignalWorkflow(r.Context(), s.webhookWorkflowID, webhook.SignalTest, webhook.TestSignal{ID: id})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+	})
+}
+
+func (s *Server) queryWebhooks(ctx context.Context) ([]webhook.Webhook, error) {
+	result, err := s.reg.QueryWorkflow(ctx, s.webhookWorkflowID, webhook.QueryWebhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []webhook.Webhook
+	if err := decodeResult(result, &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// decodeResult converts a generic query result into a typed value.
+func decodeResult(result interface{}, out interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,385 @@
+// Package bridge connects Ziggy to a team chat platform. Incoming slash
+// commands and mentions (Slack/Discord-style webhooks) are mapped onto the
+// same workflow signals the web UI uses, and Ziggy's replies are posted back
+// through an outgoing webhook.
+package bridge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+const (
+	HeaderSignature = "X-Slack-Signature"
+	HeaderTimestamp = "X-Slack-Request-Timestamp"
+
+	// MaxClockSkew is how old a signed request may be before it is rejected
+	// as a possible replay.
+	MaxClockSkew = 5 * time.Minute
+)
+
+// Backend is the subset of the Temporal registry the bridge needs.
+type Backend interface {
+	SignalWorkflow(ctx context.Context, workflowID, signalName string, arg interface{}) error
+	QueryWorkflow(ctx context.Context, workflowID, queryType string, args ...interface{}) (interface{}, error)
+}
+
+// Poster delivers a reply to the chat platform.
+type Poster interface {
+	Post(ctx context.Context, url string, msg OutgoingMessage) error
+}
+
+type Config struct {
+	SigningSecret  string
+	OutgoingURL    string
+	WorkflowID     string
+	ChatWorkflowID string
+	ReplyTimeout   time.Duration
+	PollInterval   time.Duration
+}
+
+// OutgoingMessage is accepted by both Slack ("text") and Discord ("content")
+// incoming webhooks.
+type OutgoingMessage struct {
+	ResponseType string `json:"response_type,omitempty"`
+	Text         string `json:"text"`
+	Content      string `json:"content,omitempty"`
+}
+
+func newMessage(text string) OutgoingMessage {
+	return OutgoingMessage{ResponseType: "in_channel", Text: text, Content: text}
+}
+
+type Bridge struct {
+	cfg     Config
+	backend Backend
+	poster  Poster
+	now     func() time.Time
+}
+
+func New(cfg Config, backend Backend, poster Poster) *Bridge {
+	if cfg.ReplyTimeout == 0 {
+		cfg.ReplyTimeout = 60 * time.Second
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 500 * time.Millisecond
+	}
+	if poster == nil {
+		poster = NewHTTPPoster(nil)
+	}
+	return &Bridge{cfg: cfg, backend: backend, poster: poster, now: time.Now}
+}
+
+// Incoming is a normalized chat platform request.
+type Incoming struct {
+	Command     string
+	Text        string
+	User        string
+	ResponseURL string
+}
+
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := b.verify(r.Header, body); err != nil {
+		log.Printf("[Bridge] Rejected request: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		b.handleJSON(w, r.Context(), body)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+	in := Incoming{
+		Command:     form.Get("command"),
+		Text:        form.Get("text"),
+		User:        form.Get("user_name"),
+		ResponseURL: form.Get("response_url"),
+	}
+	b.respond(w, b.Handle(r.Context(), in))
+}
+
+type jsonPayload struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Text        string `json:"text"`
+	User        string `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Event       *struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+		User string `json:"user"`
+	} `json:"event"`
+}
+
+func (b *Bridge) handleJSON(w http.ResponseWriter, ctx context.Context, body []byte) {
+	var p jsonPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	// Slack verifies event subscription URLs with a one-off challenge
+	if p.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, p.Challenge)
+		return
+	}
+
+	in := Incoming{Text: p.Text, User: p.User, ResponseURL: p.ResponseURL}
+	if p.Event != nil {
+		in.Text = p.Event.Text
+		in.User = p.Event.User
+	}
+	in.Text = stripMentions(in.Text)
+
+	b.respond(w, b.Handle(ctx, in))
+}
+
+func (b *Bridge) respond(w http.ResponseWriter, msg OutgoingMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// Handle executes an incoming command or chat message and returns the
+// immediate reply. Chat replies arrive later via the outgoing webhook.
+func (b *Bridge) Handle(ctx context.Context, in Incoming) OutgoingMessage {
+	text := strings.TrimSpace(in.Text)
+
+	// Mentions and free text go to the chat workflow; "/ziggy <verb>" runs a command
+	if in.Command == "" {
+		return b.chat(ctx, in, text)
+	}
+
+	verb, rest, _ := strings.Cut(text, " ")
+	switch strings.ToLower(verb) {
+	case "feed":
+		return b.action(ctx, ziggyworkflow.SignalFeed)
+	case "play":
+		return b.action(ctx, ziggyworkflow.SignalPlay)
+	case "pet":
+		return b.action(ctx, ziggyworkflow.SignalPet)
+	case "wake":
+		return b.action(ctx, ziggyworkflow.SignalWake)
+	case "status", "":
+		return b.status(ctx)
+	case "help":
+		return newMessage(helpText(in.Command))
+	case "say", "chat":
+		return b.chat(ctx, in, strings.TrimSpace(rest))
+	default:
+		return b.chat(ctx, in, text)
+	}
+}
+
+func helpText(command string) string {
+	if command == "" {
+		command = "/ziggy"
+	}
+	return fmt.Sprintf("%[1]s feed | play | pet | wake - take care of Ziggy\n"+
+		"%[1]s status - check on Ziggy\n"+
+		"%[1]s <message> - chat with Ziggy", command)
+}
+
+func (b *Bridge) action(ctx context.Context, signal string) OutgoingMessage {
+	if err := b.backend.SignalWorkflow(ctx, b.cfg.WorkflowID, signal, struct{}{}); err != nil {
+		return newMessage("Ziggy couldn't hear you: " + err.Error())
+	}
+	return b.status(ctx)
+}
+
+func (b *Bridge) status(ctx context.Context) OutgoingMessage {
+	result, err := b.backend.QueryWorkflow(ctx, b.cfg.WorkflowID, ziggyworkflow.QueryState)
+	if err != nil {
+		return newMessage("Ziggy is unreachable: " + err.Error())
+	}
+
+	var state z.State
+	if err := decode(result, &state); err != nil {
+		return newMessage("Ziggy is unreachable: " + err.Error())
+	}
+
+	now := b.now()
+	current := state.CalculateCurrentState(now)
+	resp := current.ToResponse(now)
+	return newMessage(fmt.Sprintf("%s\n\nMood: %s | Stage: %s | Personality: %s\nFullness %.0f | Happiness %.0f | Bond %.0f | HP %.0f",
+		strings.ReplaceAll(resp.Message, "\n", " "),
+		current.GetMood(), resp.Stage, resp.Personality,
+		resp.Fullness, resp.Happiness, resp.Bond, resp.HP))
+}
+
+func (b *Bridge) chat(ctx context.Context, in Incoming, text string) OutgoingMessage {
+	if text == "" {
+		return newMessage(helpText(in.Command))
+	}
+
+	before, err := b.history(ctx)
+	if err != nil {
+		return newMessage("Ziggy is unreachable: " + err.Error())
+	}
+
+	signal := chat.SendMessageSignal{Content: text}
+	if err := b.backend.SignalWorkflow(ctx, b.cfg.ChatWorkflowID, chat.SignalSendMessage, signal); err != nil {
+		return newMessage("Ziggy couldn't hear you: " + err.Error())
+	}
+
+	replyURL := in.ResponseURL
+	if replyURL == "" {
+		replyURL = b.cfg.OutgoingURL
+	}
+	if replyURL != "" {
+		var last chat.Message
+		if n := len(before.Messages); n > 0 {
+			last = before.Messages[n-1]
+		}
+		go b.forwardReply(replyURL, last, text)
+	}
+
+	return OutgoingMessage{Text: "*wiggle* Ziggy is thinking..."}
+}
+
+// forwardReply waits for Ziggy's answer to land in chat history and posts it
+// to the outgoing webhook. last is the newest message before the owner's.
+func (b *Bridge) forwardReply(replyURL string, last chat.Message, text string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.ReplyTimeout)
+	defer cancel()
+
+	reply, err := b.awaitReply(ctx, last, text)
+	if err != nil {
+		log.Printf("[Bridge] No reply from Ziggy: %v", err)
+		return
+	}
+
+	if err := b.poster.Post(ctx, replyURL, newMessage(reply)); err != nil {
+		log.Printf("[Bridge] Failed to post reply: %v", err)
+	}
+}
+
+func (b *Bridge) awaitReply(ctx context.Context, last chat.Message, text string) (string, error) {
+	ticker := time.NewTicker(b.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		history, err := b.history(ctx)
+		if err == nil && !history.IsTyping {
+			if reply, ok := replyTo(history.Messages, last, text); ok {
+				return reply, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// replyTo finds Ziggy's reply to the owner's text among the messages after
+// last. History is trimmed on continue-as-new, so when last is gone the
+// messages after its timestamp are searched instead.
+func replyTo(messages []chat.Message, last chat.Message, text string) (string, bool) {
+	start := 0
+	if last.ID != "" {
+		start = slices.IndexFunc(messages, func(m chat.Message) bool { return m.ID == last.ID }) + 1
+		if start == 0 {
+			start = slices.IndexFunc(messages, func(m chat.Message) bool { return m.Timestamp.After(last.Timestamp) })
+			if start < 0 {
+				return "", false
+			}
+		}
+	}
+
+	asked := false
+	for _, m := range messages[start:] {
+		switch {
+		case !asked && m.Role == "user" && m.Content == text:
+			asked = true
+		case asked && m.Role == "ziggy":
+			return m.Content, true
+		}
+	}
+	return "", false
+}
+
+func (b *Bridge) history(ctx context.Context) (*chat.HistoryResponse, error) {
+	result, err := b.backend.QueryWorkflow(ctx, b.cfg.ChatWorkflowID, chat.QueryChatHistory)
+	if err != nil {
+		return nil, err
+	}
+	var history chat.HistoryResponse
+	if err := decode(result, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+func (b *Bridge) verify(h http.Header, body []byte) error {
+	if b.cfg.SigningSecret == "" {
+		return nil
+	}
+
+	ts, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing timestamp")
+	}
+	if d := b.now().Sub(time.Unix(ts, 0)); d > MaxClockSkew || d < -MaxClockSkew {
+		return fmt.Errorf("stale timestamp")
+	}
+
+	expected := Sign(b.cfg.SigningSecret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(h.Get(HeaderSignature))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Sign computes a Slack-compatible v0 request signature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%d:", timestamp)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var mentionPattern = regexp.MustCompile(`<@[^>]+>`)
+
+func stripMentions(text string) string {
+	return strings.TrimSpace(mentionPattern.ReplaceAllString(text, ""))
+}
+
+func decode(result interface{}, out interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,147 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+)
+
+const testSecret = "bridge-secret"
+
+func newTestBridge() (*Bridge, *FakeBackend, *FakePoster) {
+	backend := NewFakeBackend()
+	poster := NewFakePoster()
+	b := New(Config{
+		SigningSecret:  testSecret,
+		OutgoingURL:    "https://hooks.example.com/out",
+		WorkflowID:     "ziggy-test",
+		ChatWorkflowID: "ziggy-chat-test",
+		PollInterval:   time.Millisecond,
+		ReplyTimeout:   time.Second,
+	}, backend, poster)
+	return b, backend, poster
+}
+
+func signedRequest(body, contentType string, ts time.Time, secret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/bridge/chat", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts.Unix(), []byte(body)))
+	return req
+}
+
+func slashCommand(text string) string {
+	return url.Values{"command": {"/ziggy"}, "text": {text}, "user_name": {"sam"}}.Encode()
+}
+
+func TestSlashCommandFeedSignalsZiggy(t *testing.T) {
+	b, backend, _ := newTestBridge()
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, signedRequest(slashCommand("feed"), "application/x-www-form-urlencoded", time.Now(), testSecret))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(backend.Signals) != 1 || backend.Signals[0] != ziggyworkflow.SignalFeed {
+		t.Errorf("signals = %v, want [%s]", backend.Signals, ziggyworkflow.SignalFeed)
+	}
+	if !strings.Contains(rec.Body.String(), "Fullness") {
+		t.Errorf("expected status in reply, got %s", rec.Body.String())
+	}
+}
+
+func TestMentionIsForwardedToChatAndReplyPosted(t *testing.T) {
+	b, backend, poster := newTestBridge()
+
+	body := `{"type":"event_callback","event":{"type":"app_mention","text":"<@U123> how are you?","user":"U999"}}`
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, signedRequest(body, "application/json", time.Now(), testSecret))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(backend.Signals) != 1 || backend.Signals[0] != chat.SignalSendMessage {
+		t.Fatalf("signals = %v, want [%s]", backend.Signals, chat.SignalSendMessage)
+	}
+	if got := backend.History.Messages[0].Content; got != "how are you?" {
+		t.Errorf("forwarded content = %q, mention not stripped", got)
+	}
+
+	if !poster.Wait(time.Second) {
+		t.Fatal("reply was never posted")
+	}
+	if poster.Messages[0].Text != backend.Reply {
+		t.Errorf("posted %q, want %q", poster.Messages[0].Text, backend.Reply)
+	}
+}
+
+func TestReplyToSurvivesTrimmedHistory(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	msg := func(id, role, content string, minute int) chat.Message {
+		return chat.Message{ID: id, Role: role, Content: content, Timestamp: at.Add(time.Duration(minute) * time.Minute)}
+	}
+	last := msg("msg-39", "ziggy", "*yawn*", 0)
+
+	// The owner's message landed just after last
+	history := []chat.Message{last, msg("msg-40", "user", "hi", 1), msg("msg-41", "ziggy", "hello!", 1)}
+	if reply, ok := replyTo(history, last, "hi"); !ok || reply != "hello!" {
+		t.Errorf("replyTo = %q, %v", reply, ok)
+	}
+
+	// Continue-as-new trimmed last away, and the history is no longer
+	// than before
+	trimmed := []chat.Message{msg("msg-40", "user", "hi", 1), msg("msg-41", "ziggy", "hello!", 1)}
+	if reply, ok := replyTo(trimmed, last, "hi"); !ok || reply != "hello!" {
+		t.Errorf("replyTo trimmed = %q, %v", reply, ok)
+	}
+
+	// Ziggy hasn't answered yet
+	if _, ok := replyTo(history[:2], last, "hi"); ok {
+		t.Error("replyTo found a reply before Ziggy answered")
+	}
+}
+
+func TestRejectsBadSignatures(t *testing.T) {
+	b, backend, _ := newTestBridge()
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"wrong secret", signedRequest(slashCommand("feed"), "application/x-www-form-urlencoded", time.Now(), "nope")},
+		{"stale timestamp", signedRequest(slashCommand("feed"), "application/x-www-form-urlencoded", time.Now().Add(-10*time.Minute), testSecret)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			b.ServeHTTP(rec, tt.req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+
+	if len(backend.Signals) != 0 {
+		t.Errorf("rejected requests still signalled: %v", backend.Signals)
+	}
+}
+
+func TestURLVerificationChallenge(t *testing.T) {
+	b, _, _ := newTestBridge()
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, signedRequest(`{"type":"url_verification","challenge":"abc123"}`, "application/json", time.Now(), testSecret))
+
+	if rec.Body.String() != "abc123" {
+		t.Errorf("challenge response = %q", rec.Body.String())
+	}
+}
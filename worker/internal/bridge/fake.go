@@ -0,0 +1,95 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+// FakeBackend stands in for Temporal so the bridge can run locally and in
+// tests. It records signals and answers chat messages with a canned reply.
+type FakeBackend struct {
+	mu      sync.Mutex
+	State   z.State
+	History chat.HistoryResponse
+	Reply   string
+	Signals []string
+}
+
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		State: z.NewState("UTC"),
+		Reply: "*wiggle*\nHello from the fake!",
+	}
+}
+
+func (f *FakeBackend) SignalWorkflow(ctx context.Context, workflowID, signalName string, arg interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Signals = append(f.Signals, signalName)
+
+	switch signalName {
+	case ziggyworkflow.SignalFeed, ziggyworkflow.SignalPlay, ziggyworkflow.SignalPet, ziggyworkflow.SignalWake:
+		f.State.LastAction = z.Action(signalName)
+		f.State.Message = "*happy wiggle*"
+	case chat.SignalSendMessage:
+		msg, ok := arg.(chat.SendMessageSignal)
+		if !ok {
+			return fmt.Errorf("unexpected signal payload %T", arg)
+		}
+		now := time.Now()
+		f.History.Messages = append(f.History.Messages,
+			chat.Message{ID: fmt.Sprintf("msg-%d", len(f.History.Messages)), Role: "user", Content: msg.Content, Timestamp: now},
+			chat.Message{ID: fmt.Sprintf("msg-%d", len(f.History.Messages)+1), Role: "ziggy", Content: f.Reply, Timestamp: now},
+		)
+	}
+	return nil
+}
+
+func (f *FakeBackend) QueryWorkflow(ctx context.Context, workflowID, queryType string, args ...interface{}) (interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch queryType {
+	case ziggyworkflow.QueryState:
+		return f.State, nil
+	case chat.QueryChatHistory:
+		return f.History, nil
+	}
+	return nil, fmt.Errorf("unknown query %q", queryType)
+}
+
+// FakePoster collects outgoing replies instead of posting them.
+type FakePoster struct {
+	mu       sync.Mutex
+	Messages []OutgoingMessage
+	posted   chan struct{}
+}
+
+func NewFakePoster() *FakePoster {
+	return &FakePoster{posted: make(chan struct{}, 16)}
+}
+
+func (p *FakePoster) Post(ctx context.Context, url string, msg OutgoingMessage) error {
+	p.mu.Lock()
+	p.Messages = append(p.Messages, msg)
+	p.mu.Unlock()
+	p.posted <- struct{}{}
+	return nil
+}
+
+// Wait blocks until a reply is posted or the timeout elapses.
+func (p *FakePoster) Wait(timeout time.Duration) bool {
+	select {
+	case <-p.posted:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,238 @@
+// Package lessons pulls examples for the educational topics out of the
+// owner's own workflow histories, so Ziggy can point at real signals,
+// activities, timers and continue-as-new events instead of made-up ones.
+package lessons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+
+	"ziggy/internal/workflow/pool_regenerator"
+)
+
+const (
+	// MaxEvents caps how many of the most recent matching events a lesson
+	// shows.
+	MaxEvents = 20
+
+	// maxRuns is how many runs back the continue-as-new lesson follows.
+	maxRuns = 3
+)
+
+// Which workflow a lesson reads.
+const (
+	WorkflowZiggy           = "ziggy"
+	WorkflowPoolRegenerator = "pool_regenerator"
+)
+
+// ErrUnknownLesson is returned for a lesson ID that doesn't exist.
+var ErrUnknownLesson = errors.New("unknown lesson")
+
+// HistorySource reads workflow histories; the registry is one.
+type HistorySource interface {
+	WorkflowHistory(ctx context.Context, workflowID, runID string) ([]*historypb.HistoryEvent, error)
+}
+
+// Lesson ties an educational topic to the history events that show it.
+type Lesson struct {
+	// ID is the educational topic's mystery ID.
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Workflow string `json:"workflow"`
+
+	types []enumspb.EventType
+}
+
+var lessons = []Lesson{
+	{
+		ID:       "signals-queries",
+		Title:    "Signals Ziggy received",
+		Workflow: WorkflowZiggy,
+		types:    []enumspb.EventType{enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED},
+	},
+	{
+		ID:       "activities",
+		Title:    "Activities Ziggy ran",
+		Workflow: WorkflowZiggy,
+		types: []enumspb.EventType{
+			enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED,
+			enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED,
+			enumspb.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT,
+		},
+	},
+	{
+		ID:       "continue-as-new",
+		Title:    "Ziggy's runs continuing as new",
+		Workflow: WorkflowZiggy,
+		types: []enumspb.EventType{
+			enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED,
+			enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CONTINUED_AS_NEW,
+		},
+	},
+	{
+		ID:       "timers",
+		Title:    "Timers in the pool regenerator",
+		Workflow: WorkflowPoolRegenerator,
+		types: []enumspb.EventType{
+			enumspb.EVENT_TYPE_TIMER_STARTED,
+			enumspb.EVENT_TYPE_TIMER_FIRED,
+		},
+	},
+}
+
+// All lists the lessons.
+func All() []Lesson {
+	return lessons
+}
+
+// Find returns the lesson for an educational topic, or nil.
+func Find(id string) *Lesson {
+	for _, l := range lessons {
+		if l.ID == id {
+			return &l
+		}
+	}
+	return nil
+}
+
+// Event is one history event, described for the owner.
+type Event struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	WorkflowID string    `json:"workflowId"`
+	RunID      string    `json:"runId,omitempty"`
+	Summary    string    `json:"summary"`
+}
+
+// String formats e for a prompt.
+func (e Event) String() string {
+	return fmt.Sprintf("%s: %s", e.Time.UTC().Format("2006-01-02 15:04 UTC"), e.Summary)
+}
+
+// Evidence is a lesson with the events that show it, oldest first.
+type Evidence struct {
+	Lesson Lesson  `json:"lesson"`
+	Events []Event `json:"events"`
+}
+
+// Collect reads the lesson's events from the histories of the owner's
+// Ziggy workflow, ziggyID, and the workflows that serve it.
+func Collect(ctx context.Context, src HistorySource, ziggyID, lessonID string) (*Evidence, error) {
+	lesson := Find(lessonID)
+	if lesson == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLesson, lessonID)
+	}
+
+	workflowID := ziggyID
+	if lesson.Workflow == WorkflowPoolRegenerator {
+		workflowID = ziggyID + pool_regenerator.WorkflowIDSuffix
+	}
+
+	runs := 1
+	if lesson.ID == "continue-as-new" {
+		runs = maxRuns
+	}
+
+	// Runs are read newest first, following each back to the run it
+	// continued from
+	var events []Event
+	runID := ""
+	for range runs {
+		history, err := src.WorkflowHistory(ctx, workflowID, runID)
+		if err != nil {
+			return nil, fmt.Errorf("read %s history: %w", workflowID, err)
+		}
+		run := describe(lesson, workflowID, history)
+		events = append(run, events...)
+
+		runID = ""
+		if len(history) > 0 {
+			runID = history[0].GetWorkflowExecutionStartedEventAttributes().GetContinuedExecutionRunId()
+		}
+		if runID == "" {
+			break
+		}
+	}
+
+	if len(events) > MaxEvents {
+		events = events[len(events)-MaxEvents:]
+	}
+	return &Evidence{Lesson: *lesson, Events: events}, nil
+}
+
+// describe summarizes one run's events of the lesson's types.
+func describe(lesson *Lesson, workflowID string, history []*historypb.HistoryEvent) []Event {
+	var runID string
+	activities := map[int64]string{}
+	if len(history) > 0 {
+		runID = history[0].GetWorkflowExecutionStartedEventAttributes().GetOriginalExecutionRunId()
+	}
+
+	events := []Event{}
+	for _, e := range history {
+		if attrs := e.GetActivityTaskScheduledEventAttributes(); attrs != nil {
+			activities[e.GetEventId()] = attrs.GetActivityType().GetName()
+		}
+		if !wanted(lesson, e.GetEventType()) {
+			continue
+		}
+		events = append(events, Event{
+			ID:         e.GetEventId(),
+			Time:       e.GetEventTime().AsTime(),
+			Type:       e.GetEventType().String(),
+			WorkflowID: workflowID,
+			RunID:      runID,
+			Summary:    summarize(e, activities, len(history)),
+		})
+	}
+	return events
+}
+
+func wanted(lesson *Lesson, t enumspb.EventType) bool {
+	for _, want := range lesson.types {
+		if t == want {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize says what an event means in a few words.
+func summarize(e *historypb.HistoryEvent, activities map[int64]string, runLength int) string {
+	switch e.GetEventType() {
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED:
+		return fmt.Sprintf("received the %q signal", e.GetWorkflowExecutionSignaledEventAttributes().GetSignalName())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+		return fmt.Sprintf("activity %s completed", activities[e.GetActivityTaskCompletedEventAttributes().GetScheduledEventId()])
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+		attrs := e.GetActivityTaskFailedEventAttributes()
+		return fmt.Sprintf("activity %s failed: %s", activities[attrs.GetScheduledEventId()], attrs.GetFailure().GetMessage())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT:
+		return fmt.Sprintf("activity %s timed out", activities[e.GetActivityTaskTimedOutEventAttributes().GetScheduledEventId()])
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
+		if from := e.GetWorkflowExecutionStartedEventAttributes().GetContinuedExecutionRunId(); from != "" {
+			return fmt.Sprintf("a new run started with a fresh history, continuing run %s", shortID(from))
+		}
+		return "the first run started"
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CONTINUED_AS_NEW:
+		return fmt.Sprintf("the run continued as new after %d events", runLength)
+	case enumspb.EVENT_TYPE_TIMER_STARTED:
+		return fmt.Sprintf("started a durable timer for %s", e.GetTimerStartedEventAttributes().GetStartToFireTimeout().AsDuration())
+	case enumspb.EVENT_TYPE_TIMER_FIRED:
+		return "the timer fired"
+	}
+	return e.GetEventType().String()
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
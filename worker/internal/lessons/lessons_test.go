@@ -0,0 +1,141 @@
+package lessons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeHistory serves canned histories by workflow and run ID.
+type fakeHistory map[string][]*historypb.HistoryEvent
+
+func (f fakeHistory) WorkflowHistory(ctx context.Context, workflowID, runID string) ([]*historypb.HistoryEvent, error) {
+	events, ok := f[workflowID+"/"+runID]
+	if !ok {
+		return nil, fmt.Errorf("no history for %s/%s", workflowID, runID)
+	}
+	return events, nil
+}
+
+var start = time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+func event(id int64, e *historypb.HistoryEvent) *historypb.HistoryEvent {
+	e.EventId = id
+	e.EventTime = timestamppb.New(start.Add(time.Duration(id) * time.Minute))
+	return e
+}
+
+func started(runID, continuedFrom string) *historypb.HistoryEvent {
+	return &historypb.HistoryEvent{
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionStartedEventAttributes{
+			WorkflowExecutionStartedEventAttributes: &historypb.WorkflowExecutionStartedEventAttributes{
+				OriginalExecutionRunId:  runID,
+				ContinuedExecutionRunId: continuedFrom,
+			},
+		},
+	}
+}
+
+func signaled(name string) *historypb.HistoryEvent {
+	return &historypb.HistoryEvent{
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionSignaledEventAttributes{
+			WorkflowExecutionSignaledEventAttributes: &historypb.WorkflowExecutionSignaledEventAttributes{SignalName: name},
+		},
+	}
+}
+
+func testHistories() fakeHistory {
+	return fakeHistory{
+		"ziggy-alice/": {
+			event(1, started("run-2", "run-1")),
+			event(2, signaled("feed")),
+			event(3, &historypb.HistoryEvent{
+				EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED,
+				Attributes: &historypb.HistoryEvent_ActivityTaskScheduledEventAttributes{
+					ActivityTaskScheduledEventAttributes: &historypb.ActivityTaskScheduledEventAttributes{
+						ActivityType: &commonpb.ActivityType{Name: "GenerateMessagePool"},
+					},
+				},
+			}),
+			event(4, &historypb.HistoryEvent{
+				EventType: enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED,
+				Attributes: &historypb.HistoryEvent_ActivityTaskCompletedEventAttributes{
+					ActivityTaskCompletedEventAttributes: &historypb.ActivityTaskCompletedEventAttributes{ScheduledEventId: 3},
+				},
+			}),
+			event(5, signaled("pet")),
+		},
+		"ziggy-alice/run-1": {
+			event(1, started("run-1", "")),
+			event(2, signaled("play")),
+			event(3, &historypb.HistoryEvent{EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CONTINUED_AS_NEW}),
+		},
+		"ziggy-alice-pool-regenerator/": {
+			event(1, started("run-p", "")),
+			event(2, &historypb.HistoryEvent{
+				EventType: enumspb.EVENT_TYPE_TIMER_STARTED,
+				Attributes: &historypb.HistoryEvent_TimerStartedEventAttributes{
+					TimerStartedEventAttributes: &historypb.TimerStartedEventAttributes{StartToFireTimeout: durationpb.New(6 * time.Hour)},
+				},
+			}),
+			event(3, &historypb.HistoryEvent{EventType: enumspb.EVENT_TYPE_TIMER_FIRED}),
+		},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		lesson string
+		want   []string
+	}{
+		{"signals-queries", []string{
+			`2026-10-19 09:02 UTC: received the "feed" signal`,
+			`2026-10-19 09:05 UTC: received the "pet" signal`,
+		}},
+		{"activities", []string{
+			"2026-10-19 09:04 UTC: activity GenerateMessagePool completed",
+		}},
+		{"continue-as-new", []string{
+			"2026-10-19 09:01 UTC: the first run started",
+			"2026-10-19 09:03 UTC: the run continued as new after 3 events",
+			"2026-10-19 09:01 UTC: a new run started with a fresh history, continuing run run-1",
+		}},
+		{"timers", []string{
+			"2026-10-19 09:02 UTC: started a durable timer for 6h0m0s",
+			"2026-10-19 09:03 UTC: the timer fired",
+		}},
+	}
+	for _, tt := range tests {
+		evidence, err := Collect(context.Background(), testHistories(), "ziggy-alice", tt.lesson)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.lesson, err)
+		}
+		var got []string
+		for _, e := range evidence.Events {
+			got = append(got, e.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.lesson, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	if _, err := Collect(context.Background(), testHistories(), "ziggy-alice", "replay"); !errors.Is(err, ErrUnknownLesson) {
+		t.Errorf("Collect(replay) error = %v, want ErrUnknownLesson", err)
+	}
+	if _, err := Collect(context.Background(), testHistories(), "ziggy-bob", "timers"); err == nil {
+		t.Error("Collect() for a missing workflow succeeded")
+	}
+}
@@ -0,0 +1,132 @@
+// Package locale lists the languages Ziggy speaks and measures text the way
+// the pet's display does, where CJK characters take two columns.
+package locale
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default is the language of the built-in pools, messages and mysteries.
+const Default = "en"
+
+// The pet's display shows DisplayLines lines of DisplayColumns columns.
+const (
+	DisplayLines   = 3
+	DisplayColumns = 24
+)
+
+// Info describes a supported locale.
+type Info struct {
+	Code string `json:"code"`
+	// Name is the language's own name for itself, for pickers.
+	Name string `json:"name"`
+	// Language is the English name models are told to write in.
+	Language string `json:"language"`
+}
+
+var locales = []Info{
+	{Code: "en", Name: "English", Language: "English"},
+	{Code: "es", Name: "Español", Language: "Spanish"},
+	{Code: "ja", Name: "日本語", Language: "Japanese"},
+}
+
+// All lists the supported locales, English first.
+func All() []Info {
+	return append([]Info(nil), locales...)
+}
+
+// Find returns the locale with the code, or false. Codes are matched
+// without case, and "" is English.
+func Find(code string) (Info, bool) {
+	code = Normalize(code)
+	for _, l := range locales {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return Info{}, false
+}
+
+// Valid reports whether the code names a supported locale.
+func Valid(code string) bool {
+	_, ok := Find(code)
+	return ok
+}
+
+// Normalize lowercases a code and maps "" to Default.
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return Default
+	}
+	return code
+}
+
+// Language is the English name of the code's language for prompts, or ""
+// for English and unknown codes, which need no instruction.
+func Language(code string) string {
+	l, ok := Find(code)
+	if !ok || l.Code == Default {
+		return ""
+	}
+	return l.Language
+}
+
+// Width is how many display columns s takes: East Asian wide and fullwidth
+// characters take two, combining marks and format characters none.
+func Width(s string) int {
+	w := 0
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case wide(r):
+			w += 2
+		default:
+			w++
+		}
+	}
+	return w
+}
+
+func wide(r rune) bool {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK radicals and punctuation
+		r >= 0x3041 && r <= 0x33FF, // kana and CJK compatibility
+		r >= 0x3400 && r <= 0x4DBF, // CJK extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK unified ideographs
+		r >= 0xA000 && r <= 0xA4CF, // Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60, // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extensions B and later
+		return true
+	}
+	return false
+}
+
+// Fits reports whether text is non-blank and fits lines lines of width
+// columns.
+func Fits(text string, lines, width int) bool {
+	if strings.TrimSpace(text) == "" {
+		return false
+	}
+	split := strings.Split(text, "\n")
+	if len(split) > lines {
+		return false
+	}
+	for _, line := range split {
+		if Width(line) > width {
+			return false
+		}
+	}
+	return true
+}
+
+// FitsDisplay reports whether text fits the pet's display.
+func FitsDisplay(text string) bool {
+	return Fits(text, DisplayLines, DisplayColumns)
+}
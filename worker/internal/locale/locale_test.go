@@ -0,0 +1,62 @@
+package locale
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"Hello!", 6},
+		{"¡Hola, qué tal!", 15},
+		{"おなかすいた", 12},
+		{"*もぐもぐ*", 10},
+		{"ＡＢＣ", 6},
+		{"cafe\u0301", 4},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := Width(tt.text); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFitsDisplay(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Adequate.\nI've survived\nworse famines.", true},
+		{"This line is far too long for the display", false},
+		{"one\ntwo\nthree\nfour", false},
+		{"   ", false},
+		// Twelve kana fill a line; thirteen overflow it even though they
+		// are well under 24 runes
+		{"あいうえおかきくけこさし", true},
+		{"あいうえおかきくけこさしす", false},
+	}
+	for _, tt := range tests {
+		if got := FitsDisplay(tt.text); got != tt.want {
+			t.Errorf("FitsDisplay(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	if l, ok := Find("JA"); !ok || l.Language != "Japanese" {
+		t.Errorf("Find(JA) = %+v, %v", l, ok)
+	}
+	if l, ok := Find(""); !ok || l.Code != Default {
+		t.Errorf("Find(\"\") = %+v, %v", l, ok)
+	}
+	if Valid("xx") {
+		t.Error("Valid(xx) = true")
+	}
+	if got := Language("en"); got != "" {
+		t.Errorf("Language(en) = %q, want none", got)
+	}
+	if got := Language("es"); got != "Spanish" {
+		t.Errorf("Language(es) = %q", got)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"sync"
 	"time"
 
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
 )
@@ -140,7 +143,11 @@ func (r *Registry) StartWorker(ctx context.Context) error {
 	registrations := r.registrations
 	r.mu.RUnlock()
 
-	w := worker.New(c, cfg.TaskQueue, worker.Options{})
+	w := worker.New(c, cfg.TaskQueue, worker.Options{
+		// Chat replies stream to the UI through heartbeat details, so send
+		// them promptly rather than every 30 seconds.
+		DefaultHeartbeatThrottleInterval: 250 * time.Millisecond,
+	})
 
 	// Register legacy registrations (from AddWorkflow/AddActivity)
 	for _, reg := range registrations {
@@ -319,6 +326,58 @@ func (r *Registry) DescribeWorkflow(ctx context.Context, workflowID string) (*Wo
 	return status, nil
 }
 
+// PendingActivityDetails decodes the latest heartbeat details of the named
+// activity while it is running in the workflow. It reports false when no
+// such activity is pending or it hasn't heartbeated yet.
+func (r *Registry) PendingActivityDetails(ctx context.Context, workflowID, activityType string, out interface{}) (bool, error) {
+	r.mu.RLock()
+	if r.client == nil {
+		r.mu.RUnlock()
+		return false, fmt.Errorf("registry not initialized")
+	}
+	c := r.client
+	r.mu.RUnlock()
+
+	desc, err := c.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return false, err
+	}
+
+	for _, pending := range desc.PendingActivities {
+		if pending.GetActivityType().GetName() != activityType || pending.GetHeartbeatDetails() == nil {
+			continue
+		}
+		if err := converter.GetDefaultDataConverter().FromPayloads(pending.GetHeartbeatDetails(), out); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// WorkflowHistory reads every event of a workflow run, the latest run when
+// runID is empty.
+func (r *Registry) WorkflowHistory(ctx context.Context, workflowID, runID string) ([]*historypb.HistoryEvent, error) {
+	r.mu.RLock()
+	if r.client == nil {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("registry not initialized")
+	}
+	c := r.client
+	r.mu.RUnlock()
+
+	var events []*historypb.HistoryEvent
+	iter := c.GetWorkflowHistory(ctx, workflowID, runID, false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
 func (r *Registry) TerminateWorkflow(ctx context.Context, workflowID, reason string) error {
 	r.mu.RLock()
 	if r.client == nil {
@@ -0,0 +1,110 @@
+// Package savefile serializes a Ziggy and its chat into a portable,
+// versioned and checksummed JSON document so a pet can be backed up or moved
+// between Temporal namespaces.
+package savefile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"ziggy/internal/workflow/chat"
+	z "ziggy/internal/ziggy"
+)
+
+const (
+	Format = "ziggy-save"
+
+	// Version is bumped whenever the payload layout changes incompatibly.
+	Version = 1
+)
+
+var ErrChecksum = errors.New("save file checksum mismatch")
+
+type SaveFile struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Owner      string    `json:"owner"`
+	Checksum   string    `json:"checksum"`
+	Data       Payload   `json:"data"`
+}
+
+type Payload struct {
+	Ziggy z.State     `json:"ziggy"`
+	Chat  *chat.State `json:"chat,omitempty"`
+}
+
+func New(owner string, ziggy z.State, chatState *chat.State, now time.Time) (*SaveFile, error) {
+	f := &SaveFile{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: now,
+		Owner:      owner,
+		Data:       Payload{Ziggy: ziggy, Chat: chatState},
+	}
+	sum, err := checksum(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	f.Checksum = sum
+	return f, nil
+}
+
+func checksum(p Payload) (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return sumBytes(data), nil
+}
+
+func sumBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (f *SaveFile) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// Decode reads a save file and verifies its format, version and checksum.
+// The checksum covers the payload bytes as written, so files stay valid after
+// new fields are added to the state types.
+func Decode(r io.Reader) (*SaveFile, error) {
+	var envelope struct {
+		SaveFile
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("parse save file: %w", err)
+	}
+
+	f := envelope.SaveFile
+	if f.Format != Format {
+		return nil, fmt.Errorf("not a ziggy save file (format %q)", f.Format)
+	}
+	if f.Version < 1 || f.Version > Version {
+		return nil, fmt.Errorf("unsupported save file version %d (this build reads up to %d)", f.Version, Version)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, envelope.Data); err != nil {
+		return nil, fmt.Errorf("parse save file data: %w", err)
+	}
+	if sumBytes(compact.Bytes()) != f.Checksum {
+		return nil, ErrChecksum
+	}
+
+	if err := json.Unmarshal(envelope.Data, &f.Data); err != nil {
+		return nil, fmt.Errorf("parse save file data: %w", err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,131 @@
+package savefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"ziggy/internal/ai"
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+func TestRoundTrip(t *testing.T) {
+	state := z.NewState("UTC")
+	state.Bond = 88
+	state.Personality = z.PersonalityCheerful
+	state.RuntimePool = &z.MessagePool{FeedSuccess: []string{"Yum!\n<3 & more"}}
+
+	chatState := chat.NewState("alice")
+	chatState.Solved = []string{"missing-snack"}
+
+	f, err := New("alice", state, &chatState, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Data.Ziggy.Bond != 88 || got.Data.Ziggy.Personality != z.PersonalityCheerful {
+		t.Errorf("ziggy state not preserved: %+v", got.Data.Ziggy)
+	}
+	if got.Data.Ziggy.RuntimePool == nil || got.Data.Ziggy.RuntimePool.FeedSuccess[0] != "Yum!\n<3 & more" {
+		t.Error("runtime pool not preserved")
+	}
+	if len(got.Data.Chat.Solved) != 1 {
+		t.Error("solved mysteries not preserved")
+	}
+}
+
+func TestImportResumesExportedChat(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	exported := chat.NewState("alice")
+	exported.CreatedAt = now.Add(-48 * time.Hour)
+	exported.Track = chat.TrackEducational
+	exported.Locale = "de"
+	exported.NextMessageID = 1
+	exported.AddMessage("user", "hallo", now.Add(-time.Hour))
+	exported.AddMessage("ziggy", "*wackel*", now.Add(-time.Hour))
+	exported.Solved = []string{"missing-snack"}
+	exported.Violations = []chat.Violation{{Violation: ai.Violation{Source: "input", Kind: "pii"}, At: now.Add(-2 * time.Hour)}}
+	exported.Daily = &chat.DailyMystery{MysteryID: "missing-snack", Date: "2026-10-19"}
+	exported.Quiz = &chat.QuizSession{MysteryID: "go-channels", Concept: "channels", Indexes: []int{0}, Results: []bool{}}
+	exported.Proactive = chat.Proactive{
+		Limits:     &chat.ProactiveLimits{MaxPerDay: 3, MinGapMinutes: 90},
+		Timezone:   "Europe/Berlin",
+		Sent:       []time.Time{now.Add(-3 * time.Hour)},
+		Need:       z.NeedFood,
+		Complaints: 2,
+	}
+
+	f, err := New("alice", z.NewState("UTC"), &exported, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(now)
+	var imported chat.State
+	env.RegisterDelayedCallback(func() {
+		result, err := env.QueryWorkflow(chat.QueryChatState)
+		if err != nil {
+			t.Errorf("query: %v", err)
+		} else if err := result.Get(&imported); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		env.CancelWorkflow()
+	}, time.Second)
+	env.ExecuteWorkflow(chat.Workflow, chatInput("alice", ziggyworkflow.WorkflowID("alice"), saved.Data.Chat))
+
+	want, _ := json.Marshal(exported)
+	got, _ := json.Marshal(imported)
+	if string(got) != string(want) {
+		t.Errorf("imported chat state =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	f, err := New("alice", z.NewState("UTC"), nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(buf.String(), `"bond": 50`, `"bond": 100`, 1)
+
+	if _, err := Decode(strings.NewReader(tampered)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Decode() error = %v, want ErrChecksum", err)
+	}
+}
+
+func TestDecodeRejectsFutureVersion(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"format":"ziggy-save","version":99,"data":{}}`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Decode() error = %v, want unsupported version", err)
+	}
+}
@@ -0,0 +1,101 @@
+package savefile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ziggy/internal/registry"
+	"ziggy/internal/workflow/chat"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+const statusRunning = "WORKFLOW_EXECUTION_STATUS_RUNNING"
+
+var ErrRunning = errors.New("workflow is already running (use force to replace it)")
+
+// Export queries an owner's running workflows and packages their state.
+// A missing chat workflow is not an error; the save simply has no chat.
+func Export(ctx context.Context, reg *registry.Registry, owner string) (*SaveFile, error) {
+	result, err := reg.QueryWorkflow(ctx, ziggyworkflow.WorkflowID(owner), ziggyworkflow.QueryState)
+	if err != nil {
+		return nil, fmt.Errorf("query ziggy state: %w", err)
+	}
+	var state z.State
+	if err := decode(result, &state); err != nil {
+		return nil, err
+	}
+
+	var chatState *chat.State
+	if result, err := reg.QueryWorkflow(ctx, chat.WorkflowID(owner), chat.QueryChatState); err == nil {
+		chatState = &chat.State{}
+		if err := decode(result, chatState); err != nil {
+			return nil, err
+		}
+	}
+
+	return New(owner, state, chatState, time.Now())
+}
+
+// Import starts fresh Ziggy and chat workflows for owner from a save file.
+// Running workflows are only replaced when force is set.
+func Import(ctx context.Context, reg *registry.Registry, owner string, f *SaveFile, force bool) error {
+	ziggyID := ziggyworkflow.WorkflowID(owner)
+	chatID := chat.WorkflowID(owner)
+
+	for _, id := range []string{ziggyID, chatID} {
+		status, err := reg.DescribeWorkflow(ctx, id)
+		if err != nil || status.Status != statusRunning {
+			continue
+		}
+		if !force {
+			return fmt.Errorf("%s: %w", id, ErrRunning)
+		}
+		if err := reg.TerminateWorkflow(ctx, id, "replaced by save file import"); err != nil {
+			return fmt.Errorf("terminate %s: %w", id, err)
+		}
+	}
+
+	snapshot := f.Data.Ziggy
+	_, err := reg.ExecuteWorkflow(ctx, ziggyID, "ZiggyWorkflow", ziggyworkflow.Input{
+		Owner:      owner,
+		Timezone:   snapshot.Timezone,
+		Generation: snapshot.Generation,
+		CreatedAt:  snapshot.CreatedAt,
+		Snapshot:   &snapshot,
+	})
+	if err != nil {
+		return fmt.Errorf("start ziggy workflow: %w", err)
+	}
+
+	input := chatInput(owner, ziggyID, f.Data.Chat)
+	if _, err := reg.ExecuteWorkflow(ctx, chatID, "ChatWorkflow", input); err != nil {
+		return fmt.Errorf("start chat workflow: %w", err)
+	}
+
+	return nil
+}
+
+// chatInput starts the chat workflow from the saved chat state, or afresh
+// when the save has none.
+func chatInput(owner, ziggyID string, c *chat.State) chat.Input {
+	input := chat.Input{Owner: owner, ZiggyID: ziggyID, Track: chat.TrackFun}
+	if c != nil {
+		input.Snapshot = c
+		if c.Track != "" {
+			input.Track = c.Track
+		}
+	}
+	return input
+}
+
+func decode(result interface{}, out interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,112 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"ziggy/internal/ai"
+)
+
+func TestBudgetWorkflowEnforcesDailyLimit(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	// Start mid-morning so the test's hours stay within one UTC day
+	env.SetStartTime(time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC))
+
+	chat := ai.Usage{Operation: ai.OperationChat, Provider: "fake", Model: "m", InputTokens: 400, OutputTokens: 100, Latency: 2 * time.Second, Success: true}
+	failed := ai.Usage{Operation: ai.OperationPool, Provider: "fake", Model: "m", InputTokens: 300, Success: false}
+
+	query := func(out *Summary) {
+		result, err := env.QueryWorkflow(QueryUsage)
+		if err != nil {
+			t.Errorf("query: %v", err)
+			return
+		}
+		if err := result.Get(out); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}
+
+	var underLimit, overLimit, raised, nextDay Summary
+	env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalRecordUsage, chat) }, time.Minute)
+	env.RegisterDelayedCallback(func() { query(&underLimit) }, 2*time.Minute)
+	env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalRecordUsage, failed) }, 3*time.Minute)
+	env.RegisterDelayedCallback(func() { query(&overLimit) }, 4*time.Minute)
+	env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalSetLimits, Limits{DailyTokens: 5000}) }, 5*time.Minute)
+	env.RegisterDelayedCallback(func() { query(&raised) }, 6*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		query(&nextDay)
+		env.CancelWorkflow()
+	}, 16*time.Hour)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", Limits: Limits{DailyTokens: 800}})
+
+	if underLimit.Exceeded || underLimit.Today.Tokens() != 500 || underLimit.Day != "2026-03-14" {
+		t.Errorf("after one call: %+v", underLimit)
+	}
+	if !overLimit.Exceeded || overLimit.ExceededBy != "tokens" {
+		t.Errorf("after 800 tokens: exceeded=%v by %q", overLimit.Exceeded, overLimit.ExceededBy)
+	}
+	if op := overLimit.TodayByOp[ai.OperationPool]; op.Calls != 1 || op.Failures != 1 {
+		t.Errorf("pool totals = %+v", op)
+	}
+	if raised.Exceeded {
+		t.Error("still exceeded after raising the limit")
+	}
+
+	if nextDay.Day != "2026-03-15" || nextDay.Today.Calls != 0 || nextDay.Exceeded {
+		t.Errorf("next day: %+v", nextDay)
+	}
+	if len(nextDay.Lifetime) != 2 || len(nextDay.Recent) != 2 {
+		t.Fatalf("lifetime = %+v, recent = %d", nextDay.Lifetime, len(nextDay.Recent))
+	}
+	if l := nextDay.Lifetime[0]; l.Operation != ai.OperationChat || l.Calls != 1 || l.LatencySeconds != 2 {
+		t.Errorf("chat series = %+v", l)
+	}
+}
+
+func TestBudgetWorkflowKeepsBufferedUsageAcrossContinueAsNew(t *testing.T) {
+	done := false
+	defer func(f func(workflow.Context) bool) { shouldContinueAsNew = f }(shouldContinueAsNew)
+	shouldContinueAsNew = func(workflow.Context) bool { return done }
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC))
+
+	chat := ai.Usage{Operation: ai.OperationChat, Provider: "fake", Model: "m", InputTokens: 400, OutputTokens: 100, Success: true}
+	pool := ai.Usage{Operation: ai.OperationPool, Provider: "fake", Model: "m", InputTokens: 300, Success: true}
+
+	// Both signals are buffered in one workflow task once the history
+	// threshold is reached; the first wakes the loop and the second must be
+	// drained before continuing as new
+	env.RegisterDelayedCallback(func() {
+		done = true
+		env.SignalWorkflowSkippingWorkflowTask(SignalRecordUsage, chat)
+		env.SignalWorkflow(SignalRecordUsage, pool)
+	}, time.Minute)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", Limits: Limits{DailyTokens: 5000}})
+
+	var can *workflow.ContinueAsNewError
+	if !errors.As(env.GetWorkflowError(), &can) {
+		t.Fatalf("workflow error = %v, want continue-as-new", env.GetWorkflowError())
+	}
+	var next Input
+	if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+		t.Fatalf("decode continue-as-new input: %v", err)
+	}
+	if next.Summary == nil {
+		t.Fatal("continue-as-new input has no summary")
+	}
+	if got := next.Summary.Today; got.Calls != 2 || got.Tokens() != 800 {
+		t.Errorf("carried totals = %+v, want 2 calls and 800 tokens", got)
+	}
+	if op := next.Summary.TodayByOp[ai.OperationPool]; op.Calls != 1 {
+		t.Errorf("pool totals = %+v", op)
+	}
+}
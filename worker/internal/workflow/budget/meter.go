@@ -0,0 +1,60 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"ziggy/internal/ai"
+	"ziggy/internal/registry"
+)
+
+// Meter is the ai.Meter backed by budget workflows. Accounts are Ziggy
+// workflow IDs. If a budget workflow can't be reached, calls are allowed
+// and their usage dropped rather than taking Ziggy's voice away.
+type Meter struct{}
+
+// Metered wraps provider so its calls are checked against, and recorded in,
+// the caller's budget workflow.
+func Metered(provider ai.Provider) ai.Provider {
+	return ai.NewMeteredProvider(provider, Meter{})
+}
+
+func (Meter) Allow(ctx context.Context, account string) error {
+	summary, err := QuerySummary(ctx, registry.Get(), account+WorkflowIDSuffix)
+	if err != nil {
+		log.Printf("[Budget] Could not check budget for %s: %v", account, err)
+		return nil
+	}
+	if summary.Exceeded {
+		log.Printf("[Budget] %s is over its daily %s limit, using fallback", account, summary.ExceededBy)
+		return ai.ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (Meter) Record(ctx context.Context, account string, usage ai.Usage) {
+	err := registry.Get().SignalWorkflow(ctx, account+WorkflowIDSuffix, SignalRecordUsage, usage)
+	if err != nil {
+		log.Printf("[Budget] Failed to record usage for %s: %v", account, err)
+	}
+}
+
+// QuerySummary fetches a budget workflow's usage summary.
+func QuerySummary(ctx context.Context, reg *registry.Registry, workflowID string) (*Summary, error) {
+	result, err := reg.QueryWorkflow(ctx, workflowID, QueryUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
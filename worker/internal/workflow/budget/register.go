@@ -0,0 +1,21 @@
+package budget
+
+import (
+	"fmt"
+
+	"ziggy/internal/registry"
+)
+
+func Register() {
+	registry.RegisterWorkflow(registry.Definition{
+		Name:     "BudgetWorkflow",
+		Workflow: Workflow,
+		IDPattern: func(owner string) string {
+			return fmt.Sprintf("ziggy-%s%s", owner, WorkflowIDSuffix)
+		},
+		NewInput: func(owner, _, _ string) any {
+			return Input{Owner: owner, Limits: LimitsFromEnv()}
+		},
+		AutoStart: true,
+	})
+}
@@ -0,0 +1,139 @@
+package budget
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"ziggy/internal/ai"
+)
+
+// Limits caps an owner's AI usage per UTC day. Zero means unlimited.
+type Limits struct {
+	DailyTokens int `json:"dailyTokens,omitempty"`
+	DailyCalls  int `json:"dailyCalls,omitempty"`
+}
+
+// LimitsFromEnv reads AI_DAILY_TOKEN_LIMIT and AI_DAILY_CALL_LIMIT.
+func LimitsFromEnv() Limits {
+	return Limits{
+		DailyTokens: envInt("AI_DAILY_TOKEN_LIMIT"),
+		DailyCalls:  envInt("AI_DAILY_CALL_LIMIT"),
+	}
+}
+
+func envInt(key string) int {
+	n, _ := strconv.Atoi(os.Getenv(key))
+	return max(n, 0)
+}
+
+// Totals aggregates a set of calls.
+type Totals struct {
+	Calls          int     `json:"calls"`
+	Failures       int     `json:"failures"`
+	InputTokens    int     `json:"inputTokens"`
+	OutputTokens   int     `json:"outputTokens"`
+	LatencySeconds float64 `json:"latencySeconds"`
+}
+
+func (t *Totals) Add(u ai.Usage) {
+	t.Calls++
+	if !u.Success {
+		t.Failures++
+	}
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.LatencySeconds += u.Latency.Seconds()
+}
+
+func (t Totals) Tokens() int {
+	return t.InputTokens + t.OutputTokens
+}
+
+// Series is the lifetime totals for one operation, provider and model.
+type Series struct {
+	Operation string `json:"operation"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Totals
+}
+
+// Summary is what the budget workflow knows about an owner's usage.
+type Summary struct {
+	Owner  string `json:"owner"`
+	Limits Limits `json:"limits"`
+
+	// Day is the UTC date (YYYY-MM-DD) Today covers.
+	Day        string            `json:"day"`
+	Today      Totals            `json:"today"`
+	TodayByOp  map[string]Totals `json:"todayByOperation,omitempty"`
+	Lifetime   []Series          `json:"lifetime,omitempty"`
+	Recent     []ai.Usage        `json:"recent,omitempty"`
+	Exceeded   bool              `json:"exceeded"`
+	ExceededBy string            `json:"exceededBy,omitempty"`
+}
+
+// Record adds one call to the summary.
+func (s *Summary) Record(u ai.Usage) {
+	s.Today.Add(u)
+	if s.TodayByOp == nil {
+		s.TodayByOp = map[string]Totals{}
+	}
+	op := s.TodayByOp[u.Operation]
+	op.Add(u)
+	s.TodayByOp[u.Operation] = op
+
+	series := s.series(u.Operation, u.Provider, u.Model)
+	series.Add(u)
+
+	s.Recent = append(s.Recent, u)
+	if len(s.Recent) > MaxRecent {
+		s.Recent = s.Recent[len(s.Recent)-MaxRecent:]
+	}
+	s.check()
+}
+
+// StartDay resets today's totals if now falls on a new UTC day.
+func (s *Summary) StartDay(now time.Time) {
+	day := now.UTC().Format(time.DateOnly)
+	if day == s.Day {
+		return
+	}
+	s.Day = day
+	s.Today = Totals{}
+	s.TodayByOp = nil
+	s.check()
+}
+
+// SetLimits replaces the limits and re-evaluates today against them.
+func (s *Summary) SetLimits(limits Limits) {
+	s.Limits = limits
+	s.check()
+}
+
+func (s *Summary) check() {
+	s.Exceeded, s.ExceededBy = false, ""
+	switch {
+	case s.Limits.DailyTokens > 0 && s.Today.Tokens() >= s.Limits.DailyTokens:
+		s.Exceeded, s.ExceededBy = true, "tokens"
+	case s.Limits.DailyCalls > 0 && s.Today.Calls >= s.Limits.DailyCalls:
+		s.Exceeded, s.ExceededBy = true, "calls"
+	}
+}
+
+func (s *Summary) series(operation, provider, model string) *Series {
+	for i := range s.Lifetime {
+		l := &s.Lifetime[i]
+		if l.Operation == operation && l.Provider == provider && l.Model == model {
+			return l
+		}
+	}
+	s.Lifetime = append(s.Lifetime, Series{Operation: operation, Provider: provider, Model: model})
+	return &s.Lifetime[len(s.Lifetime)-1]
+}
+
+// nextDay returns the start of the UTC day after now.
+func nextDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,126 @@
+package budget
+
+import (
+	"go.temporal.io/sdk/workflow"
+
+	"ziggy/internal/ai"
+)
+
+const (
+	SignalRecordUsage = "record_usage"
+	SignalSetLimits   = "set_limits"
+
+	QueryUsage = "usage"
+
+	// WorkflowIDSuffix is appended to the Ziggy workflow ID to address the
+	// owner's budget workflow.
+	WorkflowIDSuffix = "-usage"
+
+	// MaxRecent is how many individual calls the summary keeps.
+	MaxRecent = 50
+)
+
+type Input struct {
+	Owner  string `json:"owner"`
+	Limits Limits `json:"limits"`
+	// Summary carries usage, and limits set by signal, across
+	// continue-as-new.
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// Workflow aggregates every AI call made for an owner and flags the day's
+// budget as exceeded once a limit is reached. The Meter checks that flag
+// before each call.
+func Workflow(ctx workflow.Context, input Input) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Budget workflow started", "owner", input.Owner, "dailyTokens", input.Limits.DailyTokens, "dailyCalls", input.Limits.DailyCalls)
+
+	summary := Summary{Owner: input.Owner, Limits: input.Limits}
+	if input.Summary != nil {
+		summary = *input.Summary
+	}
+	summary.StartDay(workflow.Now(ctx))
+
+	err := workflow.SetQueryHandler(ctx, QueryUsage, func() (Summary, error) {
+		return summary, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	recordCh := workflow.GetSignalChannel(ctx, SignalRecordUsage)
+	limitsCh := workflow.GetSignalChannel(ctx, SignalSetLimits)
+
+	newDayTimer := func() workflow.Future {
+		now := workflow.Now(ctx)
+		return workflow.NewTimer(ctx, nextDay(now).Sub(now))
+	}
+	dayTimer := newDayTimer()
+
+	// newSignalSelector wires both signal handlers; it is also used to drain
+	// buffered signals before continuing as new.
+	newSignalSelector := func() workflow.Selector {
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(recordCh, func(c workflow.ReceiveChannel, more bool) {
+			var usage ai.Usage
+			c.Receive(ctx, &usage)
+			summary.StartDay(workflow.Now(ctx))
+			wasExceeded := summary.Exceeded
+			summary.Record(usage)
+			if summary.Exceeded && !wasExceeded {
+				logger.Info("Daily AI budget exceeded", "owner", input.Owner, "by", summary.ExceededBy,
+					"tokens", summary.Today.Tokens(), "calls", summary.Today.Calls)
+			}
+		})
+
+		selector.AddReceive(limitsCh, func(c workflow.ReceiveChannel, more bool) {
+			var limits Limits
+			c.Receive(ctx, &limits)
+			summary.SetLimits(limits)
+			logger.Info("Budget limits updated", "dailyTokens", limits.DailyTokens, "dailyCalls", limits.DailyCalls)
+		})
+
+		return selector
+	}
+
+	for {
+		selector := newSignalSelector()
+
+		selector.AddFuture(dayTimer, func(f workflow.Future) {
+			summary.StartDay(workflow.Now(ctx))
+			dayTimer = newDayTimer()
+		})
+
+		selector.Select(ctx)
+
+		// A canceled timer is ready at once, so stop rather than re-arm it
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if shouldContinueAsNew(ctx) {
+			logger.Info("Budget workflow continuing as new")
+
+			// Usage recorded after the last Select would be lost with this
+			// run, so apply it before snapshotting.
+			drain := newSignalSelector()
+			for drain.HasPending() {
+				drain.Select(ctx)
+			}
+
+			snapshot := summary
+			return workflow.NewContinueAsNewError(ctx, Workflow, Input{
+				Owner:   input.Owner,
+				Limits:  summary.Limits,
+				Summary: &snapshot,
+			})
+		}
+	}
+}
+
+// shouldContinueAsNew is a variable so tests can force continue-as-new; the
+// test environment does not track history length.
+var shouldContinueAsNew = func(ctx workflow.Context) bool {
+	return workflow.GetInfo(ctx).GetCurrentHistoryLength() > 5000
+}
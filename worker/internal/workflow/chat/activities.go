@@ -4,23 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"slices"
 	"time"
 
+	"go.temporal.io/sdk/activity"
+
 	"ziggy/internal/ai"
+	"ziggy/internal/lessons"
+	"ziggy/internal/locale"
 	"ziggy/internal/registry"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
 	z "ziggy/internal/ziggy"
 )
 
 type Activities struct {
-	aiClient *ai.Client
+	provider  ai.Provider
+	moderator *ai.Moderator
+	history   lessons.HistorySource
 }
 
-func NewActivities(aiClient *ai.Client) *Activities {
-	return &Activities{aiClient: aiClient}
+func NewActivities(provider ai.Provider) *Activities {
+	return &Activities{provider: provider, moderator: ai.ModeratorFromEnv(), history: registry.Get()}
 }
 
 const QueryZiggyState = "state"
 
+// lessonTimeout bounds reading workflow history for a lesson.
+const lessonTimeout = 5 * time.Second
+
 func (a *Activities) QueryZiggyState(ctx context.Context, ziggyID string) (*z.State, error) {
 	log.Printf("[ChatActivity] Querying Ziggy state for workflow: %s", ziggyID)
 
@@ -44,7 +55,13 @@ func (a *Activities) QueryZiggyState(ctx context.Context, ziggyID string) (*z.St
 	return &state, nil
 }
 
+// ProcessChatMessageActivity is the registered name of ProcessChatMessage.
+// While it runs, its heartbeat details hold the reply text generated so far.
+const ProcessChatMessageActivity = "ProcessChatMessage"
+
 type ProcessMessageInput struct {
+	// ZiggyID is the account AI usage is billed to.
+	ZiggyID    string    `json:"ziggyId,omitempty"`
 	State      State     `json:"state"`
 	Content    string    `json:"content"`
 	ZiggyState *z.State  `json:"ziggyState,omitempty"`
@@ -54,33 +71,113 @@ type ProcessMessageInput struct {
 
 type ProcessMessageOutput struct {
 	State State `json:"state"`
+	// Care holds the ZiggyWorkflow signals Ziggy asked for in its reply.
+	Care []string `json:"care,omitempty"`
+	// Sentiment is the model's score for the user's message, -1 (rude) to
+	// 1 (kind); 0 without a provider.
+	Sentiment float64 `json:"sentiment,omitempty"`
+	// Rejected is set when moderation kept the message from the model, so
+	// it doesn't count as an interaction.
+	Rejected bool `json:"rejected,omitempty"`
+	// Command is the chat command the message ran, if any.
+	Command string `json:"command,omitempty"`
+	// Track is the track a /track command switched to.
+	Track string `json:"track,omitempty"`
 }
 
 func (a *Activities) ProcessChatMessage(ctx context.Context, input ProcessMessageInput) (*ProcessMessageOutput, error) {
+	ctx = ai.WithAccount(ctx, input.ZiggyID)
 	state := input.State
 	now := input.Now
+	if input.ZiggyState != nil {
+		state.Locale = input.ZiggyState.Locale
+	}
 
 	state.AddMessage("user", input.Content, now)
 	log.Printf("[ChatActivity] User message received: %s", input.Content)
 
+	if violations := a.moderator.CheckInput(input.Content); len(violations) > 0 {
+		log.Printf("[ChatActivity] Message rejected by moderation: %v", violations)
+		state.Messages[len(state.Messages)-1].Flagged = true
+		state.RecordViolations(violations, now)
+		state.AddMessage("ziggy", deflection(state.Locale, violations), now)
+		state.IsTyping = false
+		return &ProcessMessageOutput{State: state, Rejected: true}, nil
+	}
+
+	// Commands run without the model
+	if name, arg, ok := parseCommand(input.Content); ok {
+		log.Printf("[ChatActivity] Running command /%s", name)
+		state.Messages[len(state.Messages)-1].Command = true
+		result := runCommand(&state, name, arg, input.Track, input.ZiggyState, now)
+		state.AddMessage("ziggy", result.reply, now)
+		state.Messages[len(state.Messages)-1].Command = true
+		state.IsTyping = false
+		return &ProcessMessageOutput{State: state, Care: result.care, Command: name, Track: result.track}, nil
+	}
+
+	// While a quiz runs, messages are answers, graded here without the
+	// model
+	if state.Quiz != nil {
+		state.AddMessage("ziggy", state.AnswerQuiz(input.Content, now), now)
+		state.IsTyping = false
+		return &ProcessMessageOutput{State: state}, nil
+	}
+
 	responseTrack := input.Track
 	if state.ActiveMystery != nil && state.ActiveMystery.Track != "" {
 		responseTrack = state.ActiveMystery.Track
 	}
 
-	response := a.generateResponse(ctx, &state, input.ZiggyState, responseTrack)
+	// Fun-track guesses are checked here so the same guess always gets the
+	// same verdict, with or without a provider
+	var guess *AnswerMatch
+	if m := state.ActiveMystery; m != nil && m.Track != TrackEducational {
+		match := MatchAnswer(m, input.Content)
+		guess = &match
+		state.noteGuess(input.Content, now)
+	}
+
+	// Lessons point at events in the owner's own workflow history
+	var evidence []string
+	if m := state.ActiveMystery; m != nil && responseTrack == TrackEducational && lessons.Find(m.ID) != nil {
+		evidence = a.lessonEvidence(ctx, input.ZiggyID, m.ID)
+	}
+
+	ctx, violations := ai.CollectViolations(ctx)
+	response := a.generateResponse(ctx, &state, input.ZiggyState, responseTrack, input.Content, guess, evidence, now)
+	state.RecordViolations(violations(), now)
+	applyVerdict(&response, guess, state.MysteryProgress)
+
+	if response.Explained {
+		state.noteExplained(now)
+		if m := state.ActiveMystery; len(m.Quiz) > 0 {
+			response.Response += "\n\n" + state.StartQuiz(m, nil, false)
+		}
+	}
+	a.processMysteryUpdate(&state, &response, now)
 
-	a.processMysteryUpdate(&state, &response)
+	if response.StartMystery != nil && state.ActiveMystery == nil {
+		log.Printf("[ChatActivity] Starting mystery from chat: %s", response.StartMystery.ID)
+		state.StartMystery(response.StartMystery, now)
+	}
 
 	state.AddMessage("ziggy", response.Response, now)
+	state.Messages[len(state.Messages)-1].Mood = response.Mood
 	state.IsTyping = false
 
-	return &ProcessMessageOutput{State: state}, nil
+	return &ProcessMessageOutput{State: state, Care: response.Care, Sentiment: response.Sentiment}, nil
 }
 
 type chatResponse struct {
 	Response      string
 	MysteryUpdate *MysteryUpdate
+	Mood          string
+	Care          []string
+	StartMystery  *Mystery
+	Sentiment     float64
+	// Explained is set when the model answered on an educational topic.
+	Explained bool
 }
 
 type MysteryUpdate struct {
@@ -90,22 +187,19 @@ type MysteryUpdate struct {
 	NewProgress int    `json:"newProgress"`
 }
 
-func (a *Activities) generateResponse(ctx context.Context, chatState *State, ziggyState *z.State, track string) chatResponse {
-	if a.aiClient == nil {
-		return chatResponse{Response: getFallbackResponse(ziggyState)}
+func (a *Activities) generateResponse(ctx context.Context, chatState *State, ziggyState *z.State, track, content string, guess *AnswerMatch, evidence []string, now time.Time) chatResponse {
+	if a.provider == nil || !a.provider.Available() {
+		return offlineReply(chatState, ziggyState, track, content, guess, evidence, now)
 	}
 
 	aiInput := ai.ChatInput{
 		Messages: convertMessages(chatState.Messages),
 		Track:    track,
+		Memories: memoryFacts(chatState.Memories),
+		Language: locale.Language(chatState.Locale),
 	}
 
-	if ziggyState != nil {
-		aiInput.Personality = string(ziggyState.Personality)
-		aiInput.Mood = string(ziggyState.GetMood())
-		aiInput.Stage = string(z.GetStageForAge(time.Since(ziggyState.CreatedAt).Seconds()))
-		aiInput.Bond = ziggyState.Bond
-	}
+	describeZiggy(&aiInput, ziggyState, now)
 
 	if chatState.ActiveMystery != nil {
 		aiInput.Mystery = &ai.MysteryContext{
@@ -117,26 +211,66 @@ func (a *Activities) generateResponse(ctx context.Context, chatState *State, zig
 			Progress:    chatState.MysteryProgress,
 			Solution:    chatState.ActiveMystery.Solution,
 			Summary:     chatState.ActiveMystery.Summary,
+			Evidence:    evidence,
+		}
+		if guess != nil {
+			aiInput.Mystery.GuessCorrect = &guess.Correct
 		}
 	}
 
-	result, err := a.aiClient.GenerateChat(ctx, aiInput)
+	offerActions(&aiInput, chatState)
+
+	result, err := ai.StreamChat(ctx, a.provider, aiInput, func(partial string) {
+		if activity.IsActivity(ctx) {
+			activity.RecordHeartbeat(ctx, partial)
+		}
+	})
 	if err != nil {
-		log.Printf("[ChatActivity] AI error: %v, using fallback", err)
-		return chatResponse{Response: getFallbackResponse(ziggyState)}
+		log.Printf("[ChatActivity] AI error: %v, answering offline", err)
+		return offlineReply(chatState, ziggyState, track, content, guess, evidence, now)
 	}
 
-	resp := chatResponse{Response: result.Response}
+	resp := chatResponse{Response: result.WithCitations(), Sentiment: result.Sentiment}
+	resp.Explained = track == TrackEducational && chatState.ActiveMystery != nil
+
+	mysteryUpdate := result.MysteryUpdate
+	for _, action := range result.Actions {
+		if !slices.Contains(aiInput.Actions, action.Name) {
+			log.Printf("[ChatActivity] Ignoring action that wasn't offered: %s", action.Name)
+			continue
+		}
+		switch action.Name {
+		case ai.ActionRequestFeed:
+			resp.Care = appendCare(resp.Care, ziggyworkflow.SignalFeed)
+		case ai.ActionRequestPlay:
+			resp.Care = appendCare(resp.Care, ziggyworkflow.SignalPlay)
+		case ai.ActionRequestPet:
+			resp.Care = appendCare(resp.Care, ziggyworkflow.SignalPet)
+		case ai.ActionExpressMood:
+			resp.Mood = action.Mood
+		case ai.ActionStartMystery:
+			resp.StartMystery = Mysteries().Localize(GetMystery(action.MysteryID, track), chatState.Locale)
+		case ai.ActionGiveHint:
+			if mysteryUpdate == nil || mysteryUpdate.HintGiven == "" {
+				update := ai.ChatMysteryUpdate{}
+				if mysteryUpdate != nil {
+					update = *mysteryUpdate
+				}
+				update.HintGiven = action.Hint
+				mysteryUpdate = &update
+			}
+		}
+	}
 
-	if result.MysteryUpdate != nil {
+	if mysteryUpdate != nil {
 		newProgress := chatState.MysteryProgress
-		if result.MysteryUpdate.HintGiven != "" {
+		if mysteryUpdate.HintGiven != "" {
 			newProgress++
 		}
 		resp.MysteryUpdate = &MysteryUpdate{
-			Solved:      result.MysteryUpdate.Solved,
-			Failed:      result.MysteryUpdate.Failed,
-			HintGiven:   result.MysteryUpdate.HintGiven,
+			Solved:      mysteryUpdate.Solved,
+			Failed:      mysteryUpdate.Failed,
+			HintGiven:   mysteryUpdate.HintGiven,
 			NewProgress: newProgress,
 		}
 	}
@@ -144,15 +278,109 @@ func (a *Activities) generateResponse(ctx context.Context, chatState *State, zig
 	return resp
 }
 
-func (a *Activities) processMysteryUpdate(state *State, resp *chatResponse) {
+// describeZiggy fills in who Ziggy is right now for the prompt.
+func describeZiggy(aiInput *ai.ChatInput, ziggyState *z.State, now time.Time) {
+	if ziggyState == nil {
+		return
+	}
+	aiInput.Personality = string(ziggyState.Personality)
+	aiInput.Mood = string(ziggyState.GetMood())
+	aiInput.Stage = string(z.GetStageForAge(now.Sub(ziggyState.CreatedAt).Seconds()))
+	aiInput.Bond = ziggyState.Bond
+}
+
+// lessonEvidence describes the lesson's events in the Ziggy workflow's
+// history. A lesson without evidence is still taught, so errors are only
+// logged.
+func (a *Activities) lessonEvidence(ctx context.Context, ziggyID, lessonID string) []string {
+	if a.history == nil || ziggyID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, lessonTimeout)
+	defer cancel()
+
+	found, err := lessons.Collect(ctx, a.history, ziggyID, lessonID)
+	if err != nil {
+		log.Printf("[ChatActivity] No lesson evidence for %s: %v", lessonID, err)
+		return nil
+	}
+	var evidence []string
+	for _, e := range found.Events {
+		evidence = append(evidence, e.String())
+	}
+	return evidence
+}
+
+// applyVerdict makes a checked guess decide whether the mystery is solved,
+// whatever the model said.
+func applyVerdict(resp *chatResponse, guess *AnswerMatch, progress int) {
+	if guess == nil {
+		return
+	}
+	if guess.Correct {
+		if resp.MysteryUpdate == nil {
+			resp.MysteryUpdate = &MysteryUpdate{NewProgress: progress}
+		}
+		resp.MysteryUpdate.Solved = true
+		resp.MysteryUpdate.Failed = false
+		resp.MysteryUpdate.HintGiven = ""
+		return
+	}
+	if resp.MysteryUpdate != nil {
+		resp.MysteryUpdate.Solved = false
+	}
+}
+
+// offerActions lists the action tools the model may call with its reply.
+// Care requests and moods are for the fun track only; mysteries can be
+// started when none is active, and hints given while some remain.
+func offerActions(aiInput *ai.ChatInput, chatState *State) {
+	if aiInput.Track == "educational" {
+		return
+	}
+	aiInput.Actions = []string{ai.ActionRequestFeed, ai.ActionRequestPlay, ai.ActionRequestPet, ai.ActionExpressMood}
+
+	if chatState.ActiveMystery != nil {
+		if chatState.MysteryProgress < len(chatState.ActiveMystery.Hints) {
+			aiInput.Actions = append(aiInput.Actions, ai.ActionGiveHint)
+		}
+		return
+	}
+	for _, m := range GetAvailableMysteries(aiInput.Track, chatState.Completed()) {
+		aiInput.Mysteries = append(aiInput.Mysteries, ai.MysteryOption{
+			ID:          m.ID,
+			Title:       m.Title,
+			Description: m.Description,
+		})
+	}
+	if len(aiInput.Mysteries) > 0 {
+		aiInput.Actions = append(aiInput.Actions, ai.ActionStartMystery)
+	}
+}
+
+func appendCare(care []string, signal string) []string {
+	if slices.Contains(care, signal) {
+		return care
+	}
+	return append(care, signal)
+}
+
+func (a *Activities) processMysteryUpdate(state *State, resp *chatResponse, now time.Time) {
 	if resp.MysteryUpdate == nil {
 		return
 	}
 
 	update := resp.MysteryUpdate
 
+	// Educational topics have no hints or answer, and explaining one
+	// doesn't solve it
+	if state.ActiveMystery != nil && state.ActiveMystery.Track == TrackEducational {
+		return
+	}
+
 	if update.HintGiven != "" {
 		state.HintsGiven = append(state.HintsGiven, update.HintGiven)
+		state.noteHints(now)
 	}
 	state.MysteryProgress = update.NewProgress
 
@@ -161,7 +389,10 @@ func (a *Activities) processMysteryUpdate(state *State, resp *chatResponse) {
 	}
 
 	if update.Solved && state.ActiveMystery != nil {
-		state.Solved = append(state.Solved, state.ActiveMystery.ID)
+		state.noteSolved(now)
+		if !slices.Contains(state.Solved, state.ActiveMystery.ID) {
+			state.Solved = append(state.Solved, state.ActiveMystery.ID)
+		}
 		state.ActiveMystery = nil
 		state.MysteryProgress = 0
 		state.HintsGiven = nil
@@ -177,41 +408,39 @@ func (a *Activities) processMysteryUpdate(state *State, resp *chatResponse) {
 		state.MysteryProgress >= len(state.ActiveMystery.Hints) &&
 		update.HintGiven == "" {
 		solution := state.ActiveMystery.Solution
+		state.noteRevealed(now)
 		state.ActiveMystery = nil
 		state.MysteryProgress = 0
 		state.HintsGiven = nil
-		resp.Response = resp.Response + "\n\n*wiggles sympathetically*\nThe answer was: " + solution + "\n\nNice try! Want to try another mystery?"
+		resp.Response += "\n\n" + text(state.Locale, textAnswerWas, "solution", solution) + "\n\n" + text(state.Locale, textTryAnother)
 	}
 }
 
+// convertMessages leaves out messages moderation flagged, so they never
+// reach a prompt.
 func convertMessages(messages []Message) []ai.ChatMessage {
-	result := make([]ai.ChatMessage, len(messages))
-	for i, m := range messages {
-		result[i] = ai.ChatMessage{
+	result := make([]ai.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		if m.Flagged || m.Command {
+			continue
+		}
+		result = append(result, ai.ChatMessage{
 			Role:    m.Role,
 			Content: m.Content,
-		}
+		})
 	}
 	return result
 }
 
-func getFallbackResponse(ziggyState *z.State) string {
-	if ziggyState == nil {
-		return "*wiggle wiggle*\nHi there!"
-	}
-
-	mood := ziggyState.GetMood()
-	switch mood {
-	case z.MoodHappy:
-		return "*happy wiggle*\nGreat to chat!"
-	case z.MoodSad:
-		return "*slow wiggle*\nI'm feeling\na bit down..."
-	case z.MoodHungry:
-		return "*tummy rumble*\nSo hungry..."
-	case z.MoodSleeping:
-		return "*snore*\nzzz..."
+// deflection is Ziggy's reply, in the locale, to a message moderation kept
+// from the model.
+func deflection(code string, violations []ai.Violation) string {
+	switch violations[0].Kind {
+	case ai.ViolationLength:
+		return text(code, textDeflectLength)
+	case ai.ViolationInjection:
+		return text(code, textDeflectInjection)
 	default:
-		return "*wiggle*\nHello!"
+		return text(code, textDeflectContent)
 	}
 }
-
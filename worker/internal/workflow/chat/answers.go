@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// MatchThreshold is the share of an answer's keywords a guess must
+	// contain to count as correct.
+	MatchThreshold = 0.6
+
+	// Keywords at least this long tolerate one typo, and twice as long two.
+	fuzzyMinLength = 4
+)
+
+// stopWords carry no meaning in an answer: "the comet ate it" and "a comet
+// ate them" are the same guess.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "it": true, "its": true, "was": true, "were": true,
+	"is": true, "are": true, "be": true, "been": true, "by": true, "of": true, "to": true,
+	"in": true, "on": true, "at": true, "and": true, "or": true, "that": true, "this": true,
+	"them": true, "they": true, "i": true, "think": true, "maybe": true, "guess": true,
+	"actually": true, "my": true, "your": true, "from": true, "some": true, "just": true,
+}
+
+// AnswerMatch is the verdict on a guess at a mystery.
+type AnswerMatch struct {
+	Correct bool `json:"correct"`
+	// Score is the best share of an answer's keywords found in the guess.
+	Score float64 `json:"score"`
+	// Answer is the solution or alias that scored best.
+	Answer string `json:"answer,omitempty"`
+}
+
+// MatchAnswer checks a guess against the mystery's solution and aliases.
+// Words are compared after lowercasing, dropping punctuation and stop
+// words, and trimming plurals; longer words may be misspelled slightly.
+// Japanese and Chinese are written without spaces, so their keywords count
+// when they appear anywhere in the guess. The same guess always gets the
+// same verdict.
+func MatchAnswer(m *Mystery, guess string) AnswerMatch {
+	if m == nil {
+		return AnswerMatch{}
+	}
+	return matchText(append([]string{m.Solution}, m.Aliases...), guess)
+}
+
+// matchText checks a guess against each accepted answer the way
+// MatchAnswer does.
+func matchText(answers []string, guess string) AnswerMatch {
+	var best AnswerMatch
+	words := keywords(guess)
+	if len(words) == 0 {
+		return best
+	}
+
+	for _, answer := range answers {
+		want := keywords(answer)
+		if len(want) == 0 {
+			continue
+		}
+		found := 0
+		for _, w := range want {
+			for _, g := range words {
+				if similar(w, g) {
+					found++
+					break
+				}
+			}
+		}
+		if score := float64(found) / float64(len(want)); score > best.Score {
+			best = AnswerMatch{Score: score, Answer: answer}
+		}
+	}
+	best.Correct = best.Score >= MatchThreshold
+	return best
+}
+
+func keywords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	var out []string
+	for _, f := range fields {
+		f = strings.Trim(f, "'")
+		f = strings.TrimSuffix(f, "'s")
+		if f == "" || stopWords[f] {
+			continue
+		}
+		out = append(out, stem(f))
+	}
+	return out
+}
+
+// stem trims plural endings so "crumbs" matches "crumb".
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func similar(want, got string) bool {
+	if want == got {
+		return true
+	}
+	if unspaced(want) {
+		return strings.Contains(got, want)
+	}
+	allowed := 0
+	switch n := len([]rune(want)); {
+	case n >= 2*fuzzyMinLength:
+		allowed = 2
+	case n >= fuzzyMinLength:
+		allowed = 1
+	}
+	return allowed > 0 && editDistance(want, got, allowed) <= allowed
+}
+
+// unspaced reports whether word is in a script written without spaces, so
+// a whole phrase arrives as one word.
+func unspaced(word string) bool {
+	return strings.IndexFunc(word, func(r rune) bool {
+		return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+	}) >= 0
+}
+
+// editDistance is the Levenshtein distance between a and b, or limit+1 once
+// it is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
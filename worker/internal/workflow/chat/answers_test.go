@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"ziggy/internal/ai"
+)
+
+func TestMatchAnswer(t *testing.T) {
+	tests := []struct {
+		mystery string
+		guess   string
+		want    bool
+	}{
+		{"missing-snack", "The cosmic crumbs were eaten by a passing comet", true},
+		{"missing-snack", "a comet ate them!", true},
+		{"missing-snack", "COMET.", true},
+		{"missing-snack", "the commet", true},
+		{"missing-snack", "a passing comit ate the cosmic crumb", true},
+		{"missing-snack", "you ate them", false},
+		{"missing-snack", "they're in the fridge", false},
+		{"missing-snack", "crumbs", false},
+
+		{"cosmic-radio", "It was a friendly space probe saying hello", true},
+		{"cosmic-radio", "a space probe!", true},
+		{"cosmic-radio", "maybe a satelite?", true},
+		{"cosmic-radio", "a probe", false},
+		{"cosmic-radio", "aliens", false},
+		{"cosmic-radio", "someone saying hello in binary", false},
+
+		{"dream-maze", "a memory of surviving a supernova", true},
+		{"dream-maze", "supernovas", true},
+		{"dream-maze", "I think it was a super nova", false},
+		{"dream-maze", "a dream", false},
+		{"dream-maze", "stardust walls", false},
+
+		{"missing-snack", "", false},
+		{"missing-snack", "?!", false},
+	}
+	for _, tt := range tests {
+		m := GetMystery(tt.mystery, TrackFun)
+		if m == nil {
+			t.Fatalf("no mystery %q", tt.mystery)
+		}
+		if got := MatchAnswer(m, tt.guess); got.Correct != tt.want {
+			t.Errorf("%s: MatchAnswer(%q) = %+v, want correct=%v", tt.mystery, tt.guess, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAnswerInTranslation(t *testing.T) {
+	tests := []struct {
+		locale, mystery, guess string
+		want                   bool
+	}{
+		{"es", "missing-snack", "¡un cometa se las comió!", true},
+		{"es", "missing-snack", "a comet ate them", true},
+		{"es", "cosmic-radio", "una sonda espacial", true},
+		{"es", "cosmic-radio", "extraterrestres", false},
+		// Japanese guesses arrive as one word
+		{"ja", "missing-snack", "彗星が食べたのかな？", true},
+		{"ja", "cosmic-radio", "たぶん人工衛星！", true},
+		{"ja", "dream-maze", "夢だった", false},
+		{"ja", "dream-maze", "supernova", true},
+	}
+	for _, tt := range tests {
+		m := Mysteries().Localize(GetMystery(tt.mystery, TrackFun), tt.locale)
+		if got := MatchAnswer(m, tt.guess); got.Correct != tt.want {
+			t.Errorf("%s %s: MatchAnswer(%q) = %+v, want correct=%v", tt.locale, tt.mystery, tt.guess, got, tt.want)
+		}
+	}
+}
+
+func TestEveryFunMysteryAcceptsItsAnswers(t *testing.T) {
+	for _, code := range []string{"en", "es", "ja"} {
+		for _, m := range Mysteries().Track(TrackFun) {
+			m := Mysteries().Localize(&m, code)
+			for _, answer := range append([]string{m.Solution}, m.Aliases...) {
+				if got := MatchAnswer(m, answer); !got.Correct || got.Score != 1 {
+					t.Errorf("%s %s: MatchAnswer(%q) = %+v", code, m.ID, answer, got)
+				}
+			}
+		}
+	}
+}
+
+func TestProcessChatMessageChecksGuesses(t *testing.T) {
+	// The model claiming a wrong guess is right doesn't solve the mystery
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{{
+		Chat: &ai.ChatResponse{Response: "Yes!", MysteryUpdate: &ai.ChatMysteryUpdate{Solved: true}},
+	}}}
+	state := processMessage(t, fake, ProcessMessageInput{
+		State: mysteryState("cosmic-radio"), Content: "aliens", Track: "fun", Now: time.Now(),
+	})
+	if state.ActiveMystery == nil || len(state.Solved) != 0 {
+		t.Errorf("wrong guess solved the mystery: active %v, solved %v", state.ActiveMystery, state.Solved)
+	}
+	if c := fake.ChatCalls()[0].Mystery.GuessCorrect; c == nil || *c {
+		t.Errorf("verdict sent to the model = %v", c)
+	}
+
+	// Without a provider, a right guess still solves it
+	state = processMessage(t, nil, ProcessMessageInput{
+		State: mysteryState("cosmic-radio"), Content: "a space probe", Track: "fun", Now: time.Now(),
+	})
+	if state.ActiveMystery != nil || len(state.Solved) != 1 || state.Solved[0] != "cosmic-radio" {
+		t.Errorf("right guess without a provider: active %v, solved %v", state.ActiveMystery, state.Solved)
+	}
+	if got := lastMessage(state); got != "*happy wiggle*\nYou got it!\nThe answer was: It was a friendly space probe saying hello" {
+		t.Errorf("reply = %q", got)
+	}
+}
@@ -0,0 +1,487 @@
+package chat
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"go.yaml.in/yaml/v3"
+
+	"ziggy/internal/locale"
+)
+
+// Mystery tracks and difficulties a catalog file may use.
+const (
+	TrackFun         = "fun"
+	TrackEducational = "educational"
+
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+var difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+//go:embed mysteries
+var defaultMysteries embed.FS
+
+// DefaultMysteries returns the catalog files built into the worker.
+func DefaultMysteries() fs.FS {
+	sub, err := fs.Sub(defaultMysteries, "mysteries")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+var mysteryID = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CatalogError lists every problem found while loading mystery files.
+type CatalogError struct {
+	Problems []string
+}
+
+func (e *CatalogError) Error() string {
+	return "invalid mystery catalog:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Catalog is a validated set of mysteries, ordered by track, difficulty
+// and ID, with their translations.
+type Catalog struct {
+	mysteries []Mystery
+	// translations hold translated mysteries by locale and ID.
+	translations map[string]map[string]Mystery
+}
+
+// LoadCatalog reads mystery files from each fsys in turn; a file in a later
+// one replaces the mystery with the same ID from an earlier one. Files are
+// YAML (.yaml, .yml), or Markdown (.md) with YAML front matter between ---
+// lines and the body as the summary. Other files are ignored.
+//
+// Translations live in locales/<code>/, in files named like the mystery
+// they translate. They hold only the text: title, description, hints,
+// solution, aliases, concept, summary and quiz. Replacing a mystery drops
+// the translations of it from earlier fsyss.
+func LoadCatalog(fsyss ...fs.FS) (*Catalog, error) {
+	var problems []string
+	byID := map[string]Mystery{}
+	source := map[string]string{}
+	translations := map[string]map[string]Mystery{}
+	translationSource := map[string]string{}
+
+	for _, fsys := range fsyss {
+		seen := map[string]string{}
+		paths, err := fs.Glob(fsys, "*")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			ext := path.Ext(p)
+			if ext != ".yaml" && ext != ".yml" && ext != ".md" {
+				continue
+			}
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			m, err := parseMystery(data, ext == ".md")
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", p, err))
+				continue
+			}
+			if want := strings.TrimSuffix(p, ext); m.ID != want {
+				problems = append(problems, fmt.Sprintf("%s: id %q does not match the file name", p, m.ID))
+				continue
+			}
+			if prev, ok := seen[m.ID]; ok {
+				problems = append(problems, fmt.Sprintf("%s: duplicate id %q (also in %s)", p, m.ID, prev))
+				continue
+			}
+			seen[m.ID] = p
+			byID[m.ID] = m
+			source[m.ID] = p
+		}
+
+		// Translations of a replaced mystery no longer match its text
+		for id := range seen {
+			for code := range translations {
+				delete(translations[code], id)
+			}
+		}
+
+		paths, err = fs.Glob(fsys, "locales/*/*")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			ext := path.Ext(p)
+			if ext != ".yaml" && ext != ".yml" && ext != ".md" {
+				continue
+			}
+			code := path.Base(path.Dir(p))
+			if code == locale.Default || !locale.Valid(code) {
+				problems = append(problems, fmt.Sprintf("%s: unsupported locale %q", p, code))
+				continue
+			}
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			m, err := parseMystery(data, ext == ".md")
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", p, err))
+				continue
+			}
+			if want := strings.TrimSuffix(path.Base(p), ext); m.ID != want {
+				problems = append(problems, fmt.Sprintf("%s: id %q does not match the file name", p, m.ID))
+				continue
+			}
+			if translations[code] == nil {
+				translations[code] = map[string]Mystery{}
+			}
+			translations[code][m.ID] = m
+			translationSource[code+"/"+m.ID] = p
+		}
+	}
+
+	var mysteries []Mystery
+	for _, m := range byID {
+		for _, problem := range validateMystery(m, byID) {
+			problems = append(problems, fmt.Sprintf("%s: %s", source[m.ID], problem))
+		}
+		mysteries = append(mysteries, m)
+	}
+	problems = append(problems, prerequisiteCycles(byID)...)
+	for code, byLocale := range translations {
+		for id, t := range byLocale {
+			for _, problem := range validateTranslation(t, byID) {
+				problems = append(problems, fmt.Sprintf("%s: %s", translationSource[code+"/"+id], problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, &CatalogError{Problems: problems}
+	}
+
+	slices.SortFunc(mysteries, func(a, b Mystery) int {
+		if a.Track != b.Track {
+			return strings.Compare(a.Track, b.Track)
+		}
+		if da, db := slices.Index(difficulties, a.Difficulty), slices.Index(difficulties, b.Difficulty); da != db {
+			return da - db
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return &Catalog{mysteries: mysteries, translations: translations}, nil
+}
+
+func parseMystery(data []byte, markdown bool) (Mystery, error) {
+	var body string
+	if markdown {
+		front, rest, ok := splitFrontMatter(data)
+		if !ok {
+			return Mystery{}, errors.New("missing --- front matter")
+		}
+		data, body = front, strings.TrimSpace(string(rest))
+	}
+
+	var m Mystery
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return Mystery{}, err
+	}
+	if body != "" {
+		if m.Summary != "" {
+			return Mystery{}, errors.New("summary is set in both front matter and body")
+		}
+		m.Summary = body
+	}
+	if m.Hints == nil {
+		m.Hints = []string{}
+	}
+	return m, nil
+}
+
+func splitFrontMatter(data []byte) (front, body []byte, ok bool) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	rest, found := bytes.CutPrefix(data, []byte("---\n"))
+	if !found {
+		return nil, nil, false
+	}
+	front, body, found = bytes.Cut(rest, []byte("\n---\n"))
+	if !found {
+		front, found = bytes.CutSuffix(rest, []byte("\n---"))
+	}
+	return front, body, found
+}
+
+// validateMystery lists what an author needs to fix in m.
+func validateMystery(m Mystery, byID map[string]Mystery) []string {
+	var problems []string
+	if !mysteryID.MatchString(m.ID) {
+		problems = append(problems, fmt.Sprintf("id %q must be lowercase words joined by dashes", m.ID))
+	}
+	if strings.TrimSpace(m.Title) == "" {
+		problems = append(problems, "title is required")
+	}
+	if strings.TrimSpace(m.Description) == "" {
+		problems = append(problems, "description is required")
+	}
+	if !slices.Contains(difficulties, m.Difficulty) {
+		problems = append(problems, fmt.Sprintf("difficulty %q must be one of %s", m.Difficulty, strings.Join(difficulties, ", ")))
+	}
+
+	switch m.Track {
+	case TrackFun:
+		if len(m.Hints) == 0 {
+			problems = append(problems, "fun mysteries need at least one hint")
+		}
+		if strings.TrimSpace(m.Solution) == "" {
+			problems = append(problems, "fun mysteries need a solution")
+		}
+		if len(m.Quiz) > 0 {
+			problems = append(problems, "fun mysteries can't have a quiz")
+		}
+	case TrackEducational:
+		if strings.TrimSpace(m.Concept) == "" {
+			problems = append(problems, "educational topics need a concept")
+		}
+		if strings.TrimSpace(m.Summary) == "" {
+			problems = append(problems, "educational topics need a summary")
+		}
+		if len(m.Quiz) == 0 {
+			problems = append(problems, "educational topics need a quiz")
+		}
+		for i, q := range m.Quiz {
+			for _, problem := range validateQuestion(q) {
+				problems = append(problems, fmt.Sprintf("quiz question %d: %s", i+1, problem))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("track %q must be %s or %s", m.Track, TrackFun, TrackEducational))
+	}
+
+	for _, hint := range m.Hints {
+		if strings.TrimSpace(hint) == "" {
+			problems = append(problems, "hints must not be empty")
+			break
+		}
+	}
+	for _, alias := range m.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			problems = append(problems, "aliases must not be empty")
+			break
+		}
+	}
+	for _, id := range m.Prerequisites {
+		switch pre, ok := byID[id]; {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("prerequisite %q does not exist", id))
+		case id == m.ID:
+			problems = append(problems, "a mystery can't be its own prerequisite")
+		case pre.Track != m.Track:
+			problems = append(problems, fmt.Sprintf("prerequisite %q is on the %s track", id, pre.Track))
+		}
+	}
+	return problems
+}
+
+func validateQuestion(q QuizQuestion) []string {
+	var problems []string
+	if strings.TrimSpace(q.Question) == "" {
+		problems = append(problems, "question is required")
+	}
+	if strings.TrimSpace(q.Answer) == "" {
+		problems = append(problems, "answer is required")
+	}
+	if len(q.Choices) == 0 {
+		return problems
+	}
+	if len(q.Choices) < 2 || len(q.Choices) > 26 {
+		problems = append(problems, "multiple choice needs 2 to 26 choices")
+	}
+	if !slices.Contains(q.Choices, q.Answer) {
+		problems = append(problems, fmt.Sprintf("answer %q is not one of the choices", q.Answer))
+	}
+	if len(q.Aliases) > 0 {
+		problems = append(problems, "aliases are for free-text questions")
+	}
+	return problems
+}
+
+// validateTranslation lists what a translator needs to fix in t: it must
+// translate an existing mystery, leave its settings alone, and keep the
+// same hints and quiz questions.
+func validateTranslation(t Mystery, byID map[string]Mystery) []string {
+	m, ok := byID[t.ID]
+	if !ok {
+		return []string{fmt.Sprintf("no mystery %q to translate", t.ID)}
+	}
+
+	var problems []string
+	if t.Track != "" || t.Difficulty != "" || len(t.Prerequisites) > 0 || len(t.Tags) > 0 {
+		problems = append(problems, "track, difficulty, prerequisites and tags come from the mystery, not its translation")
+	}
+	if len(t.Hints) > 0 && len(t.Hints) != len(m.Hints) {
+		problems = append(problems, fmt.Sprintf("%d hints translated, the mystery has %d", len(t.Hints), len(m.Hints)))
+	}
+	if len(t.Quiz) > 0 && len(t.Quiz) != len(m.Quiz) {
+		problems = append(problems, fmt.Sprintf("%d quiz questions translated, the mystery has %d", len(t.Quiz), len(m.Quiz)))
+	}
+	for i, q := range t.Quiz {
+		for _, problem := range validateQuestion(q) {
+			problems = append(problems, fmt.Sprintf("quiz question %d: %s", i+1, problem))
+		}
+		if i < len(m.Quiz) && len(q.Choices) != len(m.Quiz[i].Choices) {
+			problems = append(problems, fmt.Sprintf("quiz question %d: %d choices translated, the question has %d", i+1, len(q.Choices), len(m.Quiz[i].Choices)))
+		}
+	}
+	return problems
+}
+
+// prerequisiteCycles reports mysteries that could never become available.
+func prerequisiteCycles(byID map[string]Mystery) []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var problems []string
+
+	var visit func(id string, chain []string)
+	visit = func(id string, chain []string) {
+		switch state[id] {
+		case visiting:
+			start := slices.Index(chain, id)
+			problems = append(problems, "prerequisite cycle: "+strings.Join(append(slices.Clone(chain[start:]), id), " -> "))
+			return
+		case done:
+			return
+		}
+		state[id] = visiting
+		for _, pre := range byID[id].Prerequisites {
+			if _, ok := byID[pre]; ok && pre != id {
+				visit(pre, append(chain, id))
+			}
+		}
+		state[id] = done
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		visit(id, nil)
+	}
+	return problems
+}
+
+// Track returns the track's mysteries. Callers must not modify them.
+func (c *Catalog) Track(track string) []Mystery {
+	var mysteries []Mystery
+	for _, m := range c.mysteries {
+		if m.Track == track {
+			mysteries = append(mysteries, m)
+		}
+	}
+	return mysteries
+}
+
+// Localize returns m with the text of its translation into the locale, or
+// m itself when there is none. The original solution, aliases and
+// free-text quiz answers still count as correct.
+func (c *Catalog) Localize(m *Mystery, code string) *Mystery {
+	if m == nil {
+		return nil
+	}
+	t, ok := c.translations[locale.Normalize(code)][m.ID]
+	if !ok {
+		return m
+	}
+
+	out := *m
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&out.Title, t.Title},
+		{&out.Description, t.Description},
+		{&out.Concept, t.Concept},
+		{&out.Summary, t.Summary},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	if len(t.Hints) > 0 {
+		out.Hints = slices.Clone(t.Hints)
+	}
+	if t.Solution != "" {
+		out.Solution = t.Solution
+		out.Aliases = slices.Concat(t.Aliases, []string{m.Solution}, m.Aliases)
+	} else {
+		out.Aliases = slices.Concat(m.Aliases, t.Aliases)
+	}
+	if len(t.Quiz) > 0 {
+		out.Quiz = make([]QuizQuestion, len(t.Quiz))
+		for i, q := range t.Quiz {
+			if len(q.Choices) == 0 {
+				q.Aliases = slices.Concat(q.Aliases, []string{m.Quiz[i].Answer}, m.Quiz[i].Aliases)
+			}
+			out.Quiz[i] = q
+		}
+	}
+	return &out
+}
+
+// Len returns how many mysteries the catalog holds.
+func (c *Catalog) Len() int {
+	return len(c.mysteries)
+}
+
+var catalog atomic.Pointer[Catalog]
+
+func init() {
+	c, err := LoadCatalog(DefaultMysteries())
+	if err != nil {
+		panic(err)
+	}
+	catalog.Store(c)
+}
+
+// Mysteries returns the catalog in use.
+func Mysteries() *Catalog {
+	return catalog.Load()
+}
+
+// LoadMysteries replaces the catalog with the built-in mysteries plus those
+// in dir, which may add mysteries or override built-in ones by ID. An
+// invalid catalog is rejected and the current one kept.
+func LoadMysteries(dir string) (*Catalog, error) {
+	fsyss := []fs.FS{DefaultMysteries()}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		fsyss = append(fsyss, os.DirFS(dir))
+	}
+	c, err := LoadCatalog(fsyss...)
+	if err != nil {
+		return nil, err
+	}
+	catalog.Store(c)
+	return c, nil
+}
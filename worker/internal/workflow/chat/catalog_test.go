@@ -0,0 +1,224 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDefaultMysteriesAreValid(t *testing.T) {
+	c, err := LoadCatalog(DefaultMysteries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fun, edu := len(c.Track(TrackFun)), len(c.Track(TrackEducational)); fun != 3 || edu != 7 {
+		t.Errorf("loaded %d fun and %d educational mysteries", fun, edu)
+	}
+
+	replay := GetMystery("replay", TrackEducational)
+	if replay == nil || replay.Concept != "Workflow replay" || !strings.HasPrefix(replay.Summary, "When a worker crashes") {
+		t.Errorf("replay = %+v", replay)
+	}
+	if snack := GetMystery("missing-snack", TrackFun); snack == nil || len(snack.Hints) != 3 || len(snack.Aliases) == 0 {
+		t.Errorf("missing-snack = %+v", snack)
+	}
+}
+
+func TestLoadCatalogOverridesAndValidates(t *testing.T) {
+	override := fstest.MapFS{
+		"missing-snack.yaml": {Data: []byte(`
+id: missing-snack
+title: The Missing Snack
+track: fun
+difficulty: easy
+description: Someone took my moss!
+hints: [Check the pond]
+solution: A snail
+`)},
+		"lost-moon.md": {Data: []byte(`---
+id: lost-moon
+title: The Lost Moon
+track: fun
+difficulty: medium
+description: The moon was here yesterday.
+hints: [Look up]
+solution: It was a new moon
+prerequisites: [missing-snack]
+---
+Notes for authors are fine here.
+`)},
+		"README.txt": {Data: []byte("ignored")},
+	}
+	c, err := LoadCatalog(DefaultMysteries(), override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fun := c.Track(TrackFun)
+	if len(fun) != 4 || fun[0].ID != "missing-snack" || fun[0].Description != "Someone took my moss!" {
+		t.Errorf("fun track = %+v", fun)
+	}
+
+	broken := fstest.MapFS{
+		"bad-quiz.md":  {Data: []byte("---\nid: bad-quiz\ntitle: Q\ntrack: educational\ndifficulty: easy\nconcept: Q\ndescription: q\nquiz:\n  - question: Which?\n    choices: [a, b]\n    answer: c\n---\nBody\n")},
+		"loop-a.yaml":  {Data: []byte("id: loop-a\ntitle: A\ntrack: fun\ndifficulty: easy\ndescription: a\nhints: [a]\nsolution: a\nprerequisites: [loop-b]\n")},
+		"loop-b.yaml":  {Data: []byte("id: loop-b\ntitle: B\ntrack: fun\ndifficulty: easy\ndescription: b\nhints: [b]\nsolution: b\nprerequisites: [loop-a, replay]\n")},
+		"renamed.yaml": {Data: []byte("id: other\n")},
+		"typo.yaml":    {Data: []byte("id: typo\nhint: [oops]\n")},
+		"topic.md":     {Data: []byte("id: topic\n")},
+	}
+	_, err = LoadCatalog(DefaultMysteries(), broken)
+	var catalogErr *CatalogError
+	if !errors.As(err, &catalogErr) {
+		t.Fatalf("LoadCatalog() error = %v, want a CatalogError", err)
+	}
+	want := []string{
+		`bad-quiz.md: quiz question 1: answer "c" is not one of the choices`,
+		`loop-b.yaml: prerequisite "replay" is on the educational track`,
+		"prerequisite cycle: loop-a -> loop-b -> loop-a",
+		`renamed.yaml: id "other" does not match the file name`,
+		"topic.md: missing --- front matter",
+		"typo.yaml: yaml: unmarshal errors:\n  line 2: field hint not found in type chat.Mystery",
+	}
+	if got := strings.Join(catalogErr.Problems, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadCatalogTranslations(t *testing.T) {
+	c := Mysteries()
+	snack := GetMystery("missing-snack", TrackFun)
+	if got := c.Localize(snack, "en"); got != snack {
+		t.Errorf("Localize(en) = %+v, want the mystery itself", got)
+	}
+	es := c.Localize(snack, "es")
+	if es.Title != "El tentempié perdido" || es.Hints[0] != "Las migas llevan a un lugar frío..." || es.Difficulty != snack.Difficulty {
+		t.Errorf("Localize(es) = %+v", es)
+	}
+	if snack.Title != "The Missing Snack" {
+		t.Errorf("Localize changed the catalog's mystery: %+v", snack)
+	}
+
+	// A replaced mystery loses its built-in translations
+	override := fstest.MapFS{
+		"missing-snack.yaml":            {Data: []byte("id: missing-snack\ntitle: The Missing Snack\ntrack: fun\ndifficulty: easy\ndescription: Someone took my moss!\nhints: [Check the pond]\nsolution: A snail\n")},
+		"locales/es/missing-snack.yaml": {Data: []byte("id: missing-snack\ntitle: El musgo perdido\nhints: [Mira el estanque]\nsolution: Un caracol\n")},
+	}
+	c, err := LoadCatalog(DefaultMysteries(), override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snack = &c.Track(TrackFun)[0]
+	if got := c.Localize(snack, "ja"); got != snack {
+		t.Errorf("Localize(ja) kept a stale translation: %+v", got)
+	}
+	if got := c.Localize(snack, "es"); got.Title != "El musgo perdido" || !slices.Contains(got.Aliases, "A snail") {
+		t.Errorf("Localize(es) = %+v", got)
+	}
+
+	broken := fstest.MapFS{
+		"locales/es/lost-moon.yaml":    {Data: []byte("id: lost-moon\ntitle: La luna perdida\n")},
+		"locales/es/cosmic-radio.yaml": {Data: []byte("id: cosmic-radio\ntrack: fun\nhints: [Bip]\n")},
+		"locales/xx/dream-maze.yaml":   {Data: []byte("id: dream-maze\n")},
+		"locales/ja/replay.md":         {Data: []byte("---\nid: replay\nquiz:\n  - question: どれ？\n    choices: [a, b]\n    answer: a\n---\n本文\n")},
+		"locales/ja/cosmic-radio.yaml": {Data: []byte("id: cosmic-radio\ntitle: 宇宙ラジオ\n")},
+	}
+	_, err = LoadCatalog(DefaultMysteries(), broken)
+	var catalogErr *CatalogError
+	if !errors.As(err, &catalogErr) {
+		t.Fatalf("LoadCatalog() error = %v, want a CatalogError", err)
+	}
+	want := []string{
+		"locales/es/cosmic-radio.yaml: 1 hints translated, the mystery has 3",
+		"locales/es/cosmic-radio.yaml: track, difficulty, prerequisites and tags come from the mystery, not its translation",
+		`locales/es/lost-moon.yaml: no mystery "lost-moon" to translate`,
+		"locales/ja/replay.md: 1 quiz questions translated, the mystery has 3",
+		"locales/ja/replay.md: quiz question 1: 2 choices translated, the question has 3",
+		`locales/xx/dream-maze.yaml: unsupported locale "xx"`,
+	}
+	if got := strings.Join(catalogErr.Problems, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestGetAvailableMysteriesWaitsForPrerequisites(t *testing.T) {
+	ids := func(mysteries []Mystery) string {
+		var out []string
+		for _, m := range mysteries {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(GetAvailableMysteries(TrackFun, nil)); got != "missing-snack,cosmic-radio" {
+		t.Errorf("available = %s", got)
+	}
+	if got := ids(GetAvailableMysteries(TrackFun, []string{"missing-snack"})); got != "cosmic-radio,dream-maze" {
+		t.Errorf("available after missing-snack = %s", got)
+	}
+}
+
+func TestWatchMysteriesReloads(t *testing.T) {
+	t.Cleanup(func() {
+		if _, err := LoadMysteries(""); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchMysteries(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid file is ignored until it's fixed
+	path := filepath.Join(dir, "moss-party.yaml")
+	if err := os.WriteFile(path, []byte("id: moss-party\ntrack: fun\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * reloadDelay)
+	if GetMystery("moss-party", TrackFun) != nil {
+		t.Fatal("invalid mystery was loaded")
+	}
+
+	valid := "id: moss-party\ntitle: Moss Party\ntrack: fun\ndifficulty: easy\ndescription: Who threw it?\nhints: [Green]\nsolution: The moss\n"
+	if err := os.WriteFile(path, []byte(valid), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("catalog was not reloaded: %s", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitFor("moss-party", func() bool { return GetMystery("moss-party", TrackFun) != nil })
+
+	// Translations in locale directories made after the watch began are
+	// picked up, and so are later edits to them
+	title := func() string {
+		return Mysteries().Localize(GetMystery("moss-party", TrackFun), "ja").Title
+	}
+	ja := filepath.Join(dir, "locales", "ja")
+	if err := os.MkdirAll(ja, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(ja, "moss-party.yaml")
+	if err := os.WriteFile(path, []byte("id: moss-party\ntitle: コケパーティー\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("new translation", func() bool { return title() == "コケパーティー" })
+	if err := os.WriteFile(path, []byte("id: moss-party\ntitle: コケのうたげ\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("edited translation", func() bool { return title() == "コケのうたげ" })
+}
@@ -0,0 +1,198 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+// Chat commands. They run in ProcessChatMessage without the model, so they
+// work offline and always do the same thing.
+const (
+	CommandStatus  = "status"
+	CommandFeed    = "feed"
+	CommandHint    = "hint"
+	CommandGiveUp  = "giveup"
+	CommandMystery = "mystery"
+	CommandTrack   = "track"
+	CommandClear   = "clear"
+	CommandHelp    = "help"
+)
+
+// commandHelp lists the commands for /help; each is described by the
+// message "help.<name>".
+var commandHelp = []struct{ usage, name string }{
+	{"/status", CommandStatus},
+	{"/feed", CommandFeed},
+	{"/hint", CommandHint},
+	{"/giveup", CommandGiveUp},
+	{"/mystery [id]", CommandMystery},
+	{"/track fun|educational", CommandTrack},
+	{"/clear", CommandClear},
+	{"/help", CommandHelp},
+}
+
+// IsCommand reports whether a chat message is a command.
+func IsCommand(content string) bool {
+	_, _, ok := parseCommand(content)
+	return ok
+}
+
+// parseCommand splits "/name arg" into its lowercased name and argument.
+func parseCommand(content string) (name, arg string, ok bool) {
+	content = strings.TrimSpace(content)
+	rest, found := strings.CutPrefix(content, "/")
+	if !found || rest == "" || !isCommandLetter(rest[0]) {
+		return "", "", false
+	}
+	name, arg, _ = strings.Cut(rest, " ")
+	return strings.ToLower(name), strings.TrimSpace(arg), true
+}
+
+func isCommandLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// commandResult is what a command did.
+type commandResult struct {
+	reply string
+	care  []string
+	track string
+}
+
+// runCommand executes a command against the chat state, replying in its
+// locale.
+func runCommand(state *State, name, arg, track string, ziggy *z.State, now time.Time) commandResult {
+	code := state.Locale
+	switch name {
+	case CommandStatus:
+		return commandResult{reply: statusReply(state, ziggy, track)}
+
+	case CommandFeed:
+		return commandResult{reply: text(code, textFeed), care: []string{ziggyworkflow.SignalFeed}}
+
+	case CommandHint:
+		return commandResult{reply: nextHint(state, now)}
+
+	case CommandGiveUp:
+		switch {
+		case state.Quiz != nil:
+			// Unanswered questions count as missed
+			for len(state.Quiz.Results) < len(state.Quiz.Questions) {
+				state.Quiz.Results = append(state.Quiz.Results, false)
+			}
+			return commandResult{reply: text(code, textQuizOver) + "\n" + state.finishQuiz(now)}
+		case state.ActiveMystery != nil && state.ActiveMystery.Track != TrackEducational:
+			solution := state.ActiveMystery.Solution
+			state.noteRevealed(now)
+			state.AbandonMystery()
+			return commandResult{reply: text(code, textAnswerWas, "solution", solution)}
+		case state.ActiveMystery != nil:
+			state.AbandonMystery()
+			return commandResult{reply: text(code, textLearnElse)}
+		}
+		return commandResult{reply: text(code, textNothingToGiveUp)}
+
+	case CommandMystery:
+		return commandResult{reply: startMysteryCommand(state, arg, track, now)}
+
+	case CommandTrack:
+		switch arg = strings.ToLower(arg); arg {
+		case TrackFun, TrackEducational:
+			return commandResult{reply: text(code, textTrackSwitched, "track", trackName(code, arg)), track: arg}
+		}
+		return commandResult{reply: text(code, textTrackUsage)}
+
+	case CommandClear:
+		state.ClearHistory()
+		return commandResult{reply: text(code, textCleared)}
+
+	case CommandHelp:
+		lines := []string{text(code, textHelp)}
+		for _, c := range commandHelp {
+			lines = append(lines, c.usage+" - "+text(code, "help."+c.name))
+		}
+		return commandResult{reply: strings.Join(lines, "\n")}
+	}
+	return commandResult{reply: text(code, textUnknownCommand, "command", name)}
+}
+
+func statusReply(state *State, ziggy *z.State, track string) string {
+	code := state.Locale
+	lines := []string{text(code, textStatus)}
+	if ziggy != nil {
+		lines = append(lines,
+			text(code, textStatusMood, "mood", moodName(code, ziggy.GetMood())),
+			text(code, textStatusStats, "fullness", fmt.Sprintf("%.0f", ziggy.Fullness), "happiness", fmt.Sprintf("%.0f", ziggy.Happiness),
+				"bond", fmt.Sprintf("%.0f", ziggy.Bond), "hp", fmt.Sprintf("%.0f", ziggy.HP)))
+	}
+	lines = append(lines, text(code, textStatusTrack, "track", trackName(code, normalizeTrack(track))))
+	switch m := state.ActiveMystery; {
+	case state.Quiz != nil:
+		lines = append(lines, text(code, textStatusQuiz, "concept", state.Quiz.Concept,
+			"n", strconv.Itoa(len(state.Quiz.Results)+1), "total", strconv.Itoa(len(state.Quiz.Questions))))
+	case m != nil && m.Track == TrackEducational:
+		lines = append(lines, text(code, textStatusTopic, "title", m.Title))
+	case m != nil:
+		lines = append(lines, text(code, textStatusMystery, "title", m.Title,
+			"given", strconv.Itoa(len(state.HintsGiven)), "total", strconv.Itoa(len(m.Hints))))
+	default:
+		lines = append(lines, text(code, textStatusNone))
+	}
+	stats := state.Stats()
+	lines = append(lines, text(code, textStatusSolved, "solved", strconv.Itoa(stats.Solved),
+		"total", strconv.Itoa(stats.Total), "score", strconv.Itoa(stats.Score)))
+	return strings.Join(lines, "\n")
+}
+
+// nextHint gives the active fun mystery's next hint.
+func nextHint(state *State, now time.Time) string {
+	m := state.ActiveMystery
+	if m == nil || m.Track == TrackEducational {
+		return text(state.Locale, textNoMysteryToHint)
+	}
+	if state.MysteryProgress >= len(m.Hints) {
+		return text(state.Locale, textNoHintsLeft)
+	}
+	hint := m.Hints[state.MysteryProgress]
+	state.HintsGiven = append(state.HintsGiven, hint)
+	state.MysteryProgress++
+	state.noteHints(now)
+	return text(state.Locale, textHint, "n", strconv.Itoa(state.MysteryProgress), "total", strconv.Itoa(len(m.Hints)), "hint", hint)
+}
+
+func startMysteryCommand(state *State, id, track string, now time.Time) string {
+	if state.ActiveMystery != nil || state.Quiz != nil {
+		return text(state.Locale, textFinishFirst)
+	}
+
+	var m *Mystery
+	if id == "" {
+		m = GetRandomMystery(track, state.Completed())
+	} else {
+		id = strings.ToLower(id)
+		if m = GetMystery(id, TrackFun); m == nil {
+			m = GetMystery(id, TrackEducational)
+		}
+	}
+	if m == nil {
+		return text(state.Locale, textUnknownMystery, "id", strconv.Quote(id))
+	}
+	m = Mysteries().Localize(m, state.Locale)
+	for _, pre := range m.Prerequisites {
+		if !slices.Contains(state.Completed(), pre) {
+			return text(state.Locale, textLocked, "title", m.Title, "prerequisite", pre)
+		}
+	}
+
+	state.StartMystery(m, now)
+	if m.Track == TrackEducational {
+		return text(state.Locale, textLearnAbout, "concept", m.Concept)
+	}
+	return text(state.Locale, textMysteryIntro, "title", m.Title, "description", m.Description)
+}
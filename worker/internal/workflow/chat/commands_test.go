@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"ziggy/internal/ai"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		content   string
+		name, arg string
+		ok        bool
+	}{
+		{"/status", "status", "", true},
+		{"  /Track  Educational ", "track", "Educational", true},
+		{"/mystery dream-maze", "mystery", "dream-maze", true},
+		{"/", "", "", false},
+		{"/ hint", "", "", false},
+		{"/42", "", "", false},
+		{"what does /hint do?", "", "", false},
+	}
+	for _, tt := range tests {
+		name, arg, ok := parseCommand(tt.content)
+		if name != tt.name || arg != tt.arg || ok != tt.ok {
+			t.Errorf("parseCommand(%q) = %q, %q, %v, want %q, %q, %v", tt.content, name, arg, ok, tt.name, tt.arg, tt.ok)
+		}
+	}
+}
+
+func TestProcessChatMessageRunsCommands(t *testing.T) {
+	// No replies queued: a command reaching the model would fail the test
+	fake := &ai.FakeProvider{}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ziggy := z.NewState("UTC")
+	run := func(state State, content string) ProcessMessageOutput {
+		t.Helper()
+		return runProcessMessage(t, fake, ProcessMessageInput{State: state, Content: content, Track: TrackFun, ZiggyState: &ziggy, Now: now})
+	}
+
+	out := run(NewState("test"), "/mystery dream-maze")
+	if out.State.ActiveMystery != nil || !strings.Contains(lastMessage(out.State), "is locked") {
+		t.Errorf("/mystery started a locked mystery: %q", lastMessage(out.State))
+	}
+
+	out = run(NewState("test"), "/mystery missing-snack")
+	state := out.State
+	if state.ActiveMystery == nil || state.ActiveMystery.ID != "missing-snack" || out.Command != CommandMystery {
+		t.Fatalf("/mystery: active %v, command %q", state.ActiveMystery, out.Command)
+	}
+
+	state = run(state, "/hint").State
+	if got := lastMessage(state); got != "*whispers* Hint 1/3:\nThe crumbs lead somewhere cold..." || state.MysteryProgress != 1 {
+		t.Errorf("/hint = %q, progress %d", got, state.MysteryProgress)
+	}
+	if r := state.Records["missing-snack"]; r.HintsUsed != 1 {
+		t.Errorf("record = %+v", r)
+	}
+
+	if got := lastMessage(run(state, "/status").State); !strings.Contains(got, "Mystery: The Missing Snack, 1/3 hints") || !strings.Contains(got, "Mood: ") {
+		t.Errorf("/status = %q", got)
+	}
+
+	state = run(state, "/giveup").State
+	if state.ActiveMystery != nil || !strings.HasSuffix(lastMessage(state), "The cosmic crumbs were eaten by a passing comet") {
+		t.Errorf("/giveup: active %v, reply %q", state.ActiveMystery, lastMessage(state))
+	}
+	if r := state.Records["missing-snack"]; !r.Revealed {
+		t.Errorf("record = %+v", r)
+	}
+
+	out = run(state, "/feed")
+	if !slices.Equal(out.Care, []string{ziggyworkflow.SignalFeed}) {
+		t.Errorf("/feed care = %v", out.Care)
+	}
+
+	if out = run(state, "/track educational"); out.Track != TrackEducational {
+		t.Errorf("/track track = %q", out.Track)
+	}
+	if out = run(state, "/track space"); out.Track != "" {
+		t.Errorf("/track space switched to %q", out.Track)
+	}
+
+	if got := lastMessage(run(state, "/dance").State); !strings.Contains(got, "I don't know /dance") {
+		t.Errorf("unknown command = %q", got)
+	}
+
+	state = run(state, "/clear").State
+	if len(state.Messages) != 1 || !state.Messages[0].Command {
+		t.Errorf("/clear left %d messages", len(state.Messages))
+	}
+	if len(convertMessages(state.Messages)) != 0 {
+		t.Error("command messages reach prompts")
+	}
+	if len(fake.ChatCalls()) != 0 {
+		t.Errorf("commands reached the model: %d calls", len(fake.ChatCalls()))
+	}
+}
+
+func TestGiveUpEndsQuiz(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state := NewState("test")
+	replay := GetMystery("replay", TrackEducational)
+	state.StartMystery(replay, now)
+	state.StartQuiz(replay, nil, false)
+	state.AnswerQuiz("b", now)
+
+	state = processMessage(t, &ai.FakeProvider{}, ProcessMessageInput{State: state, Content: "/giveup", Track: TrackEducational, Now: now})
+	if state.Quiz != nil || state.ActiveMystery != nil {
+		t.Fatalf("quiz %v, active %v", state.Quiz, state.ActiveMystery)
+	}
+	if got := lastMessage(state); !strings.Contains(got, "You got 1/3 right!") {
+		t.Errorf("reply = %q", got)
+	}
+	if c := state.Concepts["replay"]; c.Wrong != 2 || c.NextReview == nil {
+		t.Errorf("concept = %+v", c)
+	}
+}
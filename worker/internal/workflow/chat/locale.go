@@ -0,0 +1,299 @@
+package chat
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"ziggy/internal/locale"
+	z "ziggy/internal/ziggy"
+)
+
+// Chat messages sent outside the phrase bank: command replies, quiz
+// scaffolding and the words around a revealed answer.
+const (
+	textDeflectLength    = "deflectLength"
+	textDeflectInjection = "deflectInjection"
+	textDeflectContent   = "deflectContent"
+
+	textAnswerWas  = "answerWas"
+	textTryAnother = "tryAnother"
+
+	textFeed            = "feed"
+	textQuizOver        = "quizOver"
+	textLearnElse       = "learnElse"
+	textNothingToGiveUp = "nothingToGiveUp"
+	textTrackSwitched   = "trackSwitched"
+	textTrackUsage      = "trackUsage"
+	textCleared         = "cleared"
+	textUnknownCommand  = "unknownCommand"
+	textHelp            = "help"
+
+	textStatus        = "status"
+	textStatusMood    = "statusMood"
+	textStatusStats   = "statusStats"
+	textStatusTrack   = "statusTrack"
+	textStatusQuiz    = "statusQuiz"
+	textStatusTopic   = "statusTopic"
+	textStatusMystery = "statusMystery"
+	textStatusNone    = "statusNone"
+	textStatusSolved  = "statusSolved"
+
+	textNoMysteryToHint = "noMysteryToHint"
+	textNoHintsLeft     = "noHintsLeft"
+	textHint            = "hint"
+	textFinishFirst     = "finishFirst"
+	textUnknownMystery  = "unknownMystery"
+	textLocked          = "locked"
+	textLearnAbout      = "learnAbout"
+	textMysteryIntro    = "mysteryIntro"
+
+	textQuizStart   = "quizStart"
+	textReviewStart = "reviewStart"
+	textQuestion    = "question"
+	textCorrect     = "correct"
+	textIncorrect   = "incorrect"
+	textScore       = "score"
+	textReviewAgain = "reviewAgain"
+	textMastered    = "mastered"
+	textNextReview  = "nextReview"
+	textHour        = "hour"
+	textHours       = "hours"
+	textDay         = "day"
+	textDays        = "days"
+)
+
+// chatMessages are the English messages. Placeholders in braces are filled
+// in by text.
+var chatMessages = map[string]string{
+	textDeflectLength:    "*wiggles dizzily*\nThat's a lot of words!\nCan you say it shorter?",
+	textDeflectInjection: "*tilts head*\nI'm just Ziggy,\na tardigrade!\nWhat shall we talk about?",
+	textDeflectContent:   "*curls up*\nLet's talk about\nsomething nicer?",
+
+	textAnswerWas:  "*wiggles sympathetically*\nThe answer was: {solution}",
+	textTryAnother: "Nice try! Want to try another mystery?",
+
+	textFeed:            "*wiggles excitedly*\nSnack time!",
+	textQuizOver:        "*pats you*\nQuiz over!",
+	textLearnElse:       "*nods*\nLet's learn something else!",
+	textNothingToGiveUp: "*tilts head*\nThere's nothing to give up on!",
+	textTrackSwitched:   "*wiggle*\nSwitched to the {track} track!",
+	textTrackUsage:      "*tilts head*\nUse /track fun or /track educational",
+	textCleared:         "*wiggle*\nFresh start!",
+	textUnknownCommand:  "*tilts head*\nI don't know /{command}.\nTry /help",
+	textHelp:            "*wiggle* I know these commands:",
+
+	"help." + CommandStatus:  "how I'm doing and your mystery",
+	"help." + CommandFeed:    "feed me",
+	"help." + CommandHint:    "a hint for the mystery",
+	"help." + CommandGiveUp:  "reveal the answer or end the quiz",
+	"help." + CommandMystery: "start a mystery (random without an id)",
+	"help." + CommandTrack:   "switch tracks",
+	"help." + CommandClear:   "clear our chat",
+	"help." + CommandHelp:    "this list",
+
+	textStatus:        "*wiggle* Here's how things are:",
+	textStatusMood:    "Mood: {mood}",
+	textStatusStats:   "Fullness {fullness}, happiness {happiness}, bond {bond}, HP {hp}",
+	textStatusTrack:   "Track: {track}",
+	textStatusQuiz:    "Quiz: {concept}, question {n}/{total}",
+	textStatusTopic:   "Topic: {title}",
+	textStatusMystery: "Mystery: {title}, {given}/{total} hints",
+	textStatusNone:    "No mystery yet. Try /mystery",
+	textStatusSolved:  "Solved {solved}/{total}, score {score}",
+
+	textNoMysteryToHint: "*tilts head*\nNo mystery to hint at!\nTry /mystery",
+	textNoHintsLeft:     "*wiggles*\nNo hints left!\nMake a guess, or /giveup",
+	textHint:            "*whispers* Hint {n}/{total}:\n{hint}",
+	textFinishFirst:     "*wiggles*\nLet's finish this one first!\nOr /giveup",
+	textUnknownMystery:  "*tilts head*\nI don't know a mystery called {id}",
+	textLocked:          "*shakes head*\n{title} is locked.\nFinish {prerequisite} first!",
+	textLearnAbout:      "*bounces*\nLet's learn about {concept}!\nAsk me anything about it.",
+	textMysteryIntro:    "*bounces*\n{title}\n{description}",
+
+	textQuizStart:   "*bounces* Quiz time!",
+	textReviewStart: "*pokes you* Review time!\nLet's see what you remember about {concept}.",
+	textQuestion:    "Q{n}/{total}: {question}",
+	textCorrect:     "*happy wiggle* Correct!",
+	textIncorrect:   "*wiggles* Not quite!\nThe answer was: {answer}",
+	textScore:       "You got {right}/{total} right!",
+	textReviewAgain: "I'll ask you about {concept} again in {interval}.",
+	textMastered:    "You've mastered {concept}!",
+	textNextReview:  "Next review of {concept} in {interval}.",
+	textHour:        "1 hour",
+	textHours:       "{n} hours",
+	textDay:         "1 day",
+	textDays:        "{n} days",
+
+	"track." + TrackFun:         "fun",
+	"track." + TrackEducational: "educational",
+
+	"mood." + string(z.MoodHappy):    "happy",
+	"mood." + string(z.MoodNeutral):  "neutral",
+	"mood." + string(z.MoodHungry):   "hungry",
+	"mood." + string(z.MoodSad):      "sad",
+	"mood." + string(z.MoodLonely):   "lonely",
+	"mood." + string(z.MoodSleeping): "sleeping",
+	"mood." + string(z.MoodCritical): "critical",
+	"mood." + string(z.MoodTun):      "tun",
+}
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// localeFile is a chat locale file: the messages above, and a phrase bank
+// in the language that replaces the built-in one.
+type localeFile struct {
+	Messages   map[string]string `yaml:"messages"`
+	PhraseBank `yaml:",inline"`
+}
+
+// translation is a parsed locale file.
+type translation struct {
+	messages map[string]string
+	phrases  *PhraseBank
+}
+
+var translations = map[string]*translation{}
+
+func init() {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		code := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		t, err := parseTranslation(code, data)
+		if err != nil {
+			panic(err)
+		}
+		translations[code] = t
+	}
+}
+
+// parseTranslation reads a locale file. Its phrase bank must answer every
+// reply the built-in one does, and every line must fit the display.
+func parseTranslation(code string, data []byte) (*translation, error) {
+	if code == locale.Default || !locale.Valid(code) {
+		return nil, fmt.Errorf("chat locale %q: unsupported", code)
+	}
+	var f localeFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("chat locale %q: %w", code, err)
+	}
+
+	var problems []string
+	bank, err := LoadPhrases(data)
+	complete := err == nil
+	if !complete {
+		problems = append(problems, err.Error())
+		bank = &f.PhraseBank
+	}
+	for key, message := range f.Messages {
+		if _, ok := chatMessages[key]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown message %q", key))
+		} else if !fitsDisplay(message) {
+			problems = append(problems, fmt.Sprintf("message %q does not fit the display", key))
+		}
+	}
+	// Beyond what every bank needs, the translation answers whatever the
+	// built-in one does, such as proactive messages
+	if complete {
+		builtin, err := LoadPhrases(defaultPhrases)
+		if err != nil {
+			return nil, err
+		}
+		for reply := range builtin.Phrases {
+			if len(bank.Phrases[reply]["default"]) == 0 {
+				problems = append(problems, fmt.Sprintf("phrases %q need default lines", reply))
+			}
+		}
+	}
+	for reply, variants := range bank.Phrases {
+		for variant, lines := range variants {
+			for _, line := range lines {
+				if !fitsDisplay(line) {
+					problems = append(problems, fmt.Sprintf("%s %s line %q does not fit the display", reply, variant, line))
+				}
+			}
+		}
+	}
+	for _, fact := range bank.Facts {
+		if !locale.FitsDisplay(fact) {
+			problems = append(problems, fmt.Sprintf("fact %q does not fit the display", fact))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("chat locale %q: %s", code, strings.Join(problems, "; "))
+	}
+	return &translation{messages: f.Messages, phrases: bank}, nil
+}
+
+var placeholder = regexp.MustCompile(`\{[a-zA-Z]+\}`)
+
+// fitsDisplay reports whether a template fits the display once filled in.
+// Placeholders take no room, and lines holding nothing else are left out,
+// since what fills them, such as a mystery's description, is written
+// elsewhere.
+func fitsDisplay(template string) bool {
+	var lines []string
+	for _, line := range strings.Split(template, "\n") {
+		if strings.TrimSpace(placeholder.ReplaceAllString(line, "")) == "" && placeholder.MatchString(line) {
+			continue
+		}
+		lines = append(lines, placeholder.ReplaceAllString(line, ""))
+	}
+	return len(lines) == 0 || locale.FitsDisplay(strings.Join(lines, "\n"))
+}
+
+// text returns a chat message in the locale, or in English when it has no
+// translation, filled in from name and value pairs.
+func text(code, key string, vars ...string) string {
+	message := chatMessages[key]
+	if t := translations[locale.Normalize(code)]; t != nil {
+		if translated, ok := t.messages[key]; ok {
+			message = translated
+		}
+	}
+	pairs := make([]string, 0, len(vars))
+	for i := 0; i+1 < len(vars); i += 2 {
+		pairs = append(pairs, "{"+vars[i]+"}", vars[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// trackName is the track's name in the locale.
+func trackName(code, track string) string {
+	if name := text(code, "track."+track); name != "" {
+		return name
+	}
+	return track
+}
+
+// moodName is the mood's name in the locale.
+func moodName(code string, mood z.Mood) string {
+	if name := text(code, "mood."+string(mood)); name != "" {
+		return name
+	}
+	return string(mood)
+}
+
+// phrasesFor returns the phrase bank for a locale: its translation, or the
+// phrases in use for English and locales without one.
+func phrasesFor(code string) *PhraseBank {
+	if t := translations[locale.Normalize(code)]; t != nil {
+		return t.phrases
+	}
+	return Phrases()
+}
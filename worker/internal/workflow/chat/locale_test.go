@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"ziggy/internal/ai"
+)
+
+func TestTranslationsAreComplete(t *testing.T) {
+	for _, code := range []string{"es", "ja"} {
+		tr := translations[code]
+		if tr == nil {
+			t.Fatalf("no %s translation", code)
+		}
+		for key := range chatMessages {
+			if _, ok := tr.messages[key]; !ok {
+				t.Errorf("%s: message %q is not translated", code, key)
+			}
+		}
+		if phrasesFor(code) == Phrases() {
+			t.Errorf("%s: offline replies use the English phrases", code)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	if got := text("", textAnswerWas, "solution", "a comet"); got != "*wiggles sympathetically*\nThe answer was: a comet" {
+		t.Errorf("text(en) = %q", got)
+	}
+	if got := text("ja", textAnswerWas, "solution", "彗星"); got != "*しょんぼり*\n答えは…\n彗星" {
+		t.Errorf("text(ja) = %q", got)
+	}
+	if got := text("xx", textCleared); got != chatMessages[textCleared] {
+		t.Errorf("text(xx) = %q", got)
+	}
+	if got := deflection("es", []ai.Violation{{Kind: ai.ViolationLength}}); !strings.Contains(got, "¡Cuántas palabras!") {
+		t.Errorf("es deflection = %q", got)
+	}
+}
+
+func TestParseTranslation(t *testing.T) {
+	tests := []struct {
+		name, code, data, want string
+	}{
+		{"unsupported locale", "xx", "messages: {}", "unsupported"},
+		{"English", "en", "messages: {}", "unsupported"},
+		{"unknown field", "es", "mesages: {}", "mesages"},
+		{"unknown message", "es", "messages:\n  sneeze: achís", `unknown message "sneeze"`},
+		{"missing replies", "es", "facts: [hola]\nphrases: {}", `phrases "smalltalk" need default lines`},
+		// Twelve kanji fill 24 columns; thirteen overflow them, and a
+		// placeholder takes no room
+		{"wide message", "ja", "messages:\n  cleared: 一二三四五六七八九十一二三", `message "cleared" does not fit`},
+		{"wide template", "ja", "messages:\n  mastered: \"{concept}一二三四五六七八九十一二三\"", `message "mastered" does not fit`},
+		{"wide phrase", "ja", "phrases:\n  wrong:\n    default: [一二三四五六七八九十一二三]", `wrong default line`},
+	}
+	for _, tt := range tests {
+		_, err := parseTranslation(tt.code, []byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	if !fitsDisplay("*ぴょん*\nなぞだよ！\n{title}\n{description}") {
+		t.Error("lines holding only a placeholder count against the display")
+	}
+}
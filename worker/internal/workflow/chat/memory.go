@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ziggy/internal/ai"
+)
+
+const (
+	// MemoryBatch is how many new messages accumulate before they are
+	// summarized into memories.
+	MemoryBatch = 10
+
+	// MaxMemories caps how many facts are kept; the oldest go first.
+	MaxMemories = 40
+
+	SummarizeMemoriesActivity = "SummarizeMemories"
+)
+
+type SummarizeMemoriesInput struct {
+	// ZiggyID is the account AI usage is billed to.
+	ZiggyID  string    `json:"ziggyId,omitempty"`
+	Memories []Memory  `json:"memories"`
+	Messages []Message `json:"messages"`
+	Now      time.Time `json:"now"`
+}
+
+type SummarizeMemoriesOutput struct {
+	Memories []Memory `json:"memories"`
+}
+
+// SummarizeMemories asks the AI provider for new facts in messages and merges
+// them into the existing memories. Without a provider, or when it fails, the
+// memories are returned unchanged so the messages still count as covered.
+func (a *Activities) SummarizeMemories(ctx context.Context, input SummarizeMemoriesInput) (*SummarizeMemoriesOutput, error) {
+	ctx = ai.WithAccount(ctx, input.ZiggyID)
+	memories := input.Memories
+	if a.provider == nil || !a.provider.Available() || len(input.Messages) == 0 {
+		return &SummarizeMemoriesOutput{Memories: memories}, nil
+	}
+
+	result, err := a.provider.ExtractMemories(ctx, ai.MemoryInput{
+		Known:    memoryFacts(memories),
+		Messages: convertMessages(input.Messages),
+	})
+	if err != nil {
+		log.Printf("[ChatActivity] Memory extraction failed: %v", err)
+		return &SummarizeMemoriesOutput{Memories: memories}, nil
+	}
+
+	memories = mergeMemories(memories, result.Facts, input.Now)
+	log.Printf("[ChatActivity] Remembered %d new facts (%d total)", len(result.Facts), len(memories))
+	return &SummarizeMemoriesOutput{Memories: memories}, nil
+}
+
+// mergeMemories appends facts that aren't already remembered and drops the
+// oldest memories beyond MaxMemories.
+func mergeMemories(memories []Memory, facts []ai.MemoryFact, now time.Time) []Memory {
+	merged := append([]Memory(nil), memories...)
+	for i, f := range facts {
+		if hasMemory(merged, f.Fact) {
+			continue
+		}
+		merged = append(merged, Memory{
+			ID:        fmt.Sprintf("mem-%d-%d", now.UnixNano(), i),
+			Kind:      f.Kind,
+			Fact:      f.Fact,
+			CreatedAt: now,
+		})
+	}
+	if len(merged) > MaxMemories {
+		merged = merged[len(merged)-MaxMemories:]
+	}
+	return merged
+}
+
+func hasMemory(memories []Memory, fact string) bool {
+	for _, m := range memories {
+		if strings.EqualFold(strings.TrimSpace(m.Fact), strings.TrimSpace(fact)) {
+			return true
+		}
+	}
+	return false
+}
+
+func memoryFacts(memories []Memory) []string {
+	if len(memories) == 0 {
+		return nil
+	}
+	facts := make([]string, len(memories))
+	for i, m := range memories {
+		facts[i] = m.Fact
+	}
+	return facts
+}
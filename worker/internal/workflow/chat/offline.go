@@ -0,0 +1,336 @@
+package chat
+
+import (
+	_ "embed"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+
+	"ziggy/internal/locale"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+//go:embed phrases.yaml
+var defaultPhrases []byte
+
+// Offline intents the engine acts on; a phrase file may add others, which
+// are answered from their phrases alone.
+const (
+	intentHint     = "hint"
+	intentFeelings = "feelings"
+	intentFeed     = "feed"
+	intentPlay     = "play"
+	intentPet      = "pet"
+	intentFact     = "fact"
+	intentMystery  = "mystery"
+)
+
+// Bond tiers that pick phrase variants.
+const (
+	bondDistant = 25
+	bondClose   = 75
+)
+
+// Replies every phrase bank needs, besides one per intent.
+var requiredPhrases = []string{
+	"smalltalk", "lesson", "noMysteries", "mysteryActive", "noMystery",
+	"noHints", "wrong", "wrongHint", "solved", "explain", "evidence",
+}
+
+// Intent is a kind of message the offline engine recognizes.
+type Intent struct {
+	Name string `yaml:"name"`
+	// Patterns are phrases whose words, appearing together in a message,
+	// mark it as this intent.
+	Patterns []string `yaml:"patterns"`
+}
+
+// PhraseBank is what Ziggy says without an AI provider: the intents it
+// recognizes, and lines for each reply by variant.
+type PhraseBank struct {
+	Intents []Intent                       `yaml:"intents"`
+	Facts   []string                       `yaml:"facts"`
+	Phrases map[string]map[string][]string `yaml:"phrases"`
+}
+
+// LoadPhrases parses phrase files in turn. A later file adds patterns to
+// intents of the same name, new intents after the earlier ones, and lines
+// to the earlier replies.
+func LoadPhrases(files ...[]byte) (*PhraseBank, error) {
+	bank := &PhraseBank{Phrases: map[string]map[string][]string{}}
+	for i, data := range files {
+		var f PhraseBank
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("phrase file %d: %w", i+1, err)
+		}
+		for _, intent := range f.Intents {
+			j := slices.IndexFunc(bank.Intents, func(in Intent) bool { return in.Name == intent.Name })
+			if j < 0 {
+				bank.Intents = append(bank.Intents, intent)
+			} else {
+				bank.Intents[j].Patterns = append(bank.Intents[j].Patterns, intent.Patterns...)
+			}
+		}
+		bank.Facts = append(bank.Facts, f.Facts...)
+		for reply, variants := range f.Phrases {
+			if bank.Phrases[reply] == nil {
+				bank.Phrases[reply] = map[string][]string{}
+			}
+			for variant, lines := range variants {
+				bank.Phrases[reply][variant] = append(bank.Phrases[reply][variant], lines...)
+			}
+		}
+	}
+
+	var problems []string
+	for _, intent := range bank.Intents {
+		if intent.Name == "" || len(intent.Patterns) == 0 {
+			problems = append(problems, fmt.Sprintf("intent %q needs a name and patterns", intent.Name))
+		}
+	}
+	required := slices.Clone(requiredPhrases)
+	for _, intent := range bank.Intents {
+		required = append(required, intent.Name)
+	}
+	for _, reply := range required {
+		if len(bank.Phrases[reply]["default"]) == 0 {
+			problems = append(problems, fmt.Sprintf("phrases %q need default lines", reply))
+		}
+	}
+	if len(bank.Facts) == 0 {
+		problems = append(problems, "no facts")
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid phrases: %s", strings.Join(problems, "; "))
+	}
+	return bank, nil
+}
+
+var phrases atomic.Pointer[PhraseBank]
+
+func init() {
+	bank, err := LoadPhrases(defaultPhrases)
+	if err != nil {
+		panic(err)
+	}
+	phrases.Store(bank)
+}
+
+// Phrases returns the phrase bank in use.
+func Phrases() *PhraseBank {
+	return phrases.Load()
+}
+
+// LoadPhraseFile adds the phrases in path to the built-in ones. An invalid
+// file is rejected and the current phrases kept.
+func LoadPhraseFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	bank, err := LoadPhrases(defaultPhrases, data)
+	if err != nil {
+		return err
+	}
+	phrases.Store(bank)
+	return nil
+}
+
+// Match returns the first intent a message shows, or "". Care intents only
+// count on the fun track. Patterns in wide scripts such as Japanese match
+// anywhere in the message.
+func (b *PhraseBank) Match(content, track string) string {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	}), " ") + " "
+	words = strings.ReplaceAll(words, "'", "")
+
+	for _, intent := range b.Intents {
+		switch intent.Name {
+		case intentFeed, intentPlay, intentPet:
+			if track == TrackEducational {
+				continue
+			}
+		}
+		for _, p := range intent.Patterns {
+			p = strings.ToLower(p)
+			if locale.Width(p) > utf8.RuneCountInString(p) && strings.Contains(words, p) {
+				return intent.Name
+			}
+			if strings.Contains(words, " "+p+" ") {
+				return intent.Name
+			}
+		}
+	}
+	return ""
+}
+
+// tone picks lines in the owner's language for Ziggy's personality, stage
+// and bond, with the same message always getting the same line.
+type tone struct {
+	bank     *PhraseBank
+	variants []string
+	seed     uint32
+	vars     map[string]string
+}
+
+func newTone(code string, ziggy *z.State, content string, turn int, now time.Time) *tone {
+	bank := phrasesFor(code)
+	h := fnv.New32a()
+	h.Write([]byte(content + "\x00" + strconv.Itoa(turn)))
+	t := &tone{bank: bank, seed: h.Sum32(), vars: map[string]string{}}
+
+	if ziggy != nil {
+		stage := z.GetStageForAge(now.Sub(ziggy.CreatedAt).Seconds())
+		t.variants = append(t.variants, string(ziggy.Personality), string(stage))
+		switch {
+		case ziggy.Bond < bondDistant:
+			t.variants = append(t.variants, "distant")
+		case ziggy.Bond >= bondClose:
+			t.variants = append(t.variants, "close")
+		}
+		t.vars["mood"] = moodName(code, ziggy.GetMood())
+		t.vars["stage"] = string(stage)
+		t.vars["fullness"] = fmt.Sprintf("%.0f", ziggy.Fullness)
+		t.vars["happiness"] = fmt.Sprintf("%.0f", ziggy.Happiness)
+		t.vars["bond"] = fmt.Sprintf("%.0f", ziggy.Bond)
+		t.vars["hp"] = fmt.Sprintf("%.0f", ziggy.HP)
+	}
+	t.vars["fact"] = bank.Facts[t.seed%uint32(len(bank.Facts))]
+	return t
+}
+
+// say picks a line for the reply and fills in its placeholders. Lines
+// whose placeholders have no value are skipped.
+func (t *tone) say(reply string, vars ...string) string {
+	for i := 0; i+1 < len(vars); i += 2 {
+		t.vars[vars[i]] = vars[i+1]
+	}
+	known := func(line string) bool {
+		for _, field := range strings.Split(line, "{")[1:] {
+			name, _, _ := strings.Cut(field, "}")
+			if _, ok := t.vars[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	variants := t.bank.Phrases[reply]
+	var lines []string
+	for _, v := range t.variants {
+		lines = append(lines, variants[v]...)
+	}
+	lines = slices.DeleteFunc(lines, func(line string) bool { return !known(line) })
+	if len(lines) == 0 {
+		lines = slices.DeleteFunc(slices.Clone(variants["default"]), func(line string) bool { return !known(line) })
+	}
+	if len(lines) == 0 {
+		return "*wiggle*"
+	}
+
+	line := lines[t.seed%uint32(len(lines))]
+	for name, value := range t.vars {
+		line = strings.ReplaceAll(line, "{"+name+"}", value)
+	}
+	return line
+}
+
+// offlineReply answers without a model: it recognizes the message's
+// intent, runs mysteries and topics from the catalog, and words the reply
+// in the owner's language for Ziggy's personality, stage and bond.
+func offlineReply(state *State, ziggy *z.State, track, content string, guess *AnswerMatch, evidence []string, now time.Time) chatResponse {
+	t := newTone(state.Locale, ziggy, content, len(state.Messages), now)
+	intent := t.bank.Match(content, track)
+	m := state.ActiveMystery
+
+	switch {
+	case guess != nil && guess.Correct && m != nil:
+		return chatResponse{Response: t.say("solved", "solution", m.Solution)}
+
+	// Topics are explained from their summary, which starts the quiz
+	case m != nil && m.Track == TrackEducational:
+		reply := t.say("explain", "concept", m.Concept, "summary", strings.TrimSpace(m.Summary))
+		if len(evidence) > 0 {
+			if len(evidence) > 3 {
+				evidence = evidence[len(evidence)-3:]
+			}
+			reply += "\n\n" + t.say("evidence", "events", strings.Join(evidence, "\n"))
+		}
+		return chatResponse{Response: reply, Explained: true}
+
+	case m != nil && intent == intentHint:
+		if state.MysteryProgress >= len(m.Hints) {
+			return chatResponse{Response: t.say("noHints")}
+		}
+		hint := m.Hints[state.MysteryProgress]
+		return chatResponse{
+			Response:      t.say("hint", "hint", hint),
+			MysteryUpdate: &MysteryUpdate{HintGiven: hint, NewProgress: state.MysteryProgress + 1},
+		}
+
+	case m != nil && intent == intentMystery:
+		return chatResponse{Response: t.say("mysteryActive", "title", m.Title, "description", m.Description)}
+
+	// Anything else is a guess: a wrong one earns the next hint, and once
+	// they run out the answer is revealed
+	case m != nil && intent == "":
+		if state.MysteryProgress >= len(m.Hints) {
+			return chatResponse{Response: t.say("wrong"), MysteryUpdate: &MysteryUpdate{NewProgress: state.MysteryProgress}}
+		}
+		hint := m.Hints[state.MysteryProgress]
+		return chatResponse{
+			Response:      t.say("wrongHint", "hint", hint),
+			MysteryUpdate: &MysteryUpdate{HintGiven: hint, NewProgress: state.MysteryProgress + 1},
+		}
+
+	case intent == intentMystery:
+		next := Mysteries().Localize(GetRandomMystery(track, state.Completed()), state.Locale)
+		if next == nil {
+			return chatResponse{Response: t.say("noMysteries")}
+		}
+		if next.Track == TrackEducational {
+			return chatResponse{Response: t.say("lesson", "concept", next.Concept), StartMystery: next}
+		}
+		return chatResponse{Response: t.say("mystery", "title", next.Title, "description", next.Description), StartMystery: next}
+
+	case intent == intentHint:
+		return chatResponse{Response: t.say("noMystery")}
+
+	case ziggy != nil && ziggy.Sleeping:
+		return chatResponse{Response: idleLine(ziggy, t)}
+
+	case intent == intentFeed:
+		return chatResponse{Response: t.say(intent), Care: []string{ziggyworkflow.SignalFeed}}
+	case intent == intentPlay:
+		return chatResponse{Response: t.say(intent), Care: []string{ziggyworkflow.SignalPlay}}
+	case intent == intentPet:
+		return chatResponse{Response: t.say(intent), Care: []string{ziggyworkflow.SignalPet}}
+
+	case intent != "":
+		return chatResponse{Response: t.say(intent)}
+	}
+	return chatResponse{Response: idleLine(ziggy, t)}
+}
+
+// idleLine is small talk from Ziggy's message pools for its mood.
+func idleLine(ziggy *z.State, t *tone) string {
+	if ziggy == nil {
+		return t.say("smalltalk")
+	}
+	mood := string(ziggy.GetMood())
+	if line := ziggy.PoolSelector().Pick("idle" + strings.ToUpper(mood[:1]) + mood[1:]); line != "" {
+		return line
+	}
+	return t.say("smalltalk")
+}
@@ -0,0 +1,200 @@
+package chat
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+func TestPhraseBankMatch(t *testing.T) {
+	tests := []struct {
+		content, track, want string
+	}{
+		{"Hi Ziggy!", TrackFun, "greeting"},
+		{"how are you?", TrackFun, intentFeelings},
+		{"any hints?", TrackFun, intentHint},
+		{"What's a tardigrade?", TrackFun, intentFact},
+		{"wanna play?", TrackFun, intentPlay},
+		{"feed me", TrackEducational, ""},
+		{"this is it", TrackFun, ""},
+		{"the comet did it", TrackFun, ""},
+	}
+	for _, tt := range tests {
+		if got := Phrases().Match(tt.content, tt.track); got != tt.want {
+			t.Errorf("Match(%q, %s) = %q, want %q", tt.content, tt.track, got, tt.want)
+		}
+	}
+}
+
+func TestOfflineReplyTone(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	greetings := Phrases().Phrases["greeting"]
+
+	shy := z.NewState("UTC")
+	shy.Personality = z.PersonalityShy
+	shy.CreatedAt = now.Add(-time.Duration(z.AgeEggTooBaby+1) * time.Second)
+	shy.Bond = 10
+	want := slices.Concat(greetings["shy"], greetings["baby"], greetings["distant"])
+
+	// Different messages land on different lines, all in the shy, baby
+	// and distant variants
+	seen := map[string]bool{}
+	for _, content := range []string{"hi", "hello", "hey", "hiya", "howdy", "good morning"} {
+		state := NewState("test")
+		reply := offlineReply(&state, &shy, TrackFun, content, nil, nil, now).Response
+		if !slices.Contains(want, reply) {
+			t.Errorf("reply to %q = %q, not a shy, baby or distant greeting", content, reply)
+		}
+		seen[reply] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("replies never vary: %v", seen)
+	}
+
+	state := NewState("test")
+	if reply := offlineReply(&state, nil, TrackFun, "hi", nil, nil, now).Response; !slices.Contains(greetings["default"], reply) {
+		t.Errorf("reply without Ziggy's state = %q", reply)
+	}
+
+	shy.Fullness = 42
+	if reply := offlineReply(&state, &shy, TrackFun, "how are you?", nil, nil, now).Response; !strings.Contains(reply, "Fullness 42") {
+		t.Errorf("feelings = %q", reply)
+	}
+}
+
+func TestProcessChatMessagePlaysMysteryOffline(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ziggy := z.NewState("UTC")
+	ziggy.Personality = z.PersonalityCheerful
+	ziggy.CreatedAt = now.Add(-time.Hour)
+	send := func(state State, content string) ProcessMessageOutput {
+		t.Helper()
+		return runProcessMessage(t, nil, ProcessMessageInput{State: state, Content: content, Track: TrackFun, ZiggyState: &ziggy, Now: now})
+	}
+
+	out := send(NewState("test"), "feed me")
+	if !slices.Equal(out.Care, []string{ziggyworkflow.SignalFeed}) {
+		t.Errorf("care = %v", out.Care)
+	}
+
+	state := send(NewState("test"), "tell me a mystery").State
+	m := state.ActiveMystery
+	if m == nil || !strings.Contains(lastMessage(state), m.Description) {
+		t.Fatalf("no mystery started: %q", lastMessage(state))
+	}
+
+	// Each wrong guess earns the next hint, then the answer is revealed
+	for i, hint := range m.Hints {
+		state = send(state, "no idea").State
+		if got := lastMessage(state); !strings.HasSuffix(got, hint) || state.MysteryProgress != i+1 {
+			t.Fatalf("guess %d: reply %q, progress %d", i+1, got, state.MysteryProgress)
+		}
+	}
+	state = send(state, "still no idea").State
+	if state.ActiveMystery != nil || !strings.Contains(lastMessage(state), "The answer was: "+m.Solution) {
+		t.Errorf("not revealed: active %v, reply %q", state.ActiveMystery, lastMessage(state))
+	}
+	if r := state.Records[m.ID]; !r.Revealed || r.HintsUsed != len(m.Hints) {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func TestProcessChatMessageTeachesOffline(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	replay := GetMystery("replay", TrackEducational)
+	state := NewState("test")
+	state.StartMystery(replay, now)
+
+	state = processMessage(t, nil, ProcessMessageInput{State: state, Content: "what is replay?", Track: TrackEducational, Now: now})
+	reply := lastMessage(state)
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(replay.Summary), "\n")
+	if !strings.Contains(reply, firstLine) || state.Quiz == nil || !strings.Contains(reply, "Quiz time!") {
+		t.Errorf("reply = %q, quiz %v", reply, state.Quiz)
+	}
+}
+
+func TestProcessChatMessageSpeaksJapaneseOffline(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ziggy := z.NewState("UTC")
+	ziggy.Locale = "ja"
+	ziggy.Personality = z.PersonalityCheerful
+	ziggy.CreatedAt = now.Add(-time.Hour)
+	send := func(state State, content string) State {
+		t.Helper()
+		return processMessage(t, nil, ProcessMessageInput{State: state, Content: content, Track: TrackFun, ZiggyState: &ziggy, Now: now})
+	}
+
+	// A fun mystery played to the reveal
+	state := send(NewState("test"), "/mystery missing-snack")
+	m := state.ActiveMystery
+	if m == nil || m.Title != "消えたおやつ" {
+		t.Fatalf("mystery = %+v", m)
+	}
+	for range m.Hints {
+		state = send(state, "うーん、わからない")
+	}
+	state = send(state, "まだわからない")
+	if state.ActiveMystery != nil || !strings.Contains(lastMessage(state), m.Solution) {
+		t.Fatalf("not revealed: %q", lastMessage(state))
+	}
+
+	// A topic explained, then quizzed to the end
+	state = send(state, "/mystery timers")
+	if state.ActiveMystery == nil || state.ActiveMystery.Concept != "タイマー" {
+		t.Fatalf("topic = %+v", state.ActiveMystery)
+	}
+	state = send(state, "教えて")
+	if state.Quiz == nil {
+		t.Fatalf("no quiz after the explanation: %q", lastMessage(state))
+	}
+	for state.Quiz != nil {
+		state = send(state, "a")
+	}
+
+	// Ziggy's words are Japanese; only the product name and acronyms stay
+	// in English
+	english := regexp.MustCompile(`[A-Za-z]{3,}`)
+	for _, msg := range state.Messages {
+		if msg.Role != "ziggy" {
+			continue
+		}
+		for _, word := range english.FindAllString(msg.Content, -1) {
+			if word != "Temporal" && word != strings.ToUpper(word) {
+				t.Errorf("English %q in %q", word, msg.Content)
+			}
+		}
+	}
+}
+
+func TestLoadPhrases(t *testing.T) {
+	extra := []byte(`
+intents:
+  - name: weather
+    patterns: [rain, sunny]
+  - name: greeting
+    patterns: [ahoy]
+phrases:
+  weather:
+    default: ["*looks up*\nI like the damp."]
+`)
+	bank, err := LoadPhrases(defaultPhrases, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bank.Match("is it sunny?", TrackFun); got != "weather" {
+		t.Errorf("Match(sunny) = %q", got)
+	}
+	if got := bank.Match("ahoy!", TrackFun); got != "greeting" {
+		t.Errorf("Match(ahoy) = %q", got)
+	}
+
+	missing := []byte("intents:\n  - name: weather\n    patterns: [rain]\n")
+	if _, err := LoadPhrases(defaultPhrases, missing); err == nil || !strings.Contains(err.Error(), `"weather"`) {
+		t.Errorf("LoadPhrases() without weather phrases error = %v", err)
+	}
+}
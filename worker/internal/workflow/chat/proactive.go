@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"ziggy/internal/ai"
+	"ziggy/internal/locale"
+	z "ziggy/internal/ziggy"
+)
+
+// Kinds of message Ziggy sends without being asked.
+const (
+	ProactiveGreeting  = "greeting"
+	ProactiveComplaint = "complaint"
+	ProactiveMorning   = "morning"
+	ProactiveEvening   = "evening"
+)
+
+const (
+	ComposeProactiveActivity = "ComposeProactiveMessage"
+
+	// ComplaintDelay is how long a need goes unanswered before Ziggy
+	// complains about it. Each further complaint waits twice as long, up to
+	// MaxComplaintDelay.
+	ComplaintDelay    = 30 * time.Minute
+	MaxComplaintDelay = 8 * time.Hour
+
+	// Check-ins go out at these hours of the owner's day, unless the owner
+	// wrote within CheckInQuiet.
+	MorningHour  = 9
+	EveningHour  = 20
+	CheckInQuiet = 2 * time.Hour
+)
+
+// ProactiveLimits cap how often Ziggy speaks first. MaxPerDay counts the
+// last 24 hours, and 0 turns proactive messages off.
+type ProactiveLimits struct {
+	MaxPerDay     int `json:"maxPerDay"`
+	MinGapMinutes int `json:"minGapMinutes"`
+}
+
+// DefaultProactiveLimits apply until the owner sets their own.
+var DefaultProactiveLimits = ProactiveLimits{MaxPerDay: 6, MinGapMinutes: 60}
+
+// Proactive tracks the messages Ziggy starts on its own.
+type Proactive struct {
+	// Limits are the owner's; nil means DefaultProactiveLimits.
+	Limits *ProactiveLimits `json:"limits,omitempty"`
+	// Timezone is the owner's, from pet updates; check-ins follow it.
+	Timezone string `json:"timezone,omitempty"`
+	// Sent holds when proactive messages went out in the last 24 hours.
+	Sent []time.Time `json:"sent,omitempty"`
+	// Need is the unmet need Ziggy complains about, and Complaints how
+	// many times it has so far.
+	Need       z.NeedType `json:"need,omitempty"`
+	Complaints int        `json:"complaints,omitempty"`
+}
+
+// CurrentLimits returns the owner's limits, or the defaults.
+func (p *Proactive) CurrentLimits() ProactiveLimits {
+	if p.Limits == nil {
+		return DefaultProactiveLimits
+	}
+	return *p.Limits
+}
+
+// Allowed reports whether the limits leave room for a message at now.
+func (p *Proactive) Allowed(now time.Time) bool {
+	limits := p.CurrentLimits()
+	recent := p.recent(now)
+	if len(recent) >= limits.MaxPerDay {
+		return false
+	}
+	gap := time.Duration(limits.MinGapMinutes) * time.Minute
+	return len(recent) == 0 || now.Sub(recent[len(recent)-1]) >= gap
+}
+
+// Record notes a message sent at now and forgets those older than a day.
+func (p *Proactive) Record(now time.Time) {
+	p.Sent = append(p.recent(now), now)
+}
+
+// SentToday counts the proactive messages sent in the 24 hours before now.
+func (p *Proactive) SentToday(now time.Time) int {
+	return len(p.recent(now))
+}
+
+func (p *Proactive) recent(now time.Time) []time.Time {
+	return slices.DeleteFunc(slices.Clone(p.Sent), func(t time.Time) bool {
+		return now.Sub(t) >= 24*time.Hour
+	})
+}
+
+// complaintDelay is how long to wait before the next complaint.
+func (p *Proactive) complaintDelay() time.Duration {
+	delay := ComplaintDelay
+	for i := 0; i < p.Complaints && delay < MaxComplaintDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxComplaintDelay)
+}
+
+// NextCheckIn returns the first morning or evening check-in after now in
+// the timezone, and its kind. Unknown timezones are treated as UTC.
+func NextCheckIn(now time.Time, timezone string) (time.Time, string) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	for day := 0; ; day++ {
+		for _, c := range []struct {
+			hour int
+			kind string
+		}{{MorningHour, ProactiveMorning}, {EveningHour, ProactiveEvening}} {
+			at := time.Date(local.Year(), local.Month(), local.Day()+day, c.hour, 0, 0, 0, loc)
+			if at.After(now) {
+				return at, c.kind
+			}
+		}
+	}
+}
+
+// lastOwnerMessage returns when the owner last wrote, or the zero time.
+func (s *State) lastOwnerMessage() time.Time {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			return s.Messages[i].Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+type ComposeProactiveInput struct {
+	// ZiggyID is the account AI usage is billed to.
+	ZiggyID    string   `json:"ziggyId,omitempty"`
+	Kind       string   `json:"kind"`
+	State      State    `json:"state"`
+	ZiggyState *z.State `json:"ziggyState,omitempty"`
+	// Away is how long the owner was gone, for greetings.
+	Away time.Duration `json:"away,omitempty"`
+	Now  time.Time     `json:"now"`
+}
+
+type ComposeProactiveOutput struct {
+	Content string `json:"content"`
+}
+
+// ComposeProactiveMessage words a message Ziggy sends without being asked.
+// The model writes it when a provider is available. Otherwise complaints
+// come from Ziggy's message pools, and everything else from the phrase
+// bank.
+func (a *Activities) ComposeProactiveMessage(ctx context.Context, input ComposeProactiveInput) (*ComposeProactiveOutput, error) {
+	ctx = ai.WithAccount(ctx, input.ZiggyID)
+	state := input.State
+
+	if a.provider != nil && a.provider.Available() {
+		aiInput := ai.ChatInput{
+			Messages: convertMessages(state.Messages),
+			Track:    TrackFun,
+			Memories: memoryFacts(state.Memories),
+			Language: locale.Language(state.Locale),
+			Opener:   openerReason(input.Kind, state.Proactive.Need, input.Away),
+		}
+		describeZiggy(&aiInput, input.ZiggyState, input.Now)
+
+		result, err := a.provider.GenerateChat(ctx, aiInput)
+		if err == nil && strings.TrimSpace(result.Response) != "" {
+			return &ComposeProactiveOutput{Content: result.Response}, nil
+		}
+		log.Printf("[ChatActivity] AI error composing %s message: %v, using phrases", input.Kind, err)
+	}
+
+	return &ComposeProactiveOutput{Content: offlineProactive(&state, input.ZiggyState, input.Kind, input.Now)}, nil
+}
+
+// openerReason tells the model why Ziggy is speaking first.
+func openerReason(kind string, need z.NeedType, away time.Duration) string {
+	switch kind {
+	case ProactiveGreeting:
+		return fmt.Sprintf("the owner just came back after %d hours away", int(away.Hours()))
+	case ProactiveComplaint:
+		wants := map[z.NeedType]string{
+			z.NeedFood:      "food",
+			z.NeedPlay:      "to play",
+			z.NeedAffection: "some affection",
+			z.NeedCritical:  "urgent care",
+		}[need]
+		if wants == "" {
+			wants = "attention"
+		}
+		return fmt.Sprintf("you have needed %s for a while and nobody came", wants)
+	case ProactiveMorning:
+		return "it is morning where the owner lives and you want to check in"
+	case ProactiveEvening:
+		return "it is evening where the owner lives and you want to check in"
+	}
+	return "you want to chat"
+}
+
+// offlineProactive picks a proactive message without a model.
+func offlineProactive(state *State, ziggy *z.State, kind string, now time.Time) string {
+	if kind == ProactiveComplaint && ziggy != nil && state.Proactive.Need != z.NeedNone {
+		if line := ziggy.PoolSelector().Pick(string(state.Proactive.Need)); line != "" {
+			return line
+		}
+	}
+	reply := map[string]string{
+		ProactiveGreeting:  "welcomeBack",
+		ProactiveComplaint: "complaint",
+		ProactiveMorning:   "morning",
+		ProactiveEvening:   "evening",
+	}[kind]
+	return newTone(state.Locale, ziggy, kind, len(state.Messages), now).say(reply)
+}
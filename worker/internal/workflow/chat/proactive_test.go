@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"ziggy/internal/ai"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+func TestProactiveAllowed(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	var p Proactive
+	if !p.Allowed(now) {
+		t.Fatal("first message not allowed")
+	}
+
+	p.Record(now.Add(-30 * time.Minute))
+	if p.Allowed(now) {
+		t.Error("allowed within the minimum gap")
+	}
+	if !p.Allowed(now.Add(30 * time.Minute)) {
+		t.Error("not allowed after the minimum gap")
+	}
+
+	p.Limits = &ProactiveLimits{MaxPerDay: 2}
+	p.Record(now.Add(-25 * time.Hour))
+	p.Record(now.Add(-10 * time.Minute))
+	if p.Allowed(now) || p.SentToday(now) != 2 {
+		t.Errorf("allowed past the daily limit, %d sent today", p.SentToday(now))
+	}
+	if len(p.Sent) != 2 {
+		t.Errorf("sent = %v, want messages older than a day forgotten", p.Sent)
+	}
+
+	p.Limits = &ProactiveLimits{}
+	if (&Proactive{Limits: p.Limits}).Allowed(now) {
+		t.Error("allowed with proactive messages turned off")
+	}
+}
+
+func TestComplaintDelayBacksOff(t *testing.T) {
+	want := []time.Duration{30 * time.Minute, time.Hour, 2 * time.Hour, 4 * time.Hour, 8 * time.Hour, 8 * time.Hour}
+	for i, w := range want {
+		p := Proactive{Complaints: i}
+		if got := p.complaintDelay(); got != w {
+			t.Errorf("complaint %d: delay %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestNextCheckIn(t *testing.T) {
+	tests := []struct {
+		now      string
+		timezone string
+		want     string
+		kind     string
+	}{
+		{"2026-10-19T08:00:00Z", "UTC", "2026-10-19T09:00:00Z", ProactiveMorning},
+		{"2026-10-19T09:00:00Z", "UTC", "2026-10-19T20:00:00Z", ProactiveEvening},
+		{"2026-10-19T21:00:00Z", "", "2026-10-20T09:00:00Z", ProactiveMorning},
+		// 08:00 UTC is 10:00 in Berlin, so the evening comes next
+		{"2026-10-19T08:00:00Z", "Europe/Berlin", "2026-10-19T18:00:00Z", ProactiveEvening},
+		{"2026-10-19T08:00:00Z", "Not/AZone", "2026-10-19T09:00:00Z", ProactiveMorning},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		at, kind := NextCheckIn(now, tt.timezone)
+		if got := at.UTC().Format(time.RFC3339); got != tt.want || kind != tt.kind {
+			t.Errorf("NextCheckIn(%s, %q) = %s %s, want %s %s", tt.now, tt.timezone, got, kind, tt.want, tt.kind)
+		}
+	}
+}
+
+func TestComposeProactiveMessage(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ziggy := z.NewState("UTC")
+	ziggy.Personality = z.PersonalitySassy
+	state := NewState("test")
+	state.Proactive.Need = z.NeedFood
+
+	// Offline, complaints come from the pools for the need
+	offline := NewActivities(&ai.FakeProvider{Unavailable: true})
+	out, err := offline.ComposeProactiveMessage(context.Background(), ComposeProactiveInput{
+		Kind: ProactiveComplaint, State: state, ZiggyState: &ziggy, Now: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, generic := z.FallbackPools("", ziggy.Personality)
+	fallback := z.GetFallbackPool(ziggy.Personality)
+	if pool := strings.Join(append(fallback.NeedsFood, generic.NeedsFood...), "|"); !strings.Contains(pool, out.Content) {
+		t.Errorf("offline complaint = %q, want a needsFood line", out.Content)
+	}
+
+	out, _ = offline.ComposeProactiveMessage(context.Background(), ComposeProactiveInput{
+		Kind: ProactiveMorning, State: state, ZiggyState: &ziggy, Now: now,
+	})
+	if !strings.Contains(strings.ToLower(out.Content), "morning") {
+		t.Errorf("offline check-in = %q", out.Content)
+	}
+
+	// With a provider, the model is told why Ziggy speaks first
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{{Chat: &ai.ChatResponse{Response: "You're back!"}}}}
+	out, err = NewActivities(fake).ComposeProactiveMessage(context.Background(), ComposeProactiveInput{
+		Kind: ProactiveGreeting, State: state, ZiggyState: &ziggy, Away: 5 * time.Hour, Now: now,
+	})
+	if err != nil || out.Content != "You're back!" {
+		t.Fatalf("greeting = %+v, %v", out, err)
+	}
+	calls := fake.ChatCalls()
+	if len(calls) != 1 || calls[0].Opener != "the owner just came back after 5 hours away" || calls[0].Personality != "sassy" || calls[0].Track != TrackFun {
+		t.Errorf("chat input = %+v", calls)
+	}
+}
+
+func TestChatWorkflowSpeaksFirst(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+
+	activities := NewActivities(&ai.FakeProvider{Unavailable: true})
+	env.RegisterActivityWithOptions(activities.ComposeProactiveMessage, activity.RegisterOptions{Name: ComposeProactiveActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	ziggy := z.NewState("UTC")
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(&ziggy, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Two messages a day with no gap: the greeting and the first
+	// complaint go out, and the 09:00 check-in is held back
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSetProactiveLimits, ProactiveLimits{MaxPerDay: 2})
+		env.SignalWorkflow(SignalPetUpdate, ziggyworkflow.PetUpdateSignal{
+			Kind:     ziggyworkflow.UpdateReturned,
+			Mood:     z.MoodHungry,
+			Need:     z.NeedFood,
+			Timezone: "UTC",
+			Away:     6 * time.Hour,
+		})
+	}, time.Second)
+
+	var state State
+	env.RegisterDelayedCallback(func() {
+		result, err := env.QueryWorkflow(QueryChatState)
+		if err != nil {
+			t.Errorf("query: %v", err)
+		} else if err := result.Get(&state); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		env.CancelWorkflow()
+	}, 70*time.Minute)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: "fun"})
+
+	var kinds []string
+	for _, m := range state.Messages {
+		if m.Role != "ziggy" || m.Content == "" {
+			t.Errorf("message = %+v", m)
+		}
+		kinds = append(kinds, m.Proactive)
+	}
+	if strings.Join(kinds, ",") != "greeting,complaint" {
+		t.Errorf("proactive messages = %v, want [greeting complaint]", kinds)
+	}
+	if state.Proactive.Complaints != 1 || state.Proactive.Need != z.NeedFood || state.Proactive.SentToday(state.Messages[1].Timestamp) != 2 {
+		t.Errorf("proactive state = %+v", state.Proactive)
+	}
+}
@@ -0,0 +1,288 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scoring: a solve is worth its difficulty's base points, less a share for
+// every hint used and every wrong guess, but never less than minScoreShare
+// of the base. Solving the daily mystery on its day doubles the score.
+const (
+	hintPenalty   = 0.25
+	guessPenalty  = 0.1
+	minScoreShare = 0.1
+	dailyBonus    = 2
+)
+
+var basePoints = map[string]int{
+	DifficultyEasy:   100,
+	DifficultyMedium: 200,
+	DifficultyHard:   300,
+}
+
+// MysteryRecord is the owner's history with one mystery.
+type MysteryRecord struct {
+	ID         string `json:"id"`
+	Track      string `json:"track"`
+	Difficulty string `json:"difficulty,omitempty"`
+	// Attempts counts guesses at a fun mystery; asking for a hint isn't one.
+	Attempts  int       `json:"attempts"`
+	HintsUsed int       `json:"hintsUsed"`
+	StartedAt time.Time `json:"startedAt"`
+	// Revealed is set once Ziggy has given the answer away; solving it
+	// after that scores nothing.
+	Revealed bool       `json:"revealed,omitempty"`
+	SolvedAt *time.Time `json:"solvedAt,omitempty"`
+	// SecondsTaken is the time from the first start to the solve.
+	SecondsTaken int64 `json:"secondsTaken,omitempty"`
+	Score        int   `json:"score,omitempty"`
+	// Daily is set when the mystery was solved on the day it was the daily
+	// mystery.
+	Daily bool `json:"daily,omitempty"`
+	// ExplainedAt is when Ziggy first explained an educational topic.
+	ExplainedAt *time.Time `json:"explainedAt,omitempty"`
+}
+
+// DailyMystery is the fun mystery featured for one UTC day.
+type DailyMystery struct {
+	MysteryID string `json:"mysteryId"`
+	Date      string `json:"date"`
+}
+
+// MysteryStats sums up the owner's mystery records.
+type MysteryStats struct {
+	Solved    int `json:"solved"`
+	Attempted int `json:"attempted"`
+	// Total is how many fun mysteries the catalog holds.
+	Total     int   `json:"total"`
+	Explained int   `json:"explained"`
+	Score     int   `json:"score"`
+	Attempts  int   `json:"attempts"`
+	HintsUsed int   `json:"hintsUsed"`
+	AvgSecs   int64 `json:"averageSeconds,omitempty"`
+	// SolvedByDifficulty counts solves per difficulty.
+	SolvedByDifficulty map[string]int  `json:"solvedByDifficulty"`
+	Daily              *DailyMystery   `json:"daily,omitempty"`
+	Records            []MysteryRecord `json:"records"`
+}
+
+// MysteryEntry is a catalog mystery annotated with the owner's progress.
+type MysteryEntry struct {
+	Mystery
+	// Status is one of the MysteryStatus* values.
+	Status string         `json:"status"`
+	Daily  bool           `json:"daily,omitempty"`
+	Record *MysteryRecord `json:"record,omitempty"`
+}
+
+// Statuses of a MysteryEntry.
+const (
+	MysteryStatusAvailable = "available"
+	MysteryStatusActive    = "active"
+	MysteryStatusSolved    = "solved"
+	MysteryStatusLocked    = "locked"
+)
+
+// StartMystery makes m the active mystery and opens its record, keeping
+// the attempts and hints of earlier tries.
+func (s *State) StartMystery(m *Mystery, now time.Time) {
+	s.ActiveMystery = m
+	s.MysteryProgress = 0
+	s.HintsGiven = []string{}
+
+	if s.Records == nil {
+		s.Records = map[string]MysteryRecord{}
+	}
+	if _, ok := s.Records[m.ID]; !ok {
+		s.Records[m.ID] = MysteryRecord{
+			ID:         m.ID,
+			Track:      normalizeTrack(m.Track),
+			Difficulty: m.Difficulty,
+			StartedAt:  now,
+		}
+	}
+}
+
+// record returns the active mystery's record, opening one for mysteries
+// started before records were kept.
+func (s *State) record(now time.Time) (MysteryRecord, bool) {
+	if s.ActiveMystery == nil {
+		return MysteryRecord{}, false
+	}
+	if r, ok := s.Records[s.ActiveMystery.ID]; ok {
+		return r, true
+	}
+	if s.Records == nil {
+		s.Records = map[string]MysteryRecord{}
+	}
+	return MysteryRecord{
+		ID:         s.ActiveMystery.ID,
+		Track:      normalizeTrack(s.ActiveMystery.Track),
+		Difficulty: s.ActiveMystery.Difficulty,
+		StartedAt:  now,
+	}, true
+}
+
+// noteGuess counts a guess at the active mystery.
+func (s *State) noteGuess(content string, now time.Time) {
+	if r, ok := s.record(now); ok && isGuess(content) {
+		r.Attempts++
+		s.Records[r.ID] = r
+	}
+}
+
+// noteHints records how many hints the active mystery has used.
+func (s *State) noteHints(now time.Time) {
+	if r, ok := s.record(now); ok {
+		r.HintsUsed = max(r.HintsUsed, len(s.HintsGiven))
+		s.Records[r.ID] = r
+	}
+}
+
+// noteExplained records the first explanation of the active topic.
+func (s *State) noteExplained(now time.Time) {
+	if r, ok := s.record(now); ok && r.ExplainedAt == nil {
+		r.ExplainedAt = &now
+		s.Records[r.ID] = r
+	}
+}
+
+// noteRevealed records that Ziggy gave the active mystery's answer away.
+func (s *State) noteRevealed(now time.Time) {
+	if r, ok := s.record(now); ok {
+		r.Revealed = true
+		s.Records[r.ID] = r
+	}
+}
+
+// noteSolved closes the active mystery's record and scores it.
+func (s *State) noteSolved(now time.Time) {
+	r, ok := s.record(now)
+	if !ok || r.SolvedAt != nil {
+		return
+	}
+	r.HintsUsed = max(r.HintsUsed, len(s.HintsGiven))
+	r.Attempts = max(r.Attempts, 1)
+	r.SolvedAt = &now
+	r.SecondsTaken = int64(now.Sub(r.StartedAt).Seconds())
+	r.Daily = s.Daily != nil && s.Daily.MysteryID == r.ID && s.Daily.Date == DailyDate(now)
+	r.Score = Score(r)
+	s.Records[r.ID] = r
+}
+
+// Score is what solving a mystery with r's attempts and hints is worth.
+func Score(r MysteryRecord) int {
+	if r.Revealed {
+		return 0
+	}
+	base, ok := basePoints[r.Difficulty]
+	if !ok {
+		base = basePoints[DifficultyEasy]
+	}
+	share := 1 - hintPenalty*float64(r.HintsUsed) - guessPenalty*float64(max(r.Attempts-1, 0))
+	score := int(float64(base) * max(share, minScoreShare))
+	if r.Daily {
+		score *= dailyBonus
+	}
+	return score
+}
+
+// isGuess reports whether a message is an answer rather than a request
+// for help.
+func isGuess(content string) bool {
+	lower := strings.ToLower(content)
+	return !strings.Contains(lower, "hint") && !strings.Contains(lower, "clue")
+}
+
+// Completed lists the mysteries that count toward prerequisites: solved
+// fun mysteries and explained educational topics.
+func (s *State) Completed() []string {
+	completed := slices.Clone(s.Solved)
+	for id, r := range s.Records {
+		if r.ExplainedAt != nil && !slices.Contains(completed, id) {
+			completed = append(completed, id)
+		}
+	}
+	slices.Sort(completed)
+	return completed
+}
+
+// Stats sums up the owner's records.
+func (s *State) Stats() MysteryStats {
+	stats := MysteryStats{
+		Total:              len(Mysteries().Track(TrackFun)),
+		SolvedByDifficulty: map[string]int{},
+		Daily:              s.Daily,
+		Records:            []MysteryRecord{},
+	}
+	var secs int64
+	for _, r := range s.Records {
+		stats.Records = append(stats.Records, r)
+		if r.Track == TrackEducational {
+			if r.ExplainedAt != nil {
+				stats.Explained++
+			}
+			continue
+		}
+		stats.Attempted++
+		stats.Attempts += r.Attempts
+		stats.HintsUsed += r.HintsUsed
+		if r.SolvedAt != nil {
+			stats.Solved++
+			stats.Score += r.Score
+			stats.SolvedByDifficulty[r.Difficulty]++
+			secs += r.SecondsTaken
+		}
+	}
+	if stats.Solved > 0 {
+		stats.AvgSecs = secs / int64(stats.Solved)
+	}
+	slices.SortFunc(stats.Records, func(a, b MysteryRecord) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return stats
+}
+
+// Entries lists the track's mysteries with the owner's progress on each.
+// Unless all is set, only mysteries that can be started are included.
+func (s *State) Entries(track string, all bool) []MysteryEntry {
+	completed := s.Completed()
+	available := GetAvailableMysteries(track, completed)
+	entries := []MysteryEntry{}
+	for _, m := range Mysteries().Track(normalizeTrack(track)) {
+		entry := MysteryEntry{Mystery: m, Status: MysteryStatusLocked}
+		switch {
+		case s.ActiveMystery != nil && s.ActiveMystery.ID == m.ID:
+			entry.Status = MysteryStatusActive
+		case slices.Contains(completed, m.ID):
+			entry.Status = MysteryStatusSolved
+		case slices.ContainsFunc(available, func(a Mystery) bool { return a.ID == m.ID }):
+			entry.Status = MysteryStatusAvailable
+		}
+		if !all && entry.Status != MysteryStatusAvailable {
+			continue
+		}
+		if r, ok := s.Records[m.ID]; ok {
+			entry.Record = &r
+		}
+		entry.Daily = s.Daily != nil && s.Daily.MysteryID == m.ID
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// DailyDate is the UTC day a daily mystery is featured on.
+func DailyDate(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// untilNextDay is how long until the next UTC midnight.
+func untilNextDay(now time.Time) time.Duration {
+	now = now.UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
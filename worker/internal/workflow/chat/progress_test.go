@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"ziggy/internal/ai"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		record MysteryRecord
+		want   int
+	}{
+		{MysteryRecord{Difficulty: DifficultyEasy, Attempts: 1}, 100},
+		{MysteryRecord{Difficulty: DifficultyMedium, Attempts: 2}, 180},
+		{MysteryRecord{Difficulty: DifficultyHard, Attempts: 1, HintsUsed: 2}, 150},
+		{MysteryRecord{Difficulty: DifficultyHard, Attempts: 1, HintsUsed: 2, Daily: true}, 300},
+		{MysteryRecord{Difficulty: DifficultyEasy, Attempts: 20, HintsUsed: 3}, 10},
+		{MysteryRecord{Difficulty: DifficultyEasy, Attempts: 1, Revealed: true}, 0},
+	}
+	for _, tt := range tests {
+		if got := Score(tt.record); got != tt.want {
+			t.Errorf("Score(%+v) = %d, want %d", tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestProcessChatMessageRecordsProgress(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state := NewState("test")
+	state.StartMystery(GetMystery("cosmic-radio", TrackFun), start)
+	state.Daily = &DailyMystery{MysteryID: "cosmic-radio", Date: "2026-10-19"}
+
+	for i, content := range []string{"aliens", "can I have a hint?", "a space probe"} {
+		state = processMessage(t, nil, ProcessMessageInput{
+			State: state, Content: content, Track: "fun", Now: start.Add(time.Duration(i+1) * time.Minute),
+		})
+	}
+
+	// Offline, the wrong guess and the request each earn a hint: 200 less
+	// two hints and one extra guess, doubled for the daily mystery
+	r := state.Records["cosmic-radio"]
+	if r.SolvedAt == nil || r.Attempts != 2 || r.HintsUsed != 2 || r.SecondsTaken != 180 || !r.Daily || r.Score != 160 {
+		t.Errorf("record = %+v", r)
+	}
+	stats := state.Stats()
+	if stats.Solved != 1 || stats.Total != 3 || stats.Score != 160 || stats.SolvedByDifficulty[DifficultyMedium] != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	entries := state.Entries(TrackFun, true)
+	status := map[string]string{}
+	for _, e := range entries {
+		status[e.ID] = e.Status
+	}
+	if status["missing-snack"] != MysteryStatusAvailable || status["cosmic-radio"] != MysteryStatusSolved || status["dream-maze"] != MysteryStatusLocked {
+		t.Errorf("statuses = %v", status)
+	}
+	if available := state.Entries(TrackFun, false); len(available) != 1 || available[0].ID != "missing-snack" {
+		t.Errorf("available = %+v", available)
+	}
+}
+
+func TestProcessChatMessageExplainsTopicsWithoutSolving(t *testing.T) {
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{{
+		Chat: &ai.ChatResponse{Response: "Replay re-runs your code...", MysteryUpdate: &ai.ChatMysteryUpdate{Solved: true}},
+	}}}
+	state := NewState("test")
+	state.StartMystery(GetMystery("replay", TrackEducational), time.Now())
+
+	state = processMessage(t, fake, ProcessMessageInput{State: state, Content: "What is replay?", Track: "educational", Now: time.Now()})
+
+	if state.ActiveMystery == nil || len(state.Solved) != 0 {
+		t.Errorf("topic was solved: active %v, solved %v", state.ActiveMystery, state.Solved)
+	}
+	if r := state.Records["replay"]; r.ExplainedAt == nil || r.SolvedAt != nil {
+		t.Errorf("record = %+v", r)
+	}
+	if completed := state.Completed(); len(completed) != 1 || completed[0] != "replay" {
+		t.Errorf("completed = %v", completed)
+	}
+}
+
+func TestGetRandomMysteryPrefersUnsolved(t *testing.T) {
+	seen := map[string]int{}
+	for range 200 {
+		seen[GetRandomMystery(TrackFun, nil).ID]++
+	}
+	if len(seen) != 2 || seen["missing-snack"] == 0 || seen["cosmic-radio"] == 0 {
+		t.Errorf("picked %v, want both available mysteries", seen)
+	}
+
+	for range 20 {
+		if m := GetRandomMystery(TrackFun, []string{"missing-snack", "cosmic-radio"}); m.ID != "dream-maze" {
+			t.Fatalf("picked %s with only dream-maze unsolved", m.ID)
+		}
+	}
+	if m := GetRandomMystery(TrackFun, []string{"missing-snack", "cosmic-radio", "dream-maze"}); m == nil {
+		t.Error("nothing picked with every mystery solved")
+	}
+}
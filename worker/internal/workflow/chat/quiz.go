@@ -0,0 +1,277 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReviewIntervals space out re-asking the questions of a concept the owner
+// got wrong. A wrong answer starts over from the first interval; a perfect
+// review moves on to the next, and one after the last masters the concept.
+var ReviewIntervals = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	3 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// QuizQuestion is multiple choice when it has choices, and free text
+// otherwise.
+type QuizQuestion struct {
+	Question string   `json:"question"`
+	Choices  []string `json:"choices,omitempty"`
+	// Answer is the correct choice, or the expected free-text answer.
+	Answer string `json:"answer"`
+	// Aliases are other free-text answers that count as correct.
+	Aliases     []string `json:"aliases,omitempty"`
+	Explanation string   `json:"explanation,omitempty"`
+}
+
+// QuizSession is a quiz in progress on one topic.
+type QuizSession struct {
+	MysteryID string         `json:"mysteryId"`
+	Concept   string         `json:"concept"`
+	Questions []QuizQuestion `json:"questions"`
+	// Indexes are the questions' positions in the topic's quiz.
+	Indexes []int `json:"indexes"`
+	// Results holds a verdict for each question answered so far.
+	Results []bool `json:"results"`
+	// Review is set when the quiz re-asks questions that were missed.
+	Review bool `json:"review,omitempty"`
+}
+
+// ConceptProgress is the owner's quiz history on an educational topic.
+type ConceptProgress struct {
+	MysteryID string `json:"mysteryId"`
+	Concept   string `json:"concept"`
+	Title     string `json:"title"`
+	Explained bool   `json:"explained"`
+	Quizzes   int    `json:"quizzes"`
+	Correct   int    `json:"correct"`
+	Wrong     int    `json:"wrong"`
+	// Box is how many reviews in a row were perfect; see ReviewIntervals.
+	Box      int  `json:"box"`
+	Mastered bool `json:"mastered"`
+	// Missed are the quiz questions to re-ask at the next review.
+	Missed     []int      `json:"missed,omitempty"`
+	LastQuizAt *time.Time `json:"lastQuizAt,omitempty"`
+	NextReview *time.Time `json:"nextReview,omitempty"`
+}
+
+// GradeQuizAnswer checks an answer to q. A multiple choice answer may be
+// the choice's letter, its number or its text; free text is matched like a
+// mystery guess.
+func GradeQuizAnswer(q QuizQuestion, answer string) bool {
+	if len(q.Choices) == 0 {
+		return matchText(append([]string{q.Answer}, q.Aliases...), answer).Correct
+	}
+	want := slices.Index(q.Choices, q.Answer)
+	return want >= 0 && chooseAnswer(q.Choices, answer) == want
+}
+
+// chooseAnswer returns the index of the choice an answer picks, or -1.
+func chooseAnswer(choices []string, answer string) int {
+	short := strings.ToLower(strings.Trim(strings.TrimSpace(answer), ".)!"))
+	if len(short) == 1 && short[0] >= 'a' && int(short[0]-'a') < len(choices) {
+		return int(short[0] - 'a')
+	}
+	if n, err := strconv.Atoi(short); err == nil && n >= 1 && n <= len(choices) {
+		return n - 1
+	}
+
+	// Otherwise the choice whose words the answer matches best, if only
+	// one matches that well
+	best, bestScore, tied := -1, 0.0, false
+	for i, choice := range choices {
+		match := matchText([]string{choice}, answer)
+		switch {
+		case !match.Correct:
+		case match.Score > bestScore:
+			best, bestScore, tied = i, match.Score, false
+		case match.Score == bestScore:
+			tied = true
+		}
+	}
+	if tied {
+		return -1
+	}
+	return best
+}
+
+// StartQuiz begins a quiz on m: the questions at indexes, or all of them
+// when indexes is empty. It returns the first question.
+func (s *State) StartQuiz(m *Mystery, indexes []int, review bool) string {
+	session := &QuizSession{MysteryID: m.ID, Concept: m.Concept, Review: review, Results: []bool{}}
+	for _, i := range indexes {
+		if i >= 0 && i < len(m.Quiz) {
+			session.Questions = append(session.Questions, m.Quiz[i])
+			session.Indexes = append(session.Indexes, i)
+		}
+	}
+	if len(session.Questions) == 0 {
+		session.Questions = slices.Clone(m.Quiz)
+		session.Indexes = nil
+		for i := range m.Quiz {
+			session.Indexes = append(session.Indexes, i)
+		}
+	}
+	s.Quiz = session
+
+	intro := text(s.Locale, textQuizStart)
+	if review {
+		intro = text(s.Locale, textReviewStart, "concept", m.Concept)
+	}
+	return intro + "\n\n" + session.question(s.Locale)
+}
+
+// question formats the next unanswered question in the locale.
+func (q *QuizSession) question(code string) string {
+	i := len(q.Results)
+	question := q.Questions[i]
+	formatted := text(code, textQuestion, "n", strconv.Itoa(i+1), "total", strconv.Itoa(len(q.Questions)), "question", question.Question)
+	for j, choice := range question.Choices {
+		formatted += fmt.Sprintf("\n%c) %s", 'a'+j, choice)
+	}
+	return formatted
+}
+
+// AnswerQuiz grades answer to the current question and returns Ziggy's
+// reply: the verdict, then the next question or the results.
+func (s *State) AnswerQuiz(answer string, now time.Time) string {
+	q := s.Quiz
+	question := q.Questions[len(q.Results)]
+	correct := GradeQuizAnswer(question, answer)
+	q.Results = append(q.Results, correct)
+
+	reply := text(s.Locale, textCorrect)
+	if !correct {
+		reply = text(s.Locale, textIncorrect, "answer", question.Answer)
+	}
+	if question.Explanation != "" {
+		reply += "\n" + question.Explanation
+	}
+	if len(q.Results) < len(q.Questions) {
+		return reply + "\n\n" + q.question(s.Locale)
+	}
+	return reply + "\n\n" + s.finishQuiz(now)
+}
+
+// finishQuiz records the quiz results and schedules the next review.
+func (s *State) finishQuiz(now time.Time) string {
+	q := s.Quiz
+	s.Quiz = nil
+
+	if s.Concepts == nil {
+		s.Concepts = map[string]ConceptProgress{}
+	}
+	c := s.Concepts[q.MysteryID]
+	c.MysteryID, c.Concept = q.MysteryID, q.Concept
+	c.Quizzes++
+	c.LastQuizAt = &now
+
+	var missed []int
+	for i, ok := range q.Results {
+		if ok {
+			c.Correct++
+		} else {
+			c.Wrong++
+			missed = append(missed, q.Indexes[i])
+		}
+	}
+	score := text(s.Locale, textScore, "right", strconv.Itoa(len(q.Results)-len(missed)), "total", strconv.Itoa(len(q.Results)))
+
+	var next string
+	switch {
+	case len(missed) > 0:
+		c.Box, c.Missed, c.Mastered = 0, missed, false
+		next = text(s.Locale, textReviewAgain, "concept", q.Concept, "interval", humanInterval(s.Locale, ReviewIntervals[0]))
+	case len(c.Missed) == 0 || c.Box+1 >= len(ReviewIntervals):
+		c.Box, c.Missed, c.Mastered = len(ReviewIntervals), nil, true
+		next = text(s.Locale, textMastered, "concept", q.Concept)
+	default:
+		c.Box++
+		next = text(s.Locale, textNextReview, "concept", q.Concept, "interval", humanInterval(s.Locale, ReviewIntervals[c.Box]))
+	}
+	c.NextReview = nil
+	if !c.Mastered {
+		due := now.Add(ReviewIntervals[c.Box])
+		c.NextReview = &due
+	}
+	s.Concepts[q.MysteryID] = c
+
+	// A perfect quiz solves the topic
+	if len(missed) == 0 && !slices.Contains(s.Solved, q.MysteryID) {
+		s.Solved = append(s.Solved, q.MysteryID)
+		if r, ok := s.Records[q.MysteryID]; ok {
+			r.SolvedAt = &now
+			r.SecondsTaken = int64(now.Sub(r.StartedAt).Seconds())
+			s.Records[q.MysteryID] = r
+		}
+	}
+	if s.ActiveMystery != nil && s.ActiveMystery.ID == q.MysteryID {
+		s.ActiveMystery = nil
+		s.MysteryProgress = 0
+		s.HintsGiven = nil
+	}
+	return score + "\n" + next
+}
+
+// NextReview returns the topic whose review is due first and when, or a
+// zero time when none is scheduled.
+func (s *State) NextReview() (id string, at time.Time) {
+	for mysteryID, c := range s.Concepts {
+		if c.NextReview == nil {
+			continue
+		}
+		if at.IsZero() || c.NextReview.Before(at) || (c.NextReview.Equal(at) && mysteryID < id) {
+			id, at = mysteryID, *c.NextReview
+		}
+	}
+	return id, at
+}
+
+// StartReview quizzes the owner again on the questions of m they missed.
+func (s *State) StartReview(m *Mystery) string {
+	return s.StartQuiz(m, s.Concepts[m.ID].Missed, true)
+}
+
+// dropReview unschedules a review whose topic is gone from the catalog.
+func (s *State) dropReview(id string) {
+	if c, ok := s.Concepts[id]; ok {
+		c.NextReview = nil
+		s.Concepts[id] = c
+	}
+}
+
+// ConceptReport lists every educational topic with the owner's progress.
+func (s *State) ConceptReport() []ConceptProgress {
+	report := []ConceptProgress{}
+	for _, m := range Mysteries().Track(TrackEducational) {
+		c, ok := s.Concepts[m.ID]
+		if !ok {
+			c = ConceptProgress{MysteryID: m.ID}
+		}
+		c.Concept, c.Title = m.Concept, m.Title
+		if r, ok := s.Records[m.ID]; ok && r.ExplainedAt != nil {
+			c.Explained = true
+		}
+		report = append(report, c)
+	}
+	return report
+}
+
+func humanInterval(code string, d time.Duration) string {
+	if d < 24*time.Hour {
+		if h := int(d.Hours()); h != 1 {
+			return text(code, textHours, "n", strconv.Itoa(h))
+		}
+		return text(code, textHour)
+	}
+	if days := int(d.Hours() / 24); days != 1 {
+		return text(code, textDays, "n", strconv.Itoa(days))
+	}
+	return text(code, textDay)
+}
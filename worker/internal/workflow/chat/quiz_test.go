@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"ziggy/internal/ai"
+)
+
+func TestGradeQuizAnswer(t *testing.T) {
+	choice := QuizQuestion{
+		Question: "What happens to completed activities when a workflow replays?",
+		Choices:  []string{"They run again", "Their recorded results are returned", "The workflow skips them and fails"},
+		Answer:   "Their recorded results are returned",
+	}
+	free := QuizQuestion{Question: "Workflow code has to be what?", Answer: "deterministic", Aliases: []string{"determinism"}}
+
+	tests := []struct {
+		q      QuizQuestion
+		answer string
+		want   bool
+	}{
+		{choice, "b", true},
+		{choice, "B)", true},
+		{choice, "2", true},
+		{choice, "their recorded results are returned", true},
+		{choice, "the recorded results are returned", true},
+		{choice, "a", false},
+		{choice, "d", false},
+		{choice, "they run again", false},
+		{choice, "they", false},
+		{free, "Deterministic!", true},
+		{free, "determinstic", true},
+		{free, "determinism", true},
+		{free, "fast", false},
+	}
+	for _, tt := range tests {
+		if got := GradeQuizAnswer(tt.q, tt.answer); got != tt.want {
+			t.Errorf("GradeQuizAnswer(%q, %q) = %v, want %v", tt.q.Question, tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestEveryQuizAcceptsItsAnswers(t *testing.T) {
+	for _, m := range Mysteries().Track(TrackEducational) {
+		for i, q := range m.Quiz {
+			for _, answer := range append([]string{q.Answer}, q.Aliases...) {
+				if !GradeQuizAnswer(q, answer) {
+					t.Errorf("%s question %d rejects %q", m.ID, i+1, answer)
+				}
+			}
+		}
+	}
+}
+
+func TestProcessChatMessageQuizzesAfterExplaining(t *testing.T) {
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{{Chat: &ai.ChatResponse{Response: "Replay re-runs your code..."}}}}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state := NewState("test")
+	state.StartMystery(GetMystery("replay", TrackEducational), start)
+
+	state = processMessage(t, fake, ProcessMessageInput{State: state, Content: "What is replay?", Track: "educational", Now: start})
+	if state.Quiz == nil || !strings.Contains(lastMessage(state), "Quiz time!\n\nQ1/3: ") {
+		t.Fatalf("no quiz after explaining: %q", lastMessage(state))
+	}
+
+	for _, answer := range []string{"b", "so queries work", "deterministic"} {
+		state = processMessage(t, fake, ProcessMessageInput{State: state, Content: answer, Track: "educational", Now: start})
+	}
+	if len(fake.ChatCalls()) != 1 {
+		t.Errorf("answers reached the model: %d calls", len(fake.ChatCalls()))
+	}
+	if got := lastMessage(state); !strings.HasSuffix(got, "You got 2/3 right!\nI'll ask you about Workflow replay again in 1 hour.") {
+		t.Errorf("results = %q", got)
+	}
+	if state.Quiz != nil || state.ActiveMystery != nil || len(state.Solved) != 0 {
+		t.Errorf("after quiz: quiz %v, active %v, solved %v", state.Quiz, state.ActiveMystery, state.Solved)
+	}
+	c := state.Concepts["replay"]
+	if c.Correct != 2 || c.Wrong != 1 || len(c.Missed) != 1 || c.Missed[0] != 1 || !c.NextReview.Equal(start.Add(time.Hour)) {
+		t.Errorf("concept = %+v", c)
+	}
+}
+
+func TestQuizReviewsSpaceOut(t *testing.T) {
+	replay := GetMystery("replay", TrackEducational)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state := NewState("test")
+	state.Concepts = map[string]ConceptProgress{"replay": {MysteryID: "replay", Missed: []int{1}}}
+
+	for i, want := range ReviewIntervals[1:] {
+		state.StartReview(replay)
+		if len(state.Quiz.Questions) != 1 {
+			t.Fatalf("review asks %d questions", len(state.Quiz.Questions))
+		}
+		state.AnswerQuiz("a", now)
+		if c := state.Concepts["replay"]; c.Box != i+1 || !c.NextReview.Equal(now.Add(want)) {
+			t.Fatalf("after review %d: %+v", i+1, c)
+		}
+	}
+	state.StartReview(replay)
+	if reply := state.AnswerQuiz("a", now); !strings.HasSuffix(reply, "You've mastered Workflow replay!") {
+		t.Errorf("reply = %q", reply)
+	}
+	if c := state.Concepts["replay"]; !c.Mastered || c.NextReview != nil || len(state.Solved) != 1 {
+		t.Errorf("after mastering: %+v, solved %v", c, state.Solved)
+	}
+	if id, _ := state.NextReview(); id != "" {
+		t.Errorf("review still scheduled for %s", id)
+	}
+}
+
+func TestChatWorkflowReviewsMissedConcepts(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	due := env.Now().Add(2 * time.Hour)
+	var before, during, after State
+	query := func(out *State) func() {
+		return func() {
+			result, err := env.QueryWorkflow(QueryChatState)
+			if err != nil {
+				t.Errorf("query: %v", err)
+				return
+			}
+			if err := result.Get(out); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+		}
+	}
+	env.RegisterDelayedCallback(query(&before), time.Hour)
+	env.RegisterDelayedCallback(query(&during), 3*time.Hour)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "b"})
+	}, 4*time.Hour)
+	env.RegisterDelayedCallback(func() {
+		query(&after)()
+		env.CancelWorkflow()
+	}, 5*time.Hour)
+
+	env.ExecuteWorkflow(Workflow, Input{
+		Owner: "test", ZiggyID: "ziggy-test", Track: "fun",
+		Concepts: map[string]ConceptProgress{"replay": {MysteryID: "replay", Concept: "Workflow replay", Missed: []int{0}, NextReview: &due}},
+	})
+
+	if before.Quiz != nil {
+		t.Error("review started early")
+	}
+	if during.Quiz == nil || !during.Quiz.Review || !strings.HasPrefix(lastMessage(during), "*pokes you* Review time!") {
+		t.Fatalf("review not started: quiz %+v", during.Quiz)
+	}
+	c := after.Concepts["replay"]
+	if after.Quiz != nil || c.Box != 1 || c.NextReview == nil || !c.NextReview.After(due) {
+		t.Errorf("after review: quiz %+v, concept %+v", after.Quiz, c)
+	}
+}
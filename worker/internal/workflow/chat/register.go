@@ -1,31 +1,56 @@
 package chat
 
 import (
-	"fmt"
+	"context"
+	"log"
+	"os"
 
 	"ziggy/internal/ai"
 	"ziggy/internal/registry"
+	"ziggy/internal/workflow/budget"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
 )
 
+// WorkflowID returns the ID of an owner's ChatWorkflow.
+func WorkflowID(owner string) string {
+	return ziggyworkflow.ChatWorkflowID(owner)
+}
+
 func Register() {
+	if dir := os.Getenv("MYSTERY_DIR"); dir != "" {
+		if err := WatchMysteries(context.Background(), dir); err != nil {
+			log.Printf("[Mysteries] Using built-in mysteries: %v", err)
+		}
+	}
+	if path := os.Getenv("PHRASE_FILE"); path != "" {
+		if err := LoadPhraseFile(path); err != nil {
+			log.Printf("[Phrases] Using built-in phrases: %v", err)
+		}
+	}
+
 	registry.RegisterWorkflow(registry.Definition{
-		Name:     "ChatWorkflow",
-		Workflow: Workflow,
-		IDPattern: func(owner string) string {
-			return fmt.Sprintf("ziggy-chat-%s", owner)
-		},
+		Name:      "ChatWorkflow",
+		Workflow:  Workflow,
+		IDPattern: WorkflowID,
 		NewInput: func(owner, ziggyID, _ string) any {
 			return Input{Owner: owner, ZiggyID: ziggyID, Track: "fun"}
 		},
 		AutoStart: true,
 	})
 
-	aiClient := ai.NewClient()
-	activities := NewActivities(aiClient)
+	activities := NewActivities(ai.NewModeratedProvider(budget.Metered(ai.NewProvider()), ai.ModeratorFromEnv()))
 	registry.RegisterActivity(registry.ActivityDef{
-		Name:     "ProcessChatMessage",
+		Name:     ProcessChatMessageActivity,
 		Activity: activities.ProcessChatMessage,
 	})
+	registry.RegisterActivity(registry.ActivityDef{
+		Name:     SummarizeMemoriesActivity,
+		Activity: activities.SummarizeMemories,
+	})
+	registry.RegisterActivity(registry.ActivityDef{
+		Name:     ComposeProactiveActivity,
+		Activity: activities.ComposeProactiveMessage,
+	})
 	registry.RegisterActivity(registry.ActivityDef{
 		Name:     "QueryZiggyState",
 		Activity: activities.QueryZiggyState,
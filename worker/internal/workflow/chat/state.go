@@ -2,14 +2,31 @@ package chat
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"ziggy/internal/ai"
 )
 
+// MaxViolations caps how many moderation findings the chat state keeps.
+const MaxViolations = 50
+
 type Message struct {
 	ID        string    `json:"id"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Mood      string    `json:"mood,omitempty"`
+	// Flagged messages were rejected by moderation and are kept out of
+	// prompts.
+	Flagged bool `json:"flagged,omitempty"`
+	// Command marks chat commands and Ziggy's replies to them, which are
+	// also kept out of prompts.
+	Command bool `json:"command,omitempty"`
+	// Proactive is the kind of message Ziggy sent without being asked.
+	Proactive string `json:"proactive,omitempty"`
 }
 
 type State struct {
@@ -22,8 +39,56 @@ type State struct {
 	CreatedAt       time.Time `json:"createdAt"`
 	LastMessageAt   time.Time `json:"lastMessageAt"`
 	IsTyping        bool      `json:"isTyping"`
+
+	// NextMessageID numbers messages, so replays give them the same IDs.
+	// Chats started before numbering keep it at 0 and their time-based
+	// IDs.
+	NextMessageID int `json:"nextMessageId,omitempty"`
+
+	// Track is the chat's current track. The workflow keeps it apart and
+	// fills it in for the chat_state query, so saves carry it.
+	Track string `json:"track,omitempty"`
+
+	// Locale is the owner's language, which mysteries are started in.
+	Locale string `json:"locale,omitempty"`
+
+	// Memories are durable facts distilled from older conversation.
+	// MemorizedThrough is the ID of the last message they cover.
+	Memories         []Memory `json:"memories,omitempty"`
+	MemorizedThrough string   `json:"memorizedThrough,omitempty"`
+
+	// Violations are the most recent moderation findings, oldest first.
+	Violations []Violation `json:"violations,omitempty"`
+
+	// Records hold the owner's progress on every mystery started, by ID.
+	Records map[string]MysteryRecord `json:"records,omitempty"`
+	Daily   *DailyMystery            `json:"daily,omitempty"`
+
+	// Quiz is the quiz in progress; while it runs, messages are answers.
+	// Concepts hold quiz results and review schedules by topic ID.
+	Quiz     *QuizSession               `json:"quiz,omitempty"`
+	Concepts map[string]ConceptProgress `json:"concepts,omitempty"`
+
+	// Proactive tracks the messages Ziggy starts on its own.
+	Proactive Proactive `json:"proactive"`
 }
 
+// Violation is a moderation finding on an owner's message or one of
+// Ziggy's generated replies.
+type Violation struct {
+	ai.Violation
+	At time.Time `json:"at"`
+}
+
+type Memory struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Fact      string    `json:"fact"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Mystery is a riddle on the fun track or a topic on the educational one.
+// They are authored as files; see LoadCatalog.
 type Mystery struct {
 	ID          string   `json:"id"`
 	Title       string   `json:"title"`
@@ -33,6 +98,16 @@ type Mystery struct {
 	Solution    string   `json:"solution"`
 	Concept     string   `json:"concept,omitempty"`
 	Summary     string   `json:"summary,omitempty"`
+
+	Difficulty string `json:"difficulty,omitempty"`
+	// Prerequisites are mysteries on the same track that must be solved
+	// before this one is offered.
+	Prerequisites []string `json:"prerequisites,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	// Aliases are other ways of stating the solution that count as correct.
+	Aliases []string `json:"aliases,omitempty"`
+	// Quiz checks an educational topic was understood.
+	Quiz []QuizQuestion `json:"quiz,omitempty"`
 }
 
 type MysteryStatus struct {
@@ -61,7 +136,7 @@ func NewState(owner string) State {
 
 func (s *State) AddMessage(role, content string, timestamp time.Time) Message {
 	msg := Message{
-		ID:        generateMessageID(len(s.Messages)),
+		ID:        s.newMessageID(),
 		Role:      role,
 		Content:   content,
 		Timestamp: timestamp,
@@ -84,6 +159,95 @@ func (s *State) GetMysteryStatus() MysteryStatus {
 	}
 }
 
-func generateMessageID(index int) string {
-	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), index)
+// Unmemorized returns the messages not yet covered by Memories.
+func (s *State) Unmemorized() []Message {
+	for i, m := range s.Messages {
+		if m.ID == s.MemorizedThrough {
+			return s.Messages[i+1:]
+		}
+	}
+	return s.Messages
+}
+
+// Forget removes the memory with the given ID, or every memory when id is
+// empty. It reports whether anything was removed.
+func (s *State) Forget(id string) bool {
+	before := len(s.Memories)
+	if id == "" {
+		s.Memories = nil
+	} else {
+		s.Memories = slices.DeleteFunc(s.Memories, func(m Memory) bool { return m.ID == id })
+	}
+	return len(s.Memories) < before
+}
+
+// ClearHistory deletes every message. Memories, solved mysteries and
+// progress are kept.
+func (s *State) ClearHistory() {
+	s.Messages = []Message{}
+	s.MemorizedThrough = ""
+}
+
+// DeleteMessage removes the message with the given ID and reports whether
+// it was there.
+func (s *State) DeleteMessage(id string) bool {
+	i := slices.IndexFunc(s.Messages, func(m Message) bool { return m.ID == id })
+	if i < 0 {
+		return false
+	}
+	// Keep the memorized mark on a message that's still there
+	if s.MemorizedThrough == id {
+		s.MemorizedThrough = ""
+		if i > 0 {
+			s.MemorizedThrough = s.Messages[i-1].ID
+		}
+	}
+	s.Messages = slices.Delete(s.Messages, i, i+1)
+	return true
+}
+
+// AbandonMystery drops the active mystery, and the quiz on it, without
+// solving it. Solved mysteries and records are kept. It reports whether a
+// mystery was active.
+func (s *State) AbandonMystery() bool {
+	if s.ActiveMystery == nil {
+		return false
+	}
+	if s.Quiz != nil && s.Quiz.MysteryID == s.ActiveMystery.ID {
+		s.Quiz = nil
+	}
+	s.ActiveMystery = nil
+	s.MysteryProgress = 0
+	s.HintsGiven = nil
+	return true
+}
+
+// RecordViolations appends violations, keeping the last MaxViolations.
+func (s *State) RecordViolations(violations []ai.Violation, now time.Time) {
+	for _, v := range violations {
+		s.Violations = append(s.Violations, Violation{Violation: v, At: now})
+	}
+	if len(s.Violations) > MaxViolations {
+		s.Violations = s.Violations[len(s.Violations)-MaxViolations:]
+	}
+}
+
+// startNumbering turns on message numbering, after any numbered messages
+// the state already holds.
+func (s *State) startNumbering() {
+	s.NextMessageID = 1
+	for _, m := range s.Messages {
+		if n, err := strconv.Atoi(strings.TrimPrefix(m.ID, "msg-")); err == nil && n >= s.NextMessageID {
+			s.NextMessageID = n + 1
+		}
+	}
+}
+
+func (s *State) newMessageID() string {
+	if s.NextMessageID == 0 {
+		return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), len(s.Messages))
+	}
+	id := fmt.Sprintf("msg-%d", s.NextMessageID)
+	s.NextMessageID++
+	return id
 }
@@ -0,0 +1,15 @@
+package chat
+
+// Change IDs for workflow.GetVersion; see the ziggy workflow package.
+const (
+	changeWebhookEvents  = "webhook-events"
+	changeMemories       = "chat-memories"
+	changeChatActions    = "chat-actions"
+	changeInteractions   = "chat-interactions"
+	changeMysteryCatalog = "mystery-catalog"
+	changeDailyMystery   = "daily-mystery"
+	changeQuizzes        = "concept-quizzes"
+	changeChatCommands   = "chat-commands"
+	changeProactive      = "proactive-messages"
+	changeMessageIDs     = "message-ids"
+)
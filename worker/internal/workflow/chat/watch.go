@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets a burst of editor writes settle before reloading.
+const reloadDelay = 250 * time.Millisecond
+
+// WatchMysteries loads the mysteries in dir on top of the built-in ones and
+// reloads them whenever a file there or in its locales/<code>/ directories
+// changes, until ctx is done. Edits that make the catalog invalid are logged
+// and the previous catalog kept.
+func WatchMysteries(ctx context.Context, dir string) error {
+	c, err := LoadMysteries(dir)
+	if err != nil {
+		return err
+	}
+	log.Printf("[Mysteries] Loaded %d mysteries from %s", c.Len(), dir)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watchMysteryDirs(watcher, dir, dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// A new locales/ or locales/<code>/ directory is watched too
+				if event.Has(fsnotify.Create) {
+					if err := watchMysteryDirs(watcher, dir, event.Name); err != nil {
+						log.Printf("[Mysteries] Watch error: %v", err)
+					}
+				}
+				reload = time.After(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("[Mysteries] Watch error: %v", err)
+			case <-reload:
+				reload = nil
+				c, err := LoadMysteries(dir)
+				if err != nil {
+					log.Printf("[Mysteries] Keeping current mysteries: %v", err)
+					continue
+				}
+				log.Printf("[Mysteries] Reloaded %d mysteries from %s", c.Len(), dir)
+			}
+		}
+	}()
+	return nil
+}
+
+// watchMysteryDirs watches path if it is dir, dir/locales or a locale
+// directory in it, and the mystery directories under it. Other paths are
+// left alone.
+func watchMysteryDirs(watcher *fsnotify.Watcher, dir, path string) error {
+	dir, path = filepath.Clean(dir), filepath.Clean(path)
+	locales := filepath.Join(dir, "locales")
+	if path != dir && path != locales && filepath.Dir(path) != locales {
+		return nil
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil
+	}
+	if err := watcher.Add(path); err != nil {
+		return err
+	}
+
+	switch path {
+	case dir:
+		return watchMysteryDirs(watcher, dir, locales)
+	case locales:
+		entries, err := os.ReadDir(locales)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := watchMysteryDirs(watcher, dir, filepath.Join(locales, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
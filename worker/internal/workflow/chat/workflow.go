@@ -6,15 +6,38 @@ import (
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
+	"ziggy/internal/locale"
+	"ziggy/internal/workflow/webhook"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
 	z "ziggy/internal/ziggy"
 )
 
 const (
 	SignalSendMessage  = "send_message"
 	SignalStartMystery = "start_mystery"
+	SignalForgetMemory = "forget_memory"
 
-	QueryChatHistory  = "chat_history"
+	SignalSetTrack       = "set_track"
+	SignalAbandonMystery = "abandon_mystery"
+	SignalClearHistory   = "clear_history"
+	SignalDeleteMessage  = "delete_message"
+
+	// SignalSetLocale takes a ziggy.SetLocaleSignal; mysteries started
+	// afterwards are in the new language.
+	SignalSetLocale = ziggyworkflow.SignalSetLocale
+
+	// SignalPetUpdate takes a ziggy.PetUpdateSignal from ZiggyWorkflow.
+	SignalPetUpdate = ziggyworkflow.SignalPetUpdate
+	// SignalSetProactiveLimits takes the owner's ProactiveLimits.
+	SignalSetProactiveLimits = "set_proactive_limits"
+
+	QueryChatHistory   = "chat_history"
 	QueryMysteryStatus = "mystery_status"
+	QueryChatState     = "chat_state"
+	QueryMemories      = "memories"
+	QueryViolations    = "violations"
+	QueryMysteryStats  = "mystery_stats"
+	QueryConcepts      = "concepts"
 
 	MaxMessages = 50
 )
@@ -23,23 +46,60 @@ type Input struct {
 	Owner   string `json:"owner"`
 	ZiggyID string `json:"ziggyId"`
 	Track   string `json:"track"`
+	Locale  string `json:"locale,omitempty"`
 
 	RecentMessages  []Message `json:"recentMessages,omitempty"`
 	ActiveMystery   *Mystery  `json:"activeMystery,omitempty"`
 	MysteryProgress int       `json:"mysteryProgress,omitempty"`
 	HintsGiven      []string  `json:"hintsGiven,omitempty"`
 	Solved          []string  `json:"solved,omitempty"`
+
+	Memories         []Memory `json:"memories,omitempty"`
+	MemorizedThrough string   `json:"memorizedThrough,omitempty"`
+
+	Violations []Violation `json:"violations,omitempty"`
+
+	Records map[string]MysteryRecord `json:"records,omitempty"`
+	Daily   *DailyMystery            `json:"daily,omitempty"`
+
+	Quiz     *QuizSession               `json:"quiz,omitempty"`
+	Concepts map[string]ConceptProgress `json:"concepts,omitempty"`
+
+	Proactive *Proactive `json:"proactive,omitempty"`
+
+	NextMessageID int `json:"nextMessageId,omitempty"`
+
+	// Snapshot is a whole state to resume, as exported in a save file. It
+	// takes precedence over the fields above.
+	Snapshot *State `json:"snapshot,omitempty"`
 }
 
 type SendMessageSignal struct {
 	Content string `json:"content"`
 }
 
+// StartMysterySignal starts a mystery, or a random one when MysteryID is
+// empty.
 type StartMysterySignal struct {
 	MysteryID string `json:"mysteryId"`
 	Track     string `json:"track"`
 }
 
+// SetTrackSignal switches the chat to TrackFun or TrackEducational.
+type SetTrackSignal struct {
+	Track string `json:"track"`
+}
+
+// DeleteMessageSignal deletes one chat message.
+type DeleteMessageSignal struct {
+	ID string `json:"id"`
+}
+
+// ForgetMemorySignal deletes one memory, or all of them when ID is empty.
+type ForgetMemorySignal struct {
+	ID string `json:"id,omitempty"`
+}
+
 func Workflow(ctx workflow.Context, input Input) error {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Chat workflow started", "owner", input.Owner, "track", input.Track)
@@ -50,6 +110,7 @@ func Workflow(ctx workflow.Context, input Input) error {
 	}
 
 	state := NewState(input.Owner)
+	state.Locale = input.Locale
 
 	if len(input.RecentMessages) > 0 {
 		state.Messages = input.RecentMessages
@@ -62,6 +123,33 @@ func Workflow(ctx workflow.Context, input Input) error {
 	if len(input.Solved) > 0 {
 		state.Solved = input.Solved
 	}
+	state.Memories = input.Memories
+	state.MemorizedThrough = input.MemorizedThrough
+	state.Violations = input.Violations
+	state.Records = input.Records
+	state.Daily = input.Daily
+	state.Quiz = input.Quiz
+	state.Concepts = input.Concepts
+	if input.Proactive != nil {
+		state.Proactive = *input.Proactive
+	}
+	state.NextMessageID = input.NextMessageID
+	if input.Snapshot != nil {
+		state = *input.Snapshot
+		state.Owner = input.Owner
+		state.IsTyping = false
+		if state.Messages == nil {
+			state.Messages = []Message{}
+		}
+		if state.Track != "" {
+			track = state.Track
+		}
+	}
+	// Time-based message IDs change on replay, so signals naming a message
+	// miss it. Chats started since number their messages instead.
+	if workflow.GetVersion(ctx, changeMessageIDs, workflow.DefaultVersion, 1) == 1 && state.NextMessageID == 0 {
+		state.startNumbering()
+	}
 
 	err := workflow.SetQueryHandler(ctx, QueryChatHistory, func() (HistoryResponse, error) {
 		mysteryStatus := state.GetMysteryStatus()
@@ -82,8 +170,59 @@ func Workflow(ctx workflow.Context, input Input) error {
 		return err
 	}
 
+	err = workflow.SetQueryHandler(ctx, QueryChatState, func() (State, error) {
+		snapshot := state
+		snapshot.Track = track
+		return snapshot, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryMemories, func() ([]Memory, error) {
+		if state.Memories == nil {
+			return []Memory{}, nil
+		}
+		return state.Memories, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryViolations, func() ([]Violation, error) {
+		if state.Violations == nil {
+			return []Violation{}, nil
+		}
+		return state.Violations, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryMysteryStats, func() (MysteryStats, error) {
+		return state.Stats(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryConcepts, func() ([]ConceptProgress, error) {
+		return state.ConceptReport(), nil
+	})
+	if err != nil {
+		return err
+	}
+
 	messageCh := workflow.GetSignalChannel(ctx, SignalSendMessage)
 	mysteryCh := workflow.GetSignalChannel(ctx, SignalStartMystery)
+	forgetCh := workflow.GetSignalChannel(ctx, SignalForgetMemory)
+	trackCh := workflow.GetSignalChannel(ctx, SignalSetTrack)
+	abandonCh := workflow.GetSignalChannel(ctx, SignalAbandonMystery)
+	clearCh := workflow.GetSignalChannel(ctx, SignalClearHistory)
+	deleteCh := workflow.GetSignalChannel(ctx, SignalDeleteMessage)
+	localeCh := workflow.GetSignalChannel(ctx, SignalSetLocale)
+	petUpdateCh := workflow.GetSignalChannel(ctx, SignalPetUpdate)
+	limitsCh := workflow.GetSignalChannel(ctx, SignalSetProactiveLimits)
 
 	activityOpts := workflow.ActivityOptions{
 		StartToCloseTimeout: 2 * time.Minute,
@@ -104,7 +243,195 @@ func Workflow(ctx workflow.Context, input Input) error {
 	}
 	queryCtx := workflow.WithActivityOptions(ctx, queryOpts)
 
-	for {
+	webhooksEnabled := workflow.GetVersion(ctx, changeWebhookEvents, workflow.DefaultVersion, 1) == 1
+	emitter := webhook.NewEmitter(input.ZiggyID)
+	memoriesEnabled := workflow.GetVersion(ctx, changeMemories, workflow.DefaultVersion, 1) == 1
+	actionsEnabled := workflow.GetVersion(ctx, changeChatActions, workflow.DefaultVersion, 1) == 1
+	interactionsEnabled := workflow.GetVersion(ctx, changeInteractions, workflow.DefaultVersion, 1) == 1
+	catalogRecorded := workflow.GetVersion(ctx, changeMysteryCatalog, workflow.DefaultVersion, 1) == 1
+	dailyEnabled := workflow.GetVersion(ctx, changeDailyMystery, workflow.DefaultVersion, 1) == 1
+
+	// rotateDaily features a random unsolved fun mystery for the UTC day and
+	// sets a timer for the next rotation
+	var dailyTimer workflow.Future
+	rotateDaily := func() {
+		now := workflow.Now(ctx)
+		if state.Daily == nil || state.Daily.Date != DailyDate(now) {
+			var picked *Mystery
+			pick := workflow.SideEffect(ctx, func(ctx workflow.Context) any {
+				return GetRandomMystery(TrackFun, state.Completed())
+			})
+			if err := pick.Get(&picked); err != nil {
+				logger.Info("Failed to pick the daily mystery", "error", err.Error())
+			}
+			if picked != nil {
+				state.Daily = &DailyMystery{MysteryID: picked.ID, Date: DailyDate(now)}
+				logger.Info("Daily mystery", "mysteryID", picked.ID, "date", state.Daily.Date)
+			}
+		}
+		dailyTimer = workflow.NewTimer(ctx, untilNextDay(now))
+	}
+	if dailyEnabled {
+		rotateDaily()
+	}
+	quizzesEnabled := workflow.GetVersion(ctx, changeQuizzes, workflow.DefaultVersion, 1) == 1
+	commandsEnabled := workflow.GetVersion(ctx, changeChatCommands, workflow.DefaultVersion, 1) == 1
+
+	// scheduleReview keeps a durable timer set for the earliest concept
+	// review; none runs while a quiz is in progress
+	var reviewTimer workflow.Future
+	var reviewAt time.Time
+	var cancelReview workflow.CancelFunc
+	scheduleReview := func() {
+		_, next := state.NextReview()
+		if state.Quiz != nil {
+			next = time.Time{}
+		}
+		if next.Equal(reviewAt) {
+			return
+		}
+		if cancelReview != nil {
+			cancelReview()
+		}
+		reviewTimer, reviewAt, cancelReview = nil, next, nil
+		if next.IsZero() {
+			return
+		}
+		timerCtx, cancel := workflow.WithCancel(ctx)
+		reviewTimer = workflow.NewTimer(timerCtx, max(next.Sub(workflow.Now(ctx)), time.Second))
+		cancelReview = cancel
+	}
+
+	// startReview quizzes the owner on the concept due for review
+	startReview := func() {
+		id, _ := state.NextReview()
+		if id == "" || state.Quiz != nil {
+			return
+		}
+		var mystery *Mystery
+		lookup := workflow.SideEffect(ctx, func(ctx workflow.Context) any {
+			return Mysteries().Localize(GetMystery(id, TrackEducational), state.Locale)
+		})
+		if err := lookup.Get(&mystery); err != nil {
+			logger.Info("Failed to look up review topic", "error", err.Error())
+		}
+		if mystery == nil || len(mystery.Quiz) == 0 {
+			state.dropReview(id)
+			return
+		}
+		logger.Info("Starting review", "mysteryID", id)
+		state.AddMessage("ziggy", state.StartReview(mystery), workflow.Now(ctx))
+	}
+	if quizzesEnabled {
+		scheduleReview()
+	}
+
+	proactiveEnabled := workflow.GetVersion(ctx, changeProactive, workflow.DefaultVersion, 1) == 1
+
+	// speak posts a message Ziggy starts on its own, when the owner's limits
+	// leave room for one. A running quiz is never interrupted.
+	speak := func(kind string, away time.Duration) {
+		now := workflow.Now(ctx)
+		if state.Quiz != nil || !state.Proactive.Allowed(now) {
+			logger.Info("Holding back proactive message", "kind", kind)
+			return
+		}
+
+		var ziggyState *z.State
+		err := workflow.ExecuteActivity(queryCtx, "QueryZiggyState", input.ZiggyID).Get(ctx, &ziggyState)
+		if err != nil {
+			logger.Info("Failed to query Ziggy state", "error", err.Error())
+		}
+
+		var output ComposeProactiveOutput
+		err = workflow.ExecuteActivity(actCtx, ComposeProactiveActivity, ComposeProactiveInput{
+			ZiggyID:    input.ZiggyID,
+			Kind:       kind,
+			State:      state,
+			ZiggyState: ziggyState,
+			Away:       away,
+			Now:        now,
+		}).Get(ctx, &output)
+		if err != nil {
+			logger.Info("ComposeProactiveMessage failed", "kind", kind, "error", err.Error())
+			return
+		}
+
+		msg := state.AddMessage("ziggy", output.Content, now)
+		state.Messages[len(state.Messages)-1].Proactive = kind
+		state.Proactive.Record(now)
+		logger.Info("Ziggy spoke first", "kind", kind)
+
+		if webhooksEnabled {
+			emitter.Emit(ctx, webhook.EventChatMessage, map[string]any{
+				"id":        msg.ID,
+				"role":      msg.Role,
+				"content":   msg.Content,
+				"proactive": kind,
+			})
+		}
+	}
+
+	// scheduleComplaint sets a timer for the next complaint about the unmet
+	// need, backing off with every complaint
+	var complaintTimer workflow.Future
+	var cancelComplaint workflow.CancelFunc
+	scheduleComplaint := func() {
+		if cancelComplaint != nil {
+			cancelComplaint()
+		}
+		complaintTimer, cancelComplaint = nil, nil
+		if state.Proactive.Need == z.NeedNone {
+			return
+		}
+		timerCtx, cancel := workflow.WithCancel(ctx)
+		complaintTimer = workflow.NewTimer(timerCtx, state.Proactive.complaintDelay())
+		cancelComplaint = cancel
+	}
+
+	// scheduleCheckIn sets a timer for the next morning or evening check-in
+	// in the owner's timezone
+	var checkInTimer workflow.Future
+	var checkInKind string
+	var cancelCheckIn workflow.CancelFunc
+	scheduleCheckIn := func() {
+		if cancelCheckIn != nil {
+			cancelCheckIn()
+		}
+		now := workflow.Now(ctx)
+		at, kind := NextCheckIn(now, state.Proactive.Timezone)
+		timerCtx, cancel := workflow.WithCancel(ctx)
+		checkInTimer, checkInKind, cancelCheckIn = workflow.NewTimer(timerCtx, at.Sub(now)), kind, cancel
+	}
+	if proactiveEnabled {
+		scheduleComplaint()
+		scheduleCheckIn()
+	}
+
+	// memorize folds messages not yet covered by memories into them
+	memorize := func() {
+		pending := state.Unmemorized()
+		if len(pending) == 0 {
+			return
+		}
+		var output SummarizeMemoriesOutput
+		err := workflow.ExecuteActivity(actCtx, SummarizeMemoriesActivity, SummarizeMemoriesInput{
+			ZiggyID:  input.ZiggyID,
+			Memories: state.Memories,
+			Messages: pending,
+			Now:      workflow.Now(ctx),
+		}).Get(ctx, &output)
+		if err != nil {
+			logger.Info("SummarizeMemories failed", "error", err.Error())
+			return
+		}
+		state.Memories = output.Memories
+		state.MemorizedThrough = pending[len(pending)-1].ID
+	}
+
+	// newSignalSelector wires every signal handler; it is also used to drain
+	// buffered signals before continuing as new.
+	newSignalSelector := func() workflow.Selector {
 		selector := workflow.NewSelector(ctx)
 
 		selector.AddReceive(messageCh, func(c workflow.ReceiveChannel, more bool) {
@@ -117,10 +444,13 @@ func Workflow(ctx workflow.Context, input Input) error {
 				responseTrack = state.ActiveMystery.Track
 			}
 
-			if responseTrack == "educational" && state.ActiveMystery != nil {
-				state.AddMessage("ziggy", "Searching the Temporal docs...", now)
-				state.IsTyping = true
+			quizzing := quizzesEnabled && state.Quiz != nil
+			command := commandsEnabled && IsCommand(signal.Content)
+			if responseTrack == "educational" && state.ActiveMystery != nil && !quizzing && !command {
+				state.AddMessage("ziggy", z.Text(state.Locale, z.MessageSearchingDocs), now)
 			}
+			// Ziggy types until the reply is in; clients stream it meanwhile
+			state.IsTyping = true
 
 			var ziggyState *z.State
 			err := workflow.ExecuteActivity(queryCtx, "QueryZiggyState", input.ZiggyID).Get(ctx, &ziggyState)
@@ -129,6 +459,7 @@ func Workflow(ctx workflow.Context, input Input) error {
 			}
 
 			processInput := ProcessMessageInput{
+				ZiggyID:    input.ZiggyID,
 				State:      state,
 				Content:    signal.Content,
 				ZiggyState: ziggyState,
@@ -137,12 +468,53 @@ func Workflow(ctx workflow.Context, input Input) error {
 			}
 
 			var output ProcessMessageOutput
-			err = workflow.ExecuteActivity(actCtx, "ProcessChatMessage", processInput).Get(ctx, &output)
+			err = workflow.ExecuteActivity(actCtx, ProcessChatMessageActivity, processInput).Get(ctx, &output)
 			if err != nil {
 				logger.Info("ProcessChatMessage failed", "error", err.Error())
+				state.IsTyping = false
 				return
 			}
+			prevCount := len(state.Messages)
 			state = output.State
+			if output.Track != "" {
+				track = output.Track
+			}
+
+			// Care Ziggy asked for in chat goes through ZiggyWorkflow like
+			// any other action, so its cooldowns still apply.
+			if actionsEnabled {
+				for _, signalName := range output.Care {
+					err := workflow.SignalExternalWorkflow(ctx, input.ZiggyID, "", signalName, struct{}{}).Get(ctx, nil)
+					if err != nil {
+						logger.Info("Failed to signal Ziggy", "signal", signalName, "error", err.Error())
+					}
+				}
+			}
+
+			// Every exchange counts as care; ZiggyWorkflow bounds the gains.
+			// Messages moderation rejected never reached Ziggy, and commands
+			// aren't conversation.
+			if interactionsEnabled && !output.Rejected && output.Command == "" {
+				interaction := ziggyworkflow.ChatInteractionSignal{Sentiment: output.Sentiment}
+				err := workflow.SignalExternalWorkflow(ctx, input.ZiggyID, "", ziggyworkflow.SignalChatInteraction, interaction).Get(ctx, nil)
+				if err != nil {
+					logger.Info("Failed to signal chat interaction", "error", err.Error())
+				}
+			}
+
+			if webhooksEnabled && len(state.Messages) > prevCount {
+				for _, msg := range state.Messages[prevCount:] {
+					emitter.Emit(ctx, webhook.EventChatMessage, map[string]any{
+						"id":      msg.ID,
+						"role":    msg.Role,
+						"content": msg.Content,
+					})
+				}
+			}
+
+			if memoriesEnabled && len(state.Unmemorized()) >= MemoryBatch {
+				memorize()
+			}
 		})
 
 		selector.AddReceive(mysteryCh, func(c workflow.ReceiveChannel, more bool) {
@@ -155,18 +527,169 @@ func Workflow(ctx workflow.Context, input Input) error {
 				mysteryTrack = track
 			}
 
-			mystery := GetMystery(signal.MysteryID, mysteryTrack)
+			// The catalog can be reloaded while the worker runs, so record
+			// the lookup in history for replay to reuse
+			var mystery *Mystery
+			if catalogRecorded {
+				lookup := workflow.SideEffect(ctx, func(ctx workflow.Context) any {
+					if dailyEnabled && signal.MysteryID == "" {
+						return Mysteries().Localize(GetRandomMystery(mysteryTrack, state.Completed()), state.Locale)
+					}
+					return Mysteries().Localize(GetMystery(signal.MysteryID, mysteryTrack), state.Locale)
+				})
+				if err := lookup.Get(&mystery); err != nil {
+					logger.Info("Failed to look up mystery", "error", err.Error())
+				}
+			} else {
+				mystery = GetMystery(signal.MysteryID, mysteryTrack)
+			}
 			if mystery != nil {
-				state.ActiveMystery = mystery
-				state.MysteryProgress = 0
-				state.HintsGiven = []string{}
+				state.StartMystery(mystery, workflow.Now(ctx))
+			}
+		})
+
+		selector.AddReceive(forgetCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal ForgetMemorySignal
+			c.Receive(ctx, &signal)
+			if state.Forget(signal.ID) {
+				logger.Info("Forgot memories", "id", signal.ID, "remaining", len(state.Memories))
+			}
+		})
+
+		selector.AddReceive(trackCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal SetTrackSignal
+			c.Receive(ctx, &signal)
+			switch signal.Track {
+			case TrackFun, TrackEducational:
+				track = signal.Track
+				logger.Info("Switched track", "track", track)
+			default:
+				logger.Info("Ignoring unknown track", "track", signal.Track)
+			}
+		})
+
+		selector.AddReceive(abandonCh, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			if m := state.ActiveMystery; m != nil && state.AbandonMystery() {
+				logger.Info("Abandoned mystery", "mysteryID", m.ID)
+			}
+		})
+
+		selector.AddReceive(clearCh, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			state.ClearHistory()
+			logger.Info("Cleared chat history")
+		})
+
+		selector.AddReceive(deleteCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal DeleteMessageSignal
+			c.Receive(ctx, &signal)
+			if state.DeleteMessage(signal.ID) {
+				logger.Info("Deleted message", "id", signal.ID)
 			}
 		})
 
+		selector.AddReceive(localeCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal ziggyworkflow.SetLocaleSignal
+			c.Receive(ctx, &signal)
+			if locale.Valid(signal.Locale) {
+				state.Locale = locale.Normalize(signal.Locale)
+				logger.Info("Locale changed", "locale", state.Locale)
+			}
+		})
+
+		selector.AddReceive(limitsCh, func(c workflow.ReceiveChannel, more bool) {
+			var limits ProactiveLimits
+			c.Receive(ctx, &limits)
+			state.Proactive.Limits = &limits
+			logger.Info("Proactive limits changed", "maxPerDay", limits.MaxPerDay, "minGapMinutes", limits.MinGapMinutes)
+		})
+
+		if proactiveEnabled {
+			selector.AddReceive(petUpdateCh, func(c workflow.ReceiveChannel, more bool) {
+				var update ziggyworkflow.PetUpdateSignal
+				c.Receive(ctx, &update)
+				if update.Timezone != "" && update.Timezone != state.Proactive.Timezone {
+					state.Proactive.Timezone = update.Timezone
+					scheduleCheckIn()
+				}
+				if update.Need != state.Proactive.Need {
+					state.Proactive.Need, state.Proactive.Complaints = update.Need, 0
+					scheduleComplaint()
+				}
+				if update.Kind == ziggyworkflow.UpdateReturned {
+					speak(ProactiveGreeting, update.Away)
+				}
+			})
+		}
+
+		return selector
+	}
+
+	for {
+		selector := newSignalSelector()
+
+		if complaintTimer != nil {
+			selector.AddFuture(complaintTimer, func(f workflow.Future) {
+				cancelComplaint = nil
+				speak(ProactiveComplaint, 0)
+				state.Proactive.Complaints++
+				scheduleComplaint()
+			})
+		}
+
+		// Check-ins are skipped while the owner is chatting anyway
+		if checkInTimer != nil {
+			selector.AddFuture(checkInTimer, func(f workflow.Future) {
+				kind := checkInKind
+				cancelCheckIn = nil
+				scheduleCheckIn()
+				if workflow.Now(ctx).Sub(state.lastOwnerMessage()) >= CheckInQuiet {
+					speak(kind, 0)
+				}
+			})
+		}
+
+		if dailyTimer != nil {
+			selector.AddFuture(dailyTimer, func(f workflow.Future) {
+				rotateDaily()
+			})
+		}
+
+		if reviewTimer != nil {
+			selector.AddFuture(reviewTimer, func(f workflow.Future) {
+				reviewTimer, reviewAt, cancelReview = nil, time.Time{}, nil
+				startReview()
+			})
+		}
+
 		selector.Select(ctx)
 
-		if len(state.Messages) >= MaxMessages {
-			logger.Info("Continuing as new due to message limit")
+		if (dailyEnabled || quizzesEnabled || proactiveEnabled) && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if quizzesEnabled {
+			scheduleReview()
+		}
+
+		// Proactive messages, timers and ignored signals grow the history
+		// without adding chat messages, so its length is bounded too
+		if len(state.Messages) >= MaxMessages || shouldContinueAsNew(ctx) {
+			logger.Info("Continuing as new", "messages", len(state.Messages),
+				"historyLength", workflow.GetInfo(ctx).GetCurrentHistoryLength())
+
+			// Remember what's in the messages about to be trimmed
+			if memoriesEnabled {
+				memorize()
+			}
+
+			// Signals already buffered would be lost with this run, so apply
+			// them before snapshotting. Messages handled here are the newest
+			// and stay among the recent ones.
+			drain := newSignalSelector()
+			for drain.HasPending() {
+				drain.Select(ctx)
+			}
 
 			recentMessages := state.Messages
 			if len(recentMessages) > 20 {
@@ -176,13 +699,35 @@ func Workflow(ctx workflow.Context, input Input) error {
 			return workflow.NewContinueAsNewError(ctx, Workflow, Input{
 				Owner:           input.Owner,
 				ZiggyID:         input.ZiggyID,
-				Track:           input.Track,
+				Track:           track,
+				Locale:          state.Locale,
 				RecentMessages:  recentMessages,
 				ActiveMystery:   state.ActiveMystery,
 				MysteryProgress: state.MysteryProgress,
 				HintsGiven:      state.HintsGiven,
 				Solved:          state.Solved,
+
+				Memories:         state.Memories,
+				MemorizedThrough: state.MemorizedThrough,
+
+				Violations: state.Violations,
+
+				Records: state.Records,
+				Daily:   state.Daily,
+
+				Quiz:     state.Quiz,
+				Concepts: state.Concepts,
+
+				Proactive: &state.Proactive,
+
+				NextMessageID: state.NextMessageID,
 			})
 		}
 	}
 }
+
+// shouldContinueAsNew is a variable so tests can force continue-as-new; the
+// test environment does not track history length.
+var shouldContinueAsNew = func(ctx workflow.Context) bool {
+	return workflow.GetInfo(ctx).GetCurrentHistoryLength() > 5000
+}
@@ -0,0 +1,420 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"ziggy/internal/ai"
+	ziggyworkflow "ziggy/internal/workflow/ziggy"
+	z "ziggy/internal/ziggy"
+)
+
+func TestChatWorkflowMysteryRoundTrip(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{
+		{Text: `{"response": "Here's a hint!", "mysteryUpdate": {"hintGiven": "Look back", "newProgress": 1}}`},
+		{Text: `{"response": "You solved it!", "mysteryUpdate": {"solved": true, "failed": false, "newProgress": 1}}`},
+	}}
+	activities := NewActivities(fake)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: "ProcessChatMessage"})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+
+	ziggy := z.NewState("UTC")
+	ziggy.Personality = z.PersonalityCheerful
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(&ziggy, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalStartMystery, StartMysterySignal{MysteryID: "missing-snack", Track: "fun"})
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "hint?"})
+	}, 2*time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "was it a passing comet?"})
+	}, 3*time.Second)
+
+	var afterHint, afterSolve State
+	query := func(out *State) {
+		result, err := env.QueryWorkflow(QueryChatState)
+		if err != nil {
+			t.Errorf("query: %v", err)
+			return
+		}
+		if err := result.Get(out); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}
+	env.RegisterDelayedCallback(func() { query(&afterHint) }, 2500*time.Millisecond)
+	env.RegisterDelayedCallback(func() {
+		query(&afterSolve)
+		env.CancelWorkflow()
+	}, 4*time.Second)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: "fun"})
+
+	if afterHint.MysteryProgress != 1 || len(afterHint.HintsGiven) != 1 {
+		t.Errorf("after hint: progress %d, hints %v", afterHint.MysteryProgress, afterHint.HintsGiven)
+	}
+	if afterSolve.ActiveMystery != nil || len(afterSolve.Solved) != 1 {
+		t.Errorf("after solve: active %v, solved %v", afterSolve.ActiveMystery, afterSolve.Solved)
+	}
+	if n := len(afterSolve.Messages); n != 4 || afterSolve.Messages[n-1].Content != "You solved it!" {
+		t.Errorf("messages = %+v", afterSolve.Messages)
+	}
+
+	// One hint costs a quarter of an easy mystery's points; the daily
+	// mystery is picked at random and doubles them
+	record := afterSolve.Records["missing-snack"]
+	want := 75
+	if record.Daily {
+		want *= 2
+	}
+	if record.Attempts != 1 || record.HintsUsed != 1 || record.Score != want {
+		t.Errorf("record = %+v, want score %d", record, want)
+	}
+
+	calls := fake.ChatCalls()
+	if len(calls) != 2 || calls[0].Personality != "cheerful" || calls[1].Mystery == nil || len(calls[1].Mystery.HintsGiven) != 1 {
+		t.Errorf("chat inputs = %+v", calls)
+	}
+}
+
+func TestChatWorkflowRemembersAndForgets(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	fake := &ai.FakeProvider{
+		Chats:    []ai.FakeReply{{Chat: &ai.ChatResponse{Response: "*wiggle*"}}},
+		Memories: []ai.FakeReply{{Memory: &ai.MemoryResult{Facts: []ai.MemoryFact{{Kind: ai.MemoryOwner, Fact: "The owner is called Sam."}}}}},
+	}
+	activities := NewActivities(fake)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.SummarizeMemories, activity.RegisterOptions{Name: SummarizeMemoriesActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Each message adds a reply, so MemoryBatch/2 messages fill a batch
+	turns := MemoryBatch / 2
+	for i := 1; i <= turns+1; i++ {
+		env.RegisterDelayedCallback(func() {
+			env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "I'm Sam"})
+		}, time.Duration(i)*time.Second)
+	}
+
+	var remembered, afterForget []Memory
+	query := func(out *[]Memory) {
+		result, err := env.QueryWorkflow(QueryMemories)
+		if err != nil {
+			t.Errorf("query: %v", err)
+			return
+		}
+		if err := result.Get(out); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}
+	env.RegisterDelayedCallback(func() {
+		query(&remembered)
+		if len(remembered) == 1 {
+			env.SignalWorkflow(SignalForgetMemory, ForgetMemorySignal{ID: remembered[0].ID})
+		}
+	}, time.Duration(turns+2)*time.Second)
+	env.RegisterDelayedCallback(func() {
+		query(&afterForget)
+		env.CancelWorkflow()
+	}, time.Duration(turns+3)*time.Second)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: "fun"})
+
+	if len(remembered) != 1 || remembered[0].Kind != ai.MemoryOwner {
+		t.Fatalf("memories = %+v", remembered)
+	}
+	if len(afterForget) != 0 {
+		t.Errorf("memories after forget = %+v", afterForget)
+	}
+
+	memoryCalls := fake.MemoryCalls()
+	if len(memoryCalls) != 1 || len(memoryCalls[0].Messages) != MemoryBatch {
+		t.Errorf("memory calls = %+v", memoryCalls)
+	}
+	chatCalls := fake.ChatCalls()
+	if last := chatCalls[len(chatCalls)-1]; len(last.Memories) != 1 || last.Memories[0] != "The owner is called Sam." {
+		t.Errorf("last chat input memories = %v", last.Memories)
+	}
+}
+
+func TestChatWorkflowSignalsCareToZiggy(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	fake := &ai.FakeProvider{Chats: []ai.FakeReply{{
+		Chat: &ai.ChatResponse{Response: "*munch*", Sentiment: 0.8, Actions: []ai.ChatAction{{Name: ai.ActionRequestFeed}}},
+	}}}
+	activities := NewActivities(fake)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+
+	var signals []string
+	var interaction ziggyworkflow.ChatInteractionSignal
+	env.OnSignalExternalWorkflow(mock.Anything, "ziggy-test", "", mock.Anything, mock.Anything).Return(
+		func(_ string, _ string, _ string, signalName string, arg interface{}) error {
+			signals = append(signals, signalName)
+			if s, ok := arg.(ziggyworkflow.ChatInteractionSignal); ok {
+				interaction = s
+			}
+			return nil
+		})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "here's a cookie"})
+	}, time.Second)
+	env.RegisterDelayedCallback(env.CancelWorkflow, 2*time.Second)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: "fun"})
+
+	if len(signals) != 2 || signals[0] != "feed" || signals[1] != "chat_interaction" {
+		t.Errorf("signals to Ziggy = %v, want [feed chat_interaction]", signals)
+	}
+	if interaction.Sentiment != 0.8 {
+		t.Errorf("interaction sentiment = %v, want 0.8", interaction.Sentiment)
+	}
+}
+
+func TestChatWorkflowRotatesDailyMystery(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC))
+
+	var days []DailyMystery
+	query := func() {
+		result, err := env.QueryWorkflow(QueryMysteryStats)
+		if err != nil {
+			t.Errorf("query: %v", err)
+			return
+		}
+		var stats MysteryStats
+		if err := result.Get(&stats); err != nil || stats.Daily == nil {
+			t.Errorf("stats = %+v, %v", stats, err)
+			return
+		}
+		days = append(days, *stats.Daily)
+	}
+	env.RegisterDelayedCallback(query, 30*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		query()
+		env.CancelWorkflow()
+	}, 90*time.Minute)
+
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: "fun"})
+
+	if len(days) != 2 || days[0].Date != "2026-10-19" || days[1].Date != "2026-10-20" {
+		t.Fatalf("daily mysteries = %+v", days)
+	}
+	for _, d := range days {
+		if m := GetMystery(d.MysteryID, TrackFun); m == nil || len(m.Prerequisites) > 0 {
+			t.Errorf("daily mystery %q isn't available", d.MysteryID)
+		}
+	}
+}
+
+func TestChatWorkflowManagesTrackAndHistory(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	signals := []struct {
+		name string
+		arg  any
+	}{
+		{SignalDeleteMessage, DeleteMessageSignal{ID: "msg-2"}},
+		{SignalStartMystery, StartMysterySignal{MysteryID: "replay", Track: TrackEducational}},
+		{SignalAbandonMystery, nil},
+		{SignalSetTrack, SetTrackSignal{Track: "space"}},
+		{SignalSetTrack, SetTrackSignal{Track: TrackEducational}},
+		{SignalSendMessage, SendMessageSignal{Content: "/status"}},
+	}
+	for i, s := range signals {
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow(s.name, s.arg) }, time.Duration(i+1)*time.Second)
+	}
+
+	var before, after State
+	query := func(out *State) {
+		result, err := env.QueryWorkflow(QueryChatState)
+		if err != nil {
+			t.Errorf("query: %v", err)
+			return
+		}
+		if err := result.Get(out); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}
+	env.RegisterDelayedCallback(func() {
+		query(&before)
+		env.SignalWorkflow(SignalClearHistory, nil)
+	}, 10*time.Second)
+	env.RegisterDelayedCallback(func() {
+		query(&after)
+		env.CancelWorkflow()
+	}, 11*time.Second)
+
+	env.ExecuteWorkflow(Workflow, Input{
+		Owner: "test", ZiggyID: "ziggy-test", Track: TrackFun,
+		RecentMessages: []Message{
+			{ID: "msg-1", Role: "user", Content: "hi"},
+			{ID: "msg-2", Role: "ziggy", Content: "*wiggle*"},
+			{ID: "msg-3", Role: "user", Content: "bye"},
+		},
+		Solved: []string{"missing-snack"},
+	})
+
+	if before.ActiveMystery != nil || before.Records["replay"].StartedAt.IsZero() {
+		t.Errorf("not abandoned: active %v, records %v", before.ActiveMystery, before.Records)
+	}
+	if len(before.Messages) != 4 || before.Messages[1].ID != "msg-3" {
+		t.Fatalf("messages = %+v", before.Messages)
+	}
+	// New messages are numbered after the ones carried over, the same way
+	// on every replay
+	if before.Messages[2].ID != "msg-4" || before.Messages[3].ID != "msg-5" {
+		t.Errorf("new message IDs = %s, %s, want msg-4, msg-5", before.Messages[2].ID, before.Messages[3].ID)
+	}
+	if got := before.Messages[3].Content; !strings.Contains(got, "Track: educational") {
+		t.Errorf("/status = %q", got)
+	}
+	if len(after.Messages) != 0 || len(after.Solved) != 1 || after.Solved[0] != "missing-snack" {
+		t.Errorf("after clearing: messages %v, solved %v", after.Messages, after.Solved)
+	}
+}
+
+func TestChatWorkflowContinuesAsNewInLocale(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// One more exchange takes the chat to MaxMessages
+	var recent []Message
+	for i := 1; i < MaxMessages-1; i++ {
+		recent = append(recent, Message{ID: fmt.Sprintf("msg-%d", i), Role: "user", Content: "hallo"})
+	}
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSendMessage, SendMessageSignal{Content: "/status"})
+	}, time.Second)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: TrackFun, Locale: "de", RecentMessages: recent})
+
+	var can *workflow.ContinueAsNewError
+	if err := env.GetWorkflowError(); !errors.As(err, &can) {
+		t.Fatalf("workflow error = %v, want continue-as-new", err)
+	}
+	var next Input
+	if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.Locale != "de" {
+		t.Errorf("continued locale = %q, want de", next.Locale)
+	}
+	if next.NextMessageID != MaxMessages+1 {
+		t.Errorf("continued next message ID = %d, want %d", next.NextMessageID, MaxMessages+1)
+	}
+}
+
+func TestChatWorkflowDrainsSignalsBeforeContinueAsNew(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+	env.RegisterActivityWithOptions(activities.QueryZiggyState, activity.RegisterOptions{Name: "QueryZiggyState"})
+	env.OnActivity("QueryZiggyState", mock.Anything, "ziggy-test").Return(nil, nil)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var recent []Message
+	for i := 1; i < MaxMessages-1; i++ {
+		recent = append(recent, Message{ID: fmt.Sprintf("msg-%d", i), Role: "user", Content: "hi"})
+	}
+	// The exchange that reaches MaxMessages arrives with other signals
+	// buffered behind it; all of them must make it into the next run
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflowSkippingWorkflowTask(SignalSendMessage, SendMessageSignal{Content: "/status"})
+		env.SignalWorkflowSkippingWorkflowTask(SignalSetTrack, SetTrackSignal{Track: TrackEducational})
+		env.SignalWorkflowSkippingWorkflowTask(SignalDeleteMessage, DeleteMessageSignal{ID: "msg-40"})
+		env.SignalWorkflow(SignalSetLocale, ziggyworkflow.SetLocaleSignal{Locale: "es"})
+	}, time.Second)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: TrackFun, RecentMessages: recent})
+
+	var can *workflow.ContinueAsNewError
+	if err := env.GetWorkflowError(); !errors.As(err, &can) {
+		t.Fatalf("workflow error = %v, want continue-as-new", err)
+	}
+	var next Input
+	if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.Track != TrackEducational {
+		t.Errorf("continued track = %q, want %q", next.Track, TrackEducational)
+	}
+	if next.Locale != "es" {
+		t.Errorf("continued locale = %q, want es", next.Locale)
+	}
+	for _, m := range next.RecentMessages {
+		if m.ID == "msg-40" {
+			t.Errorf("deleted message carried over: %+v", m)
+		}
+	}
+}
+
+func TestChatWorkflowContinuesAsNewOnHistoryLength(t *testing.T) {
+	defer func(f func(workflow.Context) bool) { shouldContinueAsNew = f }(shouldContinueAsNew)
+	shouldContinueAsNew = func(workflow.Context) bool { return true }
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessChatMessage, activity.RegisterOptions{Name: ProcessChatMessageActivity})
+
+	// A quiet chat still continues as new once its history is long
+	recent := []Message{{ID: "msg-1", Role: "user", Content: "hi"}, {ID: "msg-2", Role: "ziggy", Content: "*wiggle*"}}
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSetTrack, SetTrackSignal{Track: TrackEducational})
+	}, time.Second)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", ZiggyID: "ziggy-test", Track: TrackFun, RecentMessages: recent, NextMessageID: 3})
+
+	var can *workflow.ContinueAsNewError
+	if err := env.GetWorkflowError(); !errors.As(err, &can) {
+		t.Fatalf("workflow error = %v, want continue-as-new", err)
+	}
+	var next Input
+	if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.Track != TrackEducational {
+		t.Errorf("continued track = %q, want %q", next.Track, TrackEducational)
+	}
+	if len(next.RecentMessages) != 2 || next.NextMessageID != 3 {
+		t.Errorf("continued messages %v, next ID %d; want both, 3", next.RecentMessages, next.NextMessageID)
+	}
+}
@@ -2,15 +2,19 @@
 package workflow
 
 import (
+	"ziggy/internal/workflow/budget"
 	"ziggy/internal/workflow/chat"
 	"ziggy/internal/workflow/need_updater"
 	"ziggy/internal/workflow/pool_regenerator"
+	"ziggy/internal/workflow/webhook"
 	"ziggy/internal/workflow/ziggy"
 )
 
 func RegisterWorkflows() {
+	budget.Register()
 	chat.Register()
 	need_updater.Register()
 	pool_regenerator.Register()
+	webhook.Register()
 	ziggy.Register()
 }
@@ -109,10 +109,7 @@ func queryZiggyState(ctx workflow.Context, ziggyID string, logger interface{ Inf
 }
 
 func pickNeedMessage(state *z.State, need z.NeedType) string {
-	fallback := z.GetFallbackPool(state.Personality)
-	generic := z.GetFallbackPool(z.PersonalityStoic)
-	selector := z.NewPoolSelector(state.RuntimePool, fallback, generic)
-	return selector.Pick(string(need))
+	return state.PoolSelector().Pick(string(need))
 }
 
 func signalZiggyUpdate(ctx workflow.Context, workflowID string, message string, personality z.Personality, logger interface{ Info(string, ...interface{}) }) {
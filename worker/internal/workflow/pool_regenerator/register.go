@@ -11,7 +11,7 @@ func Register() {
 		Name:     "PoolRegeneratorWorkflow",
 		Workflow: Workflow,
 		IDPattern: func(owner string) string {
-			return fmt.Sprintf("ziggy-%s-pool-regenerator", owner)
+			return fmt.Sprintf("ziggy-%s%s", owner, WorkflowIDSuffix)
 		},
 		NewInput: func(owner, ziggyID, _ string) any {
 			return Input{ZiggyWorkflowID: ziggyID}
@@ -13,6 +13,10 @@ const (
 	SignalPoolRegenerate = "pool_regenerate"
 
 	RegenerationInterval = 6 * time.Hour
+
+	// WorkflowIDSuffix is appended to the Ziggy workflow ID to address the
+	// owner's pool regenerator workflow.
+	WorkflowIDSuffix = "-pool-regenerator"
 )
 
 type Input struct {
@@ -23,17 +27,21 @@ type RegenerateSignal struct {
 	Personality z.Personality `json:"personality"`
 	Stage       z.Stage       `json:"stage"`
 	Bond        float64       `json:"bond"`
+	Locale      string        `json:"locale,omitempty"`
 }
 
 type RegenerationInput struct {
+	ZiggyID     string        `json:"ziggyId,omitempty"`
 	Personality z.Personality `json:"personality"`
 	Stage       z.Stage       `json:"stage"`
 	Bond        float64       `json:"bond"`
+	Locale      string        `json:"locale,omitempty"`
 }
 
 type RegenerationOutput struct {
 	Pool        *z.MessagePool `json:"pool"`
 	GeneratedAt time.Time      `json:"generatedAt"`
+	Locale      string         `json:"locale,omitempty"`
 }
 
 func Workflow(ctx workflow.Context, input Input) error {
@@ -68,6 +76,7 @@ func Workflow(ctx workflow.Context, input Input) error {
 					Personality: state.Personality,
 					Stage:       state.Stage,
 					Bond:        state.Bond,
+					Locale:      state.Locale,
 				}
 				regenerateAndSignal(ctx, actCtx, input.ZiggyWorkflowID, signal, logger)
 			}
@@ -86,9 +95,11 @@ func Workflow(ctx workflow.Context, input Input) error {
 func regenerateAndSignal(ctx, actCtx workflow.Context, ziggyWorkflowID string, signal RegenerateSignal, logger interface{ Info(string, ...interface{}) }) {
 	var output RegenerationOutput
 	err := workflow.ExecuteActivity(actCtx, "RegeneratePool", RegenerationInput{
+		ZiggyID:     ziggyWorkflowID,
 		Personality: signal.Personality,
 		Stage:       signal.Stage,
 		Bond:        signal.Bond,
+		Locale:      signal.Locale,
 	}).Get(ctx, &output)
 
 	if err != nil {
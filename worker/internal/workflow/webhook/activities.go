@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+type Activities struct {
+	httpClient *http.Client
+}
+
+func NewActivities(httpClient *http.Client) *Activities {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Activities{httpClient: httpClient}
+}
+
+type DeliverInput struct {
+	Webhook    Webhook `json:"webhook"`
+	Event      Event   `json:"event"`
+	DeliveryID string  `json:"deliveryId"`
+}
+
+type DeliverOutput struct {
+	StatusCode int `json:"statusCode"`
+	Attempt    int `json:"attempt"`
+}
+
+const errTypeDelivery = "WebhookDeliveryError"
+
+func (a *Activities) DeliverWebhook(ctx context.Context, input DeliverInput) (*DeliverOutput, error) {
+	attempt := int(activity.GetInfo(ctx).Attempt)
+	log.Printf("[Webhook] Delivering %s to %s (attempt %d)", input.Event.Type, input.Webhook.URL, attempt)
+
+	body, err := json.Marshal(input.Event)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("marshal event", errTypeDelivery, err, DeliverOutput{Attempt: attempt})
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, input.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("build request", errTypeDelivery, err, DeliverOutput{Attempt: attempt})
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Ziggy-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(input.Event.Type))
+	req.Header.Set(HeaderDelivery, input.DeliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if input.Webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(input.Webhook.Secret, timestamp, body))
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[Webhook] Delivery to %s failed: %v", input.Webhook.URL, err)
+		return nil, temporal.NewApplicationError(err.Error(), errTypeDelivery, DeliverOutput{Attempt: attempt})
+	}
+	defer resp.Body.Close()
+
+	output := DeliverOutput{StatusCode: resp.StatusCode, Attempt: attempt}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return &output, nil
+	}
+
+	msg := fmt.Sprintf("webhook responded with status %d", resp.StatusCode)
+	log.Printf("[Webhook] %s (%s)", msg, input.Webhook.URL)
+
+	// Client errors won't fix themselves on retry, except rate limiting
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, temporal.NewNonRetryableApplicationError(msg, errTypeDelivery, nil, output)
+	}
+	return nil, temporal.NewApplicationError(msg, errTypeDelivery, output)
+}
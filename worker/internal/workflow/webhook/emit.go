@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// NewEventID builds a deterministic event ID from the event type, the
+// workflow time it was raised at and its sequence number in the run, which
+// tells apart events raised in the same workflow task.
+func NewEventID(t EventType, at time.Time, seq int) string {
+	return fmt.Sprintf("evt-%s-%d-%d", t, at.UnixNano(), seq)
+}
+
+// Emitter signals events to the owner's webhook workflow from inside
+// another workflow. Delivery is fire-and-forget: a missing webhook workflow
+// must never block the pet.
+type Emitter struct {
+	ziggyWorkflowID string
+	seq             int
+}
+
+func NewEmitter(ziggyWorkflowID string) *Emitter {
+	return &Emitter{ziggyWorkflowID: ziggyWorkflowID}
+}
+
+// Emit raises an event of type t.
+func (e *Emitter) Emit(ctx workflow.Context, t EventType, data map[string]any) {
+	e.seq++
+	now := workflow.Now(ctx)
+	event := Event{
+		ID:        NewEventID(t, now, e.seq),
+		Type:      t,
+		Timestamp: now,
+		Data:      data,
+	}
+	workflow.SignalExternalWorkflow(ctx, e.ziggyWorkflowID+WorkflowIDSuffix, "", SignalEvent, event)
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"fmt"
+
+	"ziggy/internal/registry"
+)
+
+func Register() {
+	registry.RegisterWorkflow(registry.Definition{
+		Name:     "WebhookWorkflow",
+		Workflow: Workflow,
+		IDPattern: func(owner string) string {
+			return fmt.Sprintf("ziggy-%s%s", owner, WorkflowIDSuffix)
+		},
+		NewInput: func(owner, _, _ string) any {
+			return Input{Owner: owner}
+		},
+		AutoStart: true,
+	})
+
+	activities := NewActivities(nil)
+	registry.RegisterActivity(registry.ActivityDef{
+		Name:     "DeliverWebhook",
+		Activity: activities.DeliverWebhook,
+	})
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventAction            EventType = "action"
+	EventMoodChange        EventType = "mood_change"
+	EventStageChange       EventType = "stage_change"
+	EventPersonalityChange EventType = "personality_change"
+	EventTunEnter          EventType = "tun_enter"
+	EventTunExit           EventType = "tun_exit"
+	EventChatMessage       EventType = "chat_message"
+	EventTest              EventType = "test"
+)
+
+var EventTypes = []EventType{
+	EventAction,
+	EventMoodChange,
+	EventStageChange,
+	EventPersonalityChange,
+	EventTunEnter,
+	EventTunExit,
+	EventChatMessage,
+	EventTest,
+}
+
+func IsValidEventType(t string) bool {
+	for _, et := range EventTypes {
+		if string(et) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON payload posted to every matching webhook.
+type Event struct {
+	ID        string         `json:"id"`
+	Type      EventType      `json:"type"`
+	Owner     string         `json:"owner"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether the webhook subscribes to the event type.
+// An empty filter (or "*") subscribes to everything.
+func (w Webhook) Matches(t EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == "*" || e == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+type Delivery struct {
+	ID         string         `json:"id"`
+	WebhookID  string         `json:"webhookId"`
+	EventID    string         `json:"eventId"`
+	EventType  EventType      `json:"eventType"`
+	Status     DeliveryStatus `json:"status"`
+	StatusCode int            `json:"statusCode,omitempty"`
+	Attempts   int            `json:"attempts"`
+	Error      string         `json:"error,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+const (
+	HeaderEvent     = "X-Ziggy-Event"
+	HeaderDelivery  = "X-Ziggy-Delivery"
+	HeaderTimestamp = "X-Ziggy-Timestamp"
+	HeaderSignature = "X-Ziggy-Signature"
+)
+
+// Sign returns the value of the X-Ziggy-Signature header for a payload.
+// The signed message is "<timestamp>.<body>" so receivers can reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestDeliverWebhookSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+
+	var verified bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		verified = Verify(secret, ts, body, r.Header.Get(HeaderSignature))
+		if r.Header.Get(HeaderEvent) != string(EventAction) {
+			t.Errorf("event header = %q", r.Header.Get(HeaderEvent))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	activities := NewActivities(srv.Client())
+	env.RegisterActivity(activities.DeliverWebhook)
+
+	val, err := env.ExecuteActivity(activities.DeliverWebhook, DeliverInput{
+		Webhook:    Webhook{ID: "wh_1", URL: srv.URL, Secret: secret},
+		Event:      Event{ID: "evt-1", Type: EventAction, Timestamp: time.Now()},
+		DeliveryID: "evt-1:wh_1",
+	})
+	if err != nil {
+		t.Fatalf("DeliverWebhook() error = %v", err)
+	}
+
+	var out DeliverOutput
+	if err := val.Get(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", out.StatusCode, http.StatusNoContent)
+	}
+	if !verified {
+		t.Error("signature did not verify")
+	}
+}
+
+func TestDeliverWebhookClientErrorIsFinal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	activities := NewActivities(srv.Client())
+	env.RegisterActivity(activities.DeliverWebhook)
+
+	_, err := env.ExecuteActivity(activities.DeliverWebhook, DeliverInput{
+		Webhook: Webhook{ID: "wh_1", URL: srv.URL},
+		Event:   Event{ID: "evt-1", Type: EventTest},
+	})
+	if err == nil {
+		t.Fatal("expected error for 410 response")
+	}
+}
+
+func TestWebhookMatches(t *testing.T) {
+	all := Webhook{}
+	if !all.Matches(EventChatMessage) {
+		t.Error("empty filter should match every event")
+	}
+
+	filtered := Webhook{Events: []string{"tun_enter", "tun_exit"}}
+	if !filtered.Matches(EventTunEnter) || filtered.Matches(EventAction) {
+		t.Error("filter did not restrict events")
+	}
+}
@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	SignalRegister = "register_webhook"
+	SignalDelete   = "delete_webhook"
+	SignalEvent    = "webhook_event"
+	SignalTest     = "test_webhook"
+
+	QueryWebhooks   = "webhooks"
+	QueryDeliveries = "deliveries"
+
+	// WorkflowIDSuffix is appended to the Ziggy workflow ID to address the
+	// owner's webhook workflow.
+	WorkflowIDSuffix = "-webhooks"
+
+	MaxDeliveries       = 200
+	MaxDeliveryAttempts = 5
+)
+
+type Input struct {
+	Owner      string     `json:"owner"`
+	Webhooks   []Webhook  `json:"webhooks,omitempty"`
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+}
+
+type RegisterSignal struct {
+	Webhook Webhook `json:"webhook"`
+}
+
+type DeleteSignal struct {
+	ID string `json:"id"`
+}
+
+type TestSignal struct {
+	ID string `json:"id"`
+}
+
+func Workflow(ctx workflow.Context, input Input) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Webhook workflow started", "owner", input.Owner, "webhooks", len(input.Webhooks))
+
+	webhooks := input.Webhooks
+	deliveries := input.Deliveries
+
+	err := workflow.SetQueryHandler(ctx, QueryWebhooks, func() ([]Webhook, error) {
+		return webhooks, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetQueryHandler(ctx, QueryDeliveries, func() ([]Delivery, error) {
+		return deliveries, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 15 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    MaxDeliveryAttempts,
+		},
+	})
+
+	recordDelivery := func(d Delivery) {
+		deliveries = append(deliveries, d)
+		if len(deliveries) > MaxDeliveries {
+			deliveries = deliveries[len(deliveries)-MaxDeliveries:]
+		}
+	}
+
+	// Deliveries run in their own coroutines so a slow endpoint retrying
+	// with backoff doesn't hold up events for other webhooks.
+	inFlight := 0
+	deliver := func(hook Webhook, event Event) {
+		inFlight++
+		workflow.Go(ctx, func(gCtx workflow.Context) {
+			defer func() { inFlight-- }()
+
+			delivery := Delivery{
+				ID:        event.ID + ":" + hook.ID,
+				WebhookID: hook.ID,
+				EventID:   event.ID,
+				EventType: event.Type,
+			}
+
+			var output DeliverOutput
+			err := workflow.ExecuteActivity(actCtx, "DeliverWebhook", DeliverInput{
+				Webhook:    hook,
+				Event:      event,
+				DeliveryID: delivery.ID,
+			}).Get(gCtx, &output)
+
+			delivery.Timestamp = workflow.Now(gCtx)
+			if err != nil {
+				var appErr *temporal.ApplicationError
+				if errors.As(err, &appErr) && appErr.HasDetails() {
+					_ = appErr.Details(&output)
+				}
+				delivery.Status = DeliveryFailed
+				delivery.Error = err.Error()
+				logger.Info("Webhook delivery failed", "webhook", hook.ID, "event", event.ID, "error", err.Error())
+			} else {
+				delivery.Status = DeliveryDelivered
+			}
+			delivery.StatusCode = output.StatusCode
+			delivery.Attempts = output.Attempt
+
+			recordDelivery(delivery)
+		})
+	}
+
+	onRegister := func(c workflow.ReceiveChannel, more bool) {
+		var signal RegisterSignal
+		c.Receive(ctx, &signal)
+		if signal.Webhook.ID == "" || signal.Webhook.URL == "" {
+			return
+		}
+		if signal.Webhook.CreatedAt.IsZero() {
+			signal.Webhook.CreatedAt = workflow.Now(ctx)
+		}
+		webhooks = removeWebhook(webhooks, signal.Webhook.ID)
+		webhooks = append(webhooks, signal.Webhook)
+		logger.Info("Webhook registered", "id", signal.Webhook.ID, "url", signal.Webhook.URL)
+	}
+
+	onDelete := func(c workflow.ReceiveChannel, more bool) {
+		var signal DeleteSignal
+		c.Receive(ctx, &signal)
+		webhooks = removeWebhook(webhooks, signal.ID)
+		logger.Info("Webhook deleted", "id", signal.ID)
+	}
+
+	onEvent := func(c workflow.ReceiveChannel, more bool) {
+		var event Event
+		c.Receive(ctx, &event)
+		if event.Owner == "" {
+			event.Owner = input.Owner
+		}
+		for _, hook := range webhooks {
+			if hook.Matches(event.Type) {
+				deliver(hook, event)
+			}
+		}
+	}
+
+	// tests numbers test events, so two tests in one task get distinct IDs
+	tests := 0
+	onTest := func(c workflow.ReceiveChannel, more bool) {
+		var signal TestSignal
+		c.Receive(ctx, &signal)
+		now := workflow.Now(ctx)
+		for _, hook := range webhooks {
+			if hook.ID != signal.ID {
+				continue
+			}
+			tests++
+			deliver(hook, Event{
+				ID:        NewEventID(EventTest, now, tests),
+				Type:      EventTest,
+				Owner:     input.Owner,
+				Timestamp: now,
+				Data:      map[string]any{"message": "Hello from Ziggy!"},
+			})
+		}
+	}
+
+	handlers := []struct {
+		ch     workflow.ReceiveChannel
+		handle func(workflow.ReceiveChannel, bool)
+	}{
+		{workflow.GetSignalChannel(ctx, SignalRegister), onRegister},
+		{workflow.GetSignalChannel(ctx, SignalDelete), onDelete},
+		{workflow.GetSignalChannel(ctx, SignalEvent), onEvent},
+		{workflow.GetSignalChannel(ctx, SignalTest), onTest},
+	}
+
+	for {
+		selector := workflow.NewSelector(ctx)
+		for _, h := range handlers {
+			selector.AddReceive(h.ch, h.handle)
+		}
+		selector.Select(ctx)
+
+		if workflow.GetInfo(ctx).GetCurrentHistoryLength() > 5000 {
+			logger.Info("Webhook workflow continuing as new")
+			// Handle signals that arrived meanwhile, which continue-as-new
+			// would otherwise drop, and let in-flight deliveries finish so
+			// their results land in the log
+			for {
+				if err := workflow.Await(ctx, func() bool { return inFlight == 0 }); err != nil {
+					return err
+				}
+				pending := true
+				for pending {
+					selector := workflow.NewSelector(ctx)
+					for _, h := range handlers {
+						selector.AddReceive(h.ch, h.handle)
+					}
+					selector.AddDefault(func() { pending = false })
+					selector.Select(ctx)
+				}
+				if inFlight == 0 {
+					break
+				}
+			}
+			return workflow.NewContinueAsNewError(ctx, Workflow, Input{
+				Owner:      input.Owner,
+				Webhooks:   webhooks,
+				Deliveries: deliveries,
+			})
+		}
+	}
+}
+
+func removeWebhook(webhooks []Webhook, id string) []Webhook {
+	result := make([]Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		if w.ID != id {
+			result = append(result, w)
+		}
+	}
+	return result
+}
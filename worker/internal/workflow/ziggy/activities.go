@@ -6,21 +6,24 @@ import (
 	"time"
 
 	"ziggy/internal/ai"
+	"ziggy/internal/locale"
 	z "ziggy/internal/ziggy"
 )
 
 type Activities struct {
-	aiClient *ai.Client
+	provider ai.Provider
 }
 
-func NewActivities(aiClient *ai.Client) *Activities {
-	return &Activities{aiClient: aiClient}
+func NewActivities(provider ai.Provider) *Activities {
+	return &Activities{provider: provider}
 }
 
 type ProcessActionInput struct {
-	State  z.State  `json:"state"`
-	Action z.Action `json:"action"`
+	State  z.State   `json:"state"`
+	Action z.Action  `json:"action"`
 	Now    time.Time `json:"now"`
+	// Sentiment scores a chat message from -1 (rude) to 1 (kind).
+	Sentiment float64 `json:"sentiment,omitempty"`
 }
 
 type ProcessActionOutput struct {
@@ -28,9 +31,12 @@ type ProcessActionOutput struct {
 }
 
 type PoolRegenerationInput struct {
+	// ZiggyID is the account AI usage is billed to.
+	ZiggyID     string        `json:"ziggyId,omitempty"`
 	Personality z.Personality `json:"personality"`
 	Stage       z.Stage       `json:"stage"`
 	Bond        float64       `json:"bond"`
+	Locale      string        `json:"locale,omitempty"`
 }
 
 func (a *Activities) ProcessAction(ctx context.Context, input ProcessActionInput) (*ProcessActionOutput, error) {
@@ -48,6 +54,8 @@ func (a *Activities) ProcessAction(ctx context.Context, input ProcessActionInput
 		processActionPet(&state, now)
 	case z.ActionWake:
 		processActionWake(&state, now)
+	case z.ActionChat:
+		processActionChat(&state, input.Sentiment, now)
 	}
 
 	state.LastUpdateTime = now
@@ -57,11 +65,11 @@ func (a *Activities) ProcessAction(ctx context.Context, input ProcessActionInput
 func processActionFeed(state *z.State, now time.Time) {
 	age := now.Sub(state.CreatedAt).Seconds()
 	if z.GetStageForAge(age) == z.StageEgg {
-		state.Message = "*wiggle*\n*wiggle*\nStill hatching..."
+		state.Message = z.Text(state.Locale, z.MessageHatching)
 		return
 	}
 
-	pool := getPoolSelector(state)
+	pool := state.PoolSelector()
 
 	effectiveCooldown := state.GetEffectiveCooldown(z.ActionFeed)
 	if !state.LastFeedTime.IsZero() && now.Sub(state.LastFeedTime) < effectiveCooldown {
@@ -117,11 +125,11 @@ func processActionFeed(state *z.State, now time.Time) {
 func processActionPlay(state *z.State, now time.Time) {
 	age := now.Sub(state.CreatedAt).Seconds()
 	if z.GetStageForAge(age) == z.StageEgg {
-		state.Message = "*wiggle*\n*wiggle*\nStill hatching..."
+		state.Message = z.Text(state.Locale, z.MessageHatching)
 		return
 	}
 
-	pool := getPoolSelector(state)
+	pool := state.PoolSelector()
 
 	effectiveCooldown := state.GetEffectiveCooldown(z.ActionPlay)
 	if !state.LastPlayTime.IsZero() && now.Sub(state.LastPlayTime) < effectiveCooldown {
@@ -165,7 +173,7 @@ func processActionPlay(state *z.State, now time.Time) {
 }
 
 func processActionPet(state *z.State, now time.Time) {
-	pool := getPoolSelector(state)
+	pool := state.PoolSelector()
 
 	effectiveCooldown := state.GetEffectiveCooldown(z.ActionPet)
 	if !state.LastPetTime.IsZero() && now.Sub(state.LastPetTime) < effectiveCooldown {
@@ -221,38 +229,69 @@ func processActionWake(state *z.State, now time.Time) {
 
 	state.Sleeping = false
 	state.Happiness -= 10
-	state.Message = "*yawn*\nI was having\nsuch a nice dream..."
+	state.Message = z.Text(state.Locale, z.MessageWake)
 	state.LastAction = z.ActionWake
 	state.Clamp()
 }
 
+// processActionChat counts a chat exchange as care. Gains are bounded and
+// have their own cooldown so a long conversation can't max out Bond, and the
+// displayed message is left alone since the reply is in the chat.
+func processActionChat(state *z.State, sentiment float64, now time.Time) {
+	age := now.Sub(state.CreatedAt).Seconds()
+	if z.GetStageForAge(age) == z.StageEgg || state.HP == 0 {
+		return
+	}
+
+	if !state.LastChatTime.IsZero() && now.Sub(state.LastChatTime) < state.GetEffectiveCooldown(z.ActionChat) {
+		return
+	}
+
+	state.CareMetrics.RecordInteraction(state.Fullness, state.Bond, now)
+	state.Personality = z.DerivePersonality(state.CareMetrics, state.Bond, now)
+	state.LastChatTime = now
+
+	if state.Sleeping {
+		return
+	}
+
+	sentiment = max(-1, min(1, sentiment))
+	state.Bond += z.ChatBondGain + z.ChatSentimentSwing*sentiment
+	state.Happiness += z.ChatHappinessGain + z.ChatSentimentSwing*sentiment
+	state.Clamp()
+}
+
 func (a *Activities) RegeneratePool(ctx context.Context, input PoolRegenerationInput) (*PoolRegenerationOutput, error) {
-	log.Printf("[RegeneratePool] Starting pool regeneration: personality=%s stage=%s bond=%.1f",
-		input.Personality, input.Stage, input.Bond)
+	log.Printf("[RegeneratePool] Starting pool regeneration: personality=%s stage=%s bond=%.1f locale=%s",
+		input.Personality, input.Stage, input.Bond, input.Locale)
 
-	if a.aiClient == nil {
-		log.Printf("[RegeneratePool] No AI client configured, using fallback pools")
+	ctx = ai.WithAccount(ctx, input.ZiggyID)
+	if a.provider == nil || !a.provider.Available() {
+		log.Printf("[RegeneratePool] No AI provider configured, using fallback pools")
 		return &PoolRegenerationOutput{
 			Pool:        nil,
 			GeneratedAt: time.Now(),
+			Locale:      input.Locale,
 		}, nil
 	}
 
 	bondDescription := getBondDescription(input.Bond)
-	log.Printf("[RegeneratePool] Calling Claude API with bond description: %s", bondDescription)
+	log.Printf("[RegeneratePool] Calling %s provider with bond description: %s", a.provider.Name(), bondDescription)
 
 	aiInput := ai.PoolGenerationInput{
 		Personality:     string(input.Personality),
 		Stage:           string(input.Stage),
 		BondDescription: bondDescription,
+		Language:        locale.Language(input.Locale),
 	}
 
-	aiPool, err := a.aiClient.GeneratePool(ctx, aiInput)
+	aiPool, err := a.provider.GeneratePool(ctx, aiInput)
 	if err != nil {
-		log.Printf("[RegeneratePool] Claude API error: %v", err)
+		log.Printf("[RegeneratePool] AI provider error: %v", err)
 		return &PoolRegenerationOutput{
 			Pool:        nil,
 			GeneratedAt: time.Now(),
+			Locale:      input.Locale,
 		}, nil
 	}
 
@@ -263,6 +302,7 @@ func (a *Activities) RegeneratePool(ctx context.Context, input PoolRegenerationI
 	return &PoolRegenerationOutput{
 		Pool:        pool,
 		GeneratedAt: time.Now(),
+		Locale:      input.Locale,
 	}, nil
 }
 
@@ -322,9 +362,3 @@ func convertAIPool(aiPool *ai.MessagePool) *z.MessagePool {
 		NeedsCritical:  aiPool.NeedsCritical,
 	}
 }
-
-func getPoolSelector(state *z.State) *z.PoolSelector {
-	fallback := z.GetFallbackPool(state.Personality)
-	generic := z.GetFallbackPool(z.PersonalityStoic)
-	return z.NewPoolSelector(state.RuntimePool, fallback, generic)
-}
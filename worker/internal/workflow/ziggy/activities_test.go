@@ -0,0 +1,131 @@
+package ziggy
+
+import (
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"ziggy/internal/ai"
+	z "ziggy/internal/ziggy"
+)
+
+func regeneratePool(t *testing.T, provider ai.Provider, input PoolRegenerationInput) PoolRegenerationOutput {
+	t.Helper()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	activities := NewActivities(provider)
+	env.RegisterActivity(activities.RegeneratePool)
+
+	val, err := env.ExecuteActivity(activities.RegeneratePool, input)
+	if err != nil {
+		t.Fatalf("RegeneratePool() error = %v", err)
+	}
+	var out PoolRegenerationOutput
+	if err := val.Get(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRegeneratePool(t *testing.T) {
+	fake := &ai.FakeProvider{Pools: []ai.FakeReply{{
+		Pool: &ai.MessagePool{FeedSuccess: []string{"*nom*"}, NeedsCritical: []string{"help..."}},
+	}}}
+
+	out := regeneratePool(t, fake, PoolRegenerationInput{Personality: z.PersonalityDramatic, Stage: z.StageTeen, Bond: 65})
+	if out.Pool == nil || out.Pool.FeedSuccess[0] != "*nom*" || out.Pool.NeedsCritical[0] != "help..." {
+		t.Fatalf("pool = %+v", out.Pool)
+	}
+	if out.GeneratedAt.IsZero() {
+		t.Error("generatedAt not set")
+	}
+
+	calls := fake.PoolCalls()
+	if len(calls) != 1 || calls[0].Personality != "dramatic" || calls[0].Stage != "teen" || calls[0].BondDescription != getBondDescription(65) {
+		t.Errorf("pool input = %+v", calls)
+	}
+}
+
+func TestRegeneratePoolInLocale(t *testing.T) {
+	fake := &ai.FakeProvider{Pools: []ai.FakeReply{{Pool: &ai.MessagePool{FeedSuccess: []string{"*もぐもぐ*"}}}}}
+
+	out := regeneratePool(t, fake, PoolRegenerationInput{Personality: z.PersonalityShy, Locale: "ja"})
+	if out.Locale != "ja" {
+		t.Errorf("locale = %q, want ja", out.Locale)
+	}
+	if calls := fake.PoolCalls(); len(calls) != 1 || calls[0].Language != "Japanese" {
+		t.Errorf("pool input = %+v", calls)
+	}
+}
+
+func TestRegeneratePoolFallsBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider ai.Provider
+	}{
+		{"no provider", nil},
+		{"unconfigured provider", &ai.FakeProvider{Unavailable: true}},
+		{"malformed response", &ai.FakeProvider{Pools: []ai.FakeReply{{Text: "I'd rather not."}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := regeneratePool(t, tt.provider, PoolRegenerationInput{Personality: z.PersonalityShy})
+			if out.Pool != nil {
+				t.Errorf("pool = %+v, want nil so fallback pools are used", out.Pool)
+			}
+		})
+	}
+}
+
+func TestProcessActionChat(t *testing.T) {
+	now := time.Now()
+	awake := func() z.State {
+		state := z.NewState("UTC")
+		state.CreatedAt = now.Add(-time.Hour)
+		state.Sleeping = false
+		state.Bond, state.Happiness = 50, 50
+		return state
+	}
+
+	tests := []struct {
+		name          string
+		sentiment     float64
+		wantBond      float64
+		wantHappiness float64
+	}{
+		{"neutral", 0, 50 + z.ChatBondGain, 50 + z.ChatHappinessGain},
+		{"kind", 1, 50 + z.ChatBondGain + z.ChatSentimentSwing, 50 + z.ChatHappinessGain + z.ChatSentimentSwing},
+		{"rude", -1, 50 + z.ChatBondGain - z.ChatSentimentSwing, 50 + z.ChatHappinessGain - z.ChatSentimentSwing},
+		{"out of range is clamped", 7, 50 + z.ChatBondGain + z.ChatSentimentSwing, 50 + z.ChatHappinessGain + z.ChatSentimentSwing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := awake()
+			interactions := state.CareMetrics.TotalInteractions
+			processActionChat(&state, tt.sentiment, now)
+
+			if state.Bond != tt.wantBond || state.Happiness != tt.wantHappiness {
+				t.Errorf("bond = %v, happiness = %v, want %v, %v", state.Bond, state.Happiness, tt.wantBond, tt.wantHappiness)
+			}
+			if state.CareMetrics.TotalInteractions != interactions+1 || !state.LastChatTime.Equal(now) {
+				t.Errorf("interaction not recorded: %+v, lastChat %v", state.CareMetrics, state.LastChatTime)
+			}
+		})
+	}
+
+	t.Run("cooldown", func(t *testing.T) {
+		state := awake()
+		processActionChat(&state, 1, now)
+		after := state
+		processActionChat(&state, 1, now.Add(z.CooldownChat/2))
+		if state.Bond != after.Bond || state.CareMetrics.TotalInteractions != after.CareMetrics.TotalInteractions {
+			t.Errorf("chat during cooldown changed state: bond %v -> %v", after.Bond, state.Bond)
+		}
+		processActionChat(&state, 1, now.Add(z.CooldownChat))
+		if state.Bond <= after.Bond {
+			t.Errorf("chat after cooldown had no effect: bond %v", state.Bond)
+		}
+	})
+}
@@ -5,16 +5,26 @@ import (
 
 	"ziggy/internal/ai"
 	"ziggy/internal/registry"
+	"ziggy/internal/workflow/budget"
 )
 
+// WorkflowID returns the ID of an owner's ZiggyWorkflow.
+func WorkflowID(owner string) string {
+	return fmt.Sprintf("ziggy-%s", owner)
+}
+
+// ChatWorkflowID returns the ID of an owner's ChatWorkflow, which gets pet
+// updates. It lives here because the chat package imports this one.
+func ChatWorkflowID(owner string) string {
+	return fmt.Sprintf("ziggy-chat-%s", owner)
+}
+
 func Register() {
 	// Register workflow (Weight 100 ensures dependent workflows start first)
 	registry.RegisterWorkflow(registry.Definition{
-		Name:     "ZiggyWorkflow",
-		Workflow: Workflow,
-		IDPattern: func(owner string) string {
-			return fmt.Sprintf("ziggy-%s", owner)
-		},
+		Name:      "ZiggyWorkflow",
+		Workflow:  Workflow,
+		IDPattern: WorkflowID,
 		NewInput: func(owner, _, tz string) any {
 			return Input{Owner: owner, Timezone: tz, Generation: 1}
 		},
@@ -24,8 +34,7 @@ func Register() {
 	})
 
 	// Register activities
-	aiClient := ai.NewClient()
-	activities := NewActivities(aiClient)
+	activities := NewActivities(ai.NewCachingProvider(ai.NewModeratedProvider(budget.Metered(ai.NewProvider()), ai.ModeratorFromEnv()), ai.PoolCacheConfigFromEnv()))
 	registry.RegisterActivity(registry.ActivityDef{
 		Name:     "ProcessAction",
 		Activity: activities.ProcessAction,
@@ -0,0 +1,22 @@
+package ziggy
+
+// Change IDs for workflow.GetVersion. Each guards a behavior change so
+// executions started by an older build still replay deterministically.
+// Never rename or delete an ID while such executions may still be open.
+const (
+	// Lifecycle events are signalled to the webhook workflow, with a timer to
+	// catch decay-driven mood changes.
+	changeWebhookEvents = "webhook-events"
+
+	// Starting state is normalized to the current schema (stage synced to
+	// age) before the workflow acts on it.
+	changeStateSchema = "state-schema"
+
+	// Mood, need and return updates are signalled to the owner's chat
+	// workflow, which may speak first.
+	changePetUpdates = "pet-updates"
+
+	// The mood timer is set for the next decay-driven change instead of
+	// polling every MoodCheckInterval.
+	changeMoodTimer = "mood-timer"
+)
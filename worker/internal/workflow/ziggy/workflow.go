@@ -6,6 +6,8 @@ import (
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
+	"ziggy/internal/locale"
+	"ziggy/internal/workflow/webhook"
 	z "ziggy/internal/ziggy"
 )
 
@@ -15,6 +17,16 @@ const (
 	SignalPet  = "pet"
 	SignalWake = "wake"
 
+	// SignalChatInteraction is sent by the chat workflow for each exchange.
+	SignalChatInteraction = "chat_interaction"
+
+	// SignalSetLocale changes the owner's language.
+	SignalSetLocale = "set_locale"
+
+	// SignalPetUpdate is sent to the owner's chat workflow with a
+	// PetUpdateSignal.
+	SignalPetUpdate = "pet_update"
+
 	QueryState = "state"
 
 	SignalUpdateNeedMessage = "updateNeedMessage"
@@ -22,6 +34,32 @@ const (
 
 	PoolRegenerationInterval = 6 * time.Hour
 	PoolRegenerationCooldown = 10 * time.Minute
+
+	// ContinueAsNewHistoryLength is the history size at which the workflow
+	// hands its full state to a fresh run.
+	ContinueAsNewHistoryLength = 10000
+
+	// MoodCheckInterval is how often executions started before the mood
+	// timer poll for decay-driven transitions (like slipping into tun).
+	MoodCheckInterval = time.Minute
+
+	// MaxMoodCheckInterval caps how far ahead the mood timer is set when
+	// decay changes nothing sooner.
+	MaxMoodCheckInterval = 6 * time.Hour
+
+	// ReturnAfter is how long the owner must have been away for their next
+	// care action to count as coming back.
+	ReturnAfter = 4 * time.Hour
+)
+
+// Pet update kinds.
+const (
+	// UpdateMood is sent at startup and when the mood changes.
+	UpdateMood = "mood"
+	// UpdateNeed is sent when the most urgent need changes.
+	UpdateNeed = "need"
+	// UpdateReturned is sent when the owner comes back after ReturnAfter.
+	UpdateReturned = "returned"
 )
 
 type Input struct {
@@ -29,6 +67,10 @@ type Input struct {
 	Timezone   string    `json:"timezone"`
 	Generation int       `json:"generation"`
 	CreatedAt  time.Time `json:"createdAt,omitempty"`
+
+	// Snapshot restores a previously saved state (e.g. from an imported
+	// save file) instead of hatching a new egg.
+	Snapshot *z.State `json:"snapshot,omitempty"`
 }
 
 type UpdateNeedMessageSignal struct {
@@ -36,15 +78,40 @@ type UpdateNeedMessageSignal struct {
 	Personality z.Personality `json:"personality,omitempty"`
 }
 
+type ChatInteractionSignal struct {
+	// Sentiment scores the owner's message from -1 (rude) to 1 (kind); 0
+	// when unknown.
+	Sentiment float64 `json:"sentiment,omitempty"`
+}
+
+// SetLocaleSignal names a supported locale.
+type SetLocaleSignal struct {
+	Locale string `json:"locale"`
+}
+
+// PetUpdateSignal tells the chat workflow what changed. Mood and Need are
+// the current ones, whatever the kind.
+type PetUpdateSignal struct {
+	Kind     string     `json:"kind"`
+	Mood     z.Mood     `json:"mood"`
+	Need     z.NeedType `json:"need,omitempty"`
+	Timezone string     `json:"timezone"`
+	// Away is how long the owner was gone, for UpdateReturned.
+	Away time.Duration `json:"away,omitempty"`
+}
+
 type PoolRegenerationOutput struct {
 	Pool        *z.MessagePool `json:"pool"`
 	GeneratedAt time.Time      `json:"generatedAt"`
+	// Locale is the language the pool was written in.
+	Locale string `json:"locale,omitempty"`
 }
 
 type PoolRegenerateSignal struct {
 	Personality z.Personality `json:"personality"`
 	Stage       z.Stage       `json:"stage"`
 	Bond        float64       `json:"bond"`
+	Locale      string        `json:"locale,omitempty"`
 }
 
 func Workflow(ctx workflow.Context, input Input) error {
@@ -64,6 +131,19 @@ func Workflow(ctx workflow.Context, input Input) error {
 	if !input.CreatedAt.IsZero() {
 		state.CreatedAt = input.CreatedAt
 	}
+	if input.Snapshot != nil {
+		state = *input.Snapshot
+		if state.Timezone == "" {
+			state.Timezone = timezone
+		}
+		if input.Generation > state.Generation {
+			state.Generation = input.Generation
+		}
+		logger.Info("Restored state from snapshot", "generation", state.Generation, "schemaVersion", state.SchemaVersion)
+	}
+	if workflow.GetVersion(ctx, changeStateSchema, workflow.DefaultVersion, 1) >= 1 {
+		state.Normalize(workflow.Now(ctx))
+	}
 
 	err := workflow.SetQueryHandler(ctx, QueryState, func() (z.State, error) {
 		return state, nil
@@ -86,34 +166,103 @@ func Workflow(ctx workflow.Context, input Input) error {
 	}
 	actCtx := workflow.WithActivityOptions(ctx, activityOpts)
 
-	processAction := func(action z.Action) {
-		input := ProcessActionInput{
-			State:  state,
-			Action: action,
-			Now:    workflow.Now(ctx),
+	workflowID := workflow.GetInfo(ctx).WorkflowExecution.ID
+
+	// Executions started before webhooks existed must replay without the
+	// extra signals and timers.
+	webhooksEnabled := workflow.GetVersion(ctx, changeWebhookEvents, workflow.DefaultVersion, 1) == 1
+	emitter := webhook.NewEmitter(workflowID)
+	emit := func(t webhook.EventType, data map[string]any) {
+		if webhooksEnabled {
+			emitter.Emit(ctx, t, data)
+		}
+	}
+
+	// Executions started before pet updates existed must replay without
+	// the signals to the chat workflow.
+	updatesEnabled := workflow.GetVersion(ctx, changePetUpdates, workflow.DefaultVersion, 1) == 1
+	chatWorkflowID := ChatWorkflowID(input.Owner)
+	notifyChat := func(kind string, away time.Duration) {
+		if !updatesEnabled {
+			return
 		}
+		current := state.CalculateCurrentState(workflow.Now(ctx))
+		workflow.SignalExternalWorkflow(ctx, chatWorkflowID, "", SignalPetUpdate, PetUpdateSignal{
+			Kind:     kind,
+			Mood:     current.GetMood(),
+			Need:     current.GetMostUrgentNeed(),
+			Timezone: state.Timezone,
+			Away:     away,
+		})
+	}
+
+	// runAction applies input to state and reports whether it succeeded
+	runAction := func(input ProcessActionInput) bool {
+		input.State = state
+		input.Now = workflow.Now(ctx)
 		var output ProcessActionOutput
 		err := workflow.ExecuteActivity(actCtx, "ProcessAction", input).Get(ctx, &output)
 		if err != nil {
-			logger.Info("ProcessAction failed", "action", action, "error", err.Error())
-			return
+			logger.Info("ProcessAction failed", "action", input.Action, "error", err.Error())
+			return false
 		}
 		state = output.State
+		return true
+	}
+
+	processAction := func(action z.Action) {
+		lastSeen := state.CareMetrics.LastInteractionAt
+		if !runAction(ProcessActionInput{Action: action}) {
+			return
+		}
+		if away := workflow.Now(ctx).Sub(lastSeen); !lastSeen.IsZero() && away >= ReturnAfter {
+			notifyChat(UpdateReturned, away)
+		}
+
+		emit(webhook.EventAction, map[string]any{
+			"action":  action,
+			"message": state.Message,
+			"mood":    state.GetMood(),
+		})
 	}
 
 	regeneratePool("startup")
+	notifyChat(UpdateMood, 0)
 
 	feedCh := workflow.GetSignalChannel(ctx, SignalFeed)
 	playCh := workflow.GetSignalChannel(ctx, SignalPlay)
 	petCh := workflow.GetSignalChannel(ctx, SignalPet)
 	wakeCh := workflow.GetSignalChannel(ctx, SignalWake)
+	chatCh := workflow.GetSignalChannel(ctx, SignalChatInteraction)
+	localeCh := workflow.GetSignalChannel(ctx, SignalSetLocale)
 	needMsgCh := workflow.GetSignalChannel(ctx, SignalUpdateNeedMessage)
 	poolResultCh := workflow.GetSignalChannel(ctx, SignalPoolResult)
 
-	for {
+	// The mood timer fires when decay next changes the mood, the need or
+	// the stage. Executions started before it poll every MoodCheckInterval.
+	moodTimerEnabled := workflow.GetVersion(ctx, changeMoodTimer, workflow.DefaultVersion, 1) == 1
+	var moodTimer workflow.Future
+	var moodTimerAt time.Time
+	cancelMoodTimer := func() {}
+	armMoodTimer := func() {
+		now := workflow.Now(ctx)
+		at := nextMoodCheck(&state, now)
+		if moodTimer != nil && at.Equal(moodTimerAt) {
+			return
+		}
+		cancelMoodTimer()
+		timerCtx, cancel := workflow.WithCancel(ctx)
+		moodTimer = workflow.NewTimer(timerCtx, at.Sub(now))
+		moodTimerAt, cancelMoodTimer = at, cancel
+	}
+	if webhooksEnabled && !moodTimerEnabled {
+		moodTimer = workflow.NewTimer(ctx, MoodCheckInterval)
+	}
+
+	// newSignalSelector wires every signal handler; it is also used to drain
+	// buffered signals before continuing as new.
+	newSignalSelector := func() workflow.Selector {
 		selector := workflow.NewSelector(ctx)
-		prevPersonality := state.Personality
-		prevStage := z.GetStageForAge(workflow.Now(ctx).Sub(state.CreatedAt).Seconds())
 
 		selector.AddReceive(feedCh, func(c workflow.ReceiveChannel, more bool) {
 			var signal struct{}
@@ -139,6 +288,32 @@ func Workflow(ctx workflow.Context, input Input) error {
 			processAction(z.ActionWake)
 		})
 
+		// Chat already emits chat_message webhooks, so no action event here
+		selector.AddReceive(chatCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal ChatInteractionSignal
+			c.Receive(ctx, &signal)
+			runAction(ProcessActionInput{Action: z.ActionChat, Sentiment: signal.Sentiment})
+		})
+
+		// A new language replaces the generated pool, which is in the old one
+		selector.AddReceive(localeCh, func(c workflow.ReceiveChannel, more bool) {
+			var signal SetLocaleSignal
+			c.Receive(ctx, &signal)
+			if !locale.Valid(signal.Locale) {
+				logger.Info("Ignoring unsupported locale", "locale", signal.Locale)
+				return
+			}
+			code := locale.Normalize(signal.Locale)
+			if code == locale.Normalize(state.Locale) {
+				return
+			}
+			state.Locale = code
+			state.RuntimePool = nil
+			state.PoolGeneratedAt = time.Time{}
+			logger.Info("Locale changed", "locale", code)
+			regeneratePool("locale_change")
+		})
+
 		selector.AddReceive(needMsgCh, func(c workflow.ReceiveChannel, more bool) {
 			var signal UpdateNeedMessageSignal
 			c.Receive(ctx, &signal)
@@ -161,7 +336,9 @@ func Workflow(ctx workflow.Context, input Input) error {
 		selector.AddReceive(poolResultCh, func(c workflow.ReceiveChannel, more bool) {
 			var result PoolRegenerationOutput
 			c.Receive(ctx, &result)
-			if result.Pool != nil {
+			if result.Pool != nil && locale.Normalize(result.Locale) != locale.Normalize(state.Locale) {
+				logger.Info("Discarding pool in another locale", "locale", result.Locale)
+			} else if result.Pool != nil {
 				state.RuntimePool = result.Pool
 				state.PoolGeneratedAt = result.GeneratedAt
 				logger.Info("Pool updated from regenerator workflow")
@@ -170,10 +347,39 @@ func Workflow(ctx workflow.Context, input Input) error {
 			}
 		})
 
+		return selector
+	}
+
+	for {
+		if webhooksEnabled && moodTimerEnabled {
+			// Actions change where decay is heading, so re-aim the timer
+			armMoodTimer()
+		}
+
+		prevPersonality := state.Personality
+		prevStage := z.GetStageForAge(workflow.Now(ctx).Sub(state.CreatedAt).Seconds())
+		prevMood := currentMood(ctx, &state)
+		prevNeed := currentNeed(ctx, &state)
+
+		selector := newSignalSelector()
+		if moodTimer != nil {
+			selector.AddFuture(moodTimer, func(f workflow.Future) {
+				if moodTimerEnabled {
+					moodTimer = nil
+				} else {
+					moodTimer = workflow.NewTimer(ctx, MoodCheckInterval)
+				}
+			})
+		}
+
 		selector.Select(ctx)
 
 		if state.Personality != prevPersonality {
 			logger.Info("Personality changed", "from", prevPersonality, "to", state.Personality)
+			emit(webhook.EventPersonalityChange, map[string]any{
+				"from": prevPersonality,
+				"to":   state.Personality,
+			})
 			regeneratePool("personality_change")
 		}
 
@@ -181,21 +387,90 @@ func Workflow(ctx workflow.Context, input Input) error {
 		if currentStage != prevStage {
 			logger.Info("Stage changed", "from", prevStage, "to", currentStage)
 			state.Stage = currentStage
+			emit(webhook.EventStageChange, map[string]any{
+				"from": prevStage,
+				"to":   currentStage,
+			})
 			regeneratePool("stage_change")
 		}
 
-		if workflow.GetInfo(ctx).GetCurrentHistoryLength() > 10000 {
+		if mood := currentMood(ctx, &state); mood != prevMood {
+			emitMoodChange(emit, prevMood, mood)
+			notifyChat(UpdateMood, 0)
+		} else if currentNeed(ctx, &state) != prevNeed {
+			notifyChat(UpdateNeed, 0)
+		}
+
+		if shouldContinueAsNew(ctx) {
 			logger.Info("Continuing as new due to history length")
+
+			// Signals already buffered would be lost with this run, so apply
+			// them before snapshotting.
+			drain := newSignalSelector()
+			for drain.HasPending() {
+				drain.Select(ctx)
+			}
+
+			snapshot := state
 			return workflow.NewContinueAsNewError(ctx, Workflow, Input{
 				Owner:      input.Owner,
 				Timezone:   input.Timezone,
-				Generation: state.Generation + 1,
+				// Continuing as new is the same pet, not a rebirth
+				Generation: state.Generation,
 				CreatedAt:  state.CreatedAt,
+				Snapshot:   &snapshot,
 			})
 		}
 	}
 }
 
+// nextMoodCheck is when decay next changes Ziggy's mood or need, or Ziggy
+// grows into the next stage, and at most MaxMoodCheckInterval away.
+func nextMoodCheck(state *z.State, now time.Time) time.Time {
+	limit := now.Add(MaxMoodCheckInterval)
+	for _, age := range []float64{z.AgeEggTooBaby, z.AgeBabyToTeen, z.AgeTeenToAdult, z.AgeAdultToElder} {
+		grown := state.CreatedAt.Add(time.Duration(age * float64(time.Second)))
+		if grown.After(now) {
+			if grown.Before(limit) {
+				limit = grown
+			}
+			break
+		}
+	}
+	return state.NextChange(now, limit)
+}
+
+// currentMood applies decay up to workflow time, since stats in workflow state
+// are only brought up to date when an action is processed.
+func currentMood(ctx workflow.Context, state *z.State) z.Mood {
+	current := state.CalculateCurrentState(workflow.Now(ctx))
+	return current.GetMood()
+}
+
+// currentNeed is the most urgent need after decay up to workflow time.
+func currentNeed(ctx workflow.Context, state *z.State) z.NeedType {
+	current := state.CalculateCurrentState(workflow.Now(ctx))
+	return current.GetMostUrgentNeed()
+}
+
+func emitMoodChange(emit func(webhook.EventType, map[string]any), from, to z.Mood) {
+	emit(webhook.EventMoodChange, map[string]any{
+		"from": from,
+		"to":   to,
+	})
+	if to == z.MoodTun {
+		emit(webhook.EventTunEnter, nil)
+	} else if from == z.MoodTun {
+		emit(webhook.EventTunExit, map[string]any{"mood": to})
+	}
+}
+
+// shouldContinueAsNew is a variable so tests can force continue-as-new; the
+// test environment does not track history length.
+var shouldContinueAsNew = func(ctx workflow.Context) bool {
+	return workflow.GetInfo(ctx).GetCurrentHistoryLength() > ContinueAsNewHistoryLength
+}
+
 const (
 	NeedMessageDelay      = 30 * time.Second
 	SignalPoolRegenerate  = "pool_regenerate"
@@ -221,6 +496,7 @@ func triggerPoolRegeneration(ctx workflow.Context, state *z.State, logger interf
 		Personality: state.Personality,
 		Stage:       z.GetStageForAge(age),
 		Bond:        state.Bond,
+		Locale:      state.Locale,
 	}
 
 	workflow.SignalExternalWorkflow(ctx, poolWorkflowID, "", SignalPoolRegenerate, signal)
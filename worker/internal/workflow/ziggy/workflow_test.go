@@ -0,0 +1,240 @@
+package ziggy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	z "ziggy/internal/ziggy"
+)
+
+func newTestEnv(t *testing.T, start time.Time) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(start)
+
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessAction, activity.RegisterOptions{Name: "ProcessAction"})
+
+	// Pool regeneration and webhook events go to other workflows
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return env
+}
+
+func continuedInput(t *testing.T, env *testsuite.TestWorkflowEnvironment) Input {
+	t.Helper()
+
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow did not complete")
+	}
+	var can *workflow.ContinueAsNewError
+	if err := env.GetWorkflowError(); !errors.As(err, &can) {
+		t.Fatalf("workflow error = %v, want continue-as-new", err)
+	}
+
+	var next Input
+	if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+func TestContinueAsNewCarriesFullState(t *testing.T) {
+	defer func(f func(workflow.Context) bool) { shouldContinueAsNew = f }(shouldContinueAsNew)
+	shouldContinueAsNew = func(workflow.Context) bool { return true }
+
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := z.NewState("Europe/Berlin")
+	snapshot.CreatedAt = start.Add(-2 * time.Hour)
+	snapshot.LastUpdateTime = start
+	snapshot.Fullness = 70
+	snapshot.Happiness = 80
+	snapshot.Bond = 80
+	snapshot.HP = 90
+	snapshot.Generation = 3
+	snapshot.Personality = z.PersonalityCheerful
+	snapshot.CareMetrics = z.CareMetrics{
+		TotalInteractions: 40,
+		LastInteractionAt: start.Add(-time.Minute),
+		AvgFullness:       70,
+		AvgBond:           80,
+	}
+	snapshot.RuntimePool = &z.MessagePool{FeedSuccess: []string{"*munch*"}}
+	snapshot.PoolGeneratedAt = start.Add(-time.Minute)
+
+	env := newTestEnv(t, start)
+	// Both signals are buffered when the first one wakes the workflow, so the
+	// second must be drained before continuing as new.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalFeed, struct{}{})
+		env.SignalWorkflow(SignalPet, struct{}{})
+	}, 30*time.Second)
+	env.ExecuteWorkflow(Workflow, Input{
+		Owner:      "test",
+		Timezone:   snapshot.Timezone,
+		Generation: snapshot.Generation,
+		CreatedAt:  snapshot.CreatedAt,
+		Snapshot:   &snapshot,
+	})
+
+	next := continuedInput(t, env)
+	if next.Snapshot == nil {
+		t.Fatal("continue-as-new input has no snapshot")
+	}
+	got := *next.Snapshot
+	actedAt := start.Add(30 * time.Second)
+
+	if next.Generation != 3 || got.Generation != 3 {
+		t.Errorf("generation = input %d / state %d, want 3 / 3", next.Generation, got.Generation)
+	}
+	if !got.CreatedAt.Equal(snapshot.CreatedAt) {
+		t.Errorf("createdAt = %v, want %v", got.CreatedAt, snapshot.CreatedAt)
+	}
+	if !got.LastFeedTime.Equal(actedAt) || !got.LastPetTime.Equal(actedAt) {
+		t.Errorf("cooldowns = feed %v / pet %v, want both %v", got.LastFeedTime, got.LastPetTime, actedAt)
+	}
+	if got.CareMetrics.TotalInteractions != 42 {
+		t.Errorf("totalInteractions = %d, want 42", got.CareMetrics.TotalInteractions)
+	}
+	if got.Personality != z.PersonalityCheerful {
+		t.Errorf("personality = %s, want %s", got.Personality, z.PersonalityCheerful)
+	}
+	if got.Fullness < 90 || got.Bond < 90 {
+		t.Errorf("stats reset: fullness %.1f, bond %.1f", got.Fullness, got.Bond)
+	}
+	if got.RuntimePool == nil || len(got.RuntimePool.FeedSuccess) != 1 {
+		t.Errorf("runtime pool not carried: %+v", got.RuntimePool)
+	}
+	if got.Timezone != "Europe/Berlin" || next.Timezone != "Europe/Berlin" {
+		t.Errorf("timezone = %q / %q", got.Timezone, next.Timezone)
+	}
+
+	// The next run must resume from the snapshot rather than a fresh egg
+	resumed := newTestEnv(t, actedAt)
+	var queried z.State
+	resumed.RegisterDelayedCallback(func() {
+		result, err := resumed.QueryWorkflow(QueryState)
+		if err != nil {
+			t.Errorf("query state: %v", err)
+		} else if err := result.Get(&queried); err != nil {
+			t.Errorf("decode state: %v", err)
+		}
+		resumed.SignalWorkflow(SignalWake, struct{}{})
+	}, time.Second)
+	resumed.ExecuteWorkflow(Workflow, next)
+	continuedInput(t, resumed)
+
+	if queried.Generation != 3 {
+		t.Errorf("resumed generation = %d, want 3", queried.Generation)
+	}
+	if queried.Fullness != got.Fullness || queried.Bond != got.Bond || queried.HP != got.HP {
+		t.Errorf("resumed stats = %.1f/%.1f/%.1f, want %.1f/%.1f/%.1f",
+			queried.Fullness, queried.Bond, queried.HP, got.Fullness, got.Bond, got.HP)
+	}
+	if queried.CareMetrics != got.CareMetrics {
+		t.Errorf("resumed care metrics = %+v, want %+v", queried.CareMetrics, got.CareMetrics)
+	}
+	if queried.Stage != z.StageElder {
+		t.Errorf("resumed stage = %s, want %s", queried.Stage, z.StageElder)
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	done := false
+	defer func(f func(workflow.Context) bool) { shouldContinueAsNew = f }(shouldContinueAsNew)
+	shouldContinueAsNew = func(workflow.Context) bool { return done }
+
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := z.NewState("UTC")
+	snapshot.CreatedAt = start
+	snapshot.LastUpdateTime = start
+	snapshot.RuntimePool = &z.MessagePool{FeedSuccess: []string{"*munch*"}}
+	snapshot.PoolGeneratedAt = start
+
+	env := newTestEnv(t, start)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalSetLocale, SetLocaleSignal{Locale: "xx"})
+		env.SignalWorkflow(SignalSetLocale, SetLocaleSignal{Locale: "JA"})
+		// A pool generated before the change is still in English
+		env.SignalWorkflow(SignalPoolResult, PoolRegenerationOutput{Pool: &z.MessagePool{FeedSuccess: []string{"*nom*"}}, GeneratedAt: start})
+		env.SignalWorkflow(SignalFeed, struct{}{})
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		done = true
+		env.SignalWorkflow(SignalWake, struct{}{})
+	}, 2*time.Second)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", Snapshot: &snapshot})
+
+	got := continuedInput(t, env).Snapshot
+	if got == nil {
+		t.Fatal("continue-as-new input has no snapshot")
+	}
+	if got.Locale != "ja" {
+		t.Errorf("locale = %q, want ja", got.Locale)
+	}
+	if got.RuntimePool != nil {
+		t.Errorf("runtime pool = %+v, want it dropped for the new language", got.RuntimePool)
+	}
+	if want := z.Text("ja", z.MessageHatching); got.Message != want {
+		t.Errorf("message = %q, want %q", got.Message, want)
+	}
+}
+
+func TestPetUpdatesReachChat(t *testing.T) {
+	done := false
+	defer func(f func(workflow.Context) bool) { shouldContinueAsNew = f }(shouldContinueAsNew)
+	shouldContinueAsNew = func(workflow.Context) bool { return done }
+
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := z.NewState("Europe/Berlin")
+	snapshot.CreatedAt = start.Add(-2 * time.Hour)
+	snapshot.LastUpdateTime = start
+	snapshot.CareMetrics.LastInteractionAt = start.Add(-5 * time.Hour)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(start)
+	activities := NewActivities(nil)
+	env.RegisterActivityWithOptions(activities.ProcessAction, activity.RegisterOptions{Name: "ProcessAction"})
+
+	var updates []PetUpdateSignal
+	env.OnSignalExternalWorkflow(mock.Anything, ChatWorkflowID("test"), "", SignalPetUpdate, mock.Anything).Return(
+		func(_ string, _ string, _ string, _ string, arg interface{}) error {
+			updates = append(updates, arg.(PetUpdateSignal))
+			return nil
+		})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalFeed, struct{}{})
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		done = true
+		env.SignalWorkflow(SignalPet, struct{}{})
+	}, time.Minute+time.Second)
+	env.ExecuteWorkflow(Workflow, Input{Owner: "test", Snapshot: &snapshot})
+	continuedInput(t, env)
+
+	// The run announces itself, then the feed after five hours away is a
+	// return; the pet a minute later is not
+	if len(updates) < 2 || updates[0].Kind != UpdateMood || updates[0].Timezone != "Europe/Berlin" {
+		t.Fatalf("updates = %+v", updates)
+	}
+	var returned []PetUpdateSignal
+	for _, u := range updates {
+		if u.Kind == UpdateReturned {
+			returned = append(returned, u)
+		}
+	}
+	if len(returned) != 1 || returned[0].Away != 5*time.Hour+time.Second {
+		t.Errorf("returned updates = %+v", returned)
+	}
+}
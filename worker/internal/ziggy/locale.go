@@ -0,0 +1,141 @@
+package ziggy
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"ziggy/internal/locale"
+)
+
+// System messages shown outside the message pools.
+const (
+	MessageHatching      = "hatching"
+	MessageWake          = "wake"
+	MessageSearchingDocs = "searchingDocs"
+)
+
+var systemMessages = map[string]string{
+	MessageHatching:      "*wiggle*\n*wiggle*\nStill hatching...",
+	MessageWake:          "*yawn*\nI was having\nsuch a nice dream...",
+	MessageSearchingDocs: "Searching the Temporal docs...",
+}
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// translation is a locale file: system messages, a generic pool covering
+// every category, and optional per-personality pools drawn from first.
+type translation struct {
+	Messages map[string]string       `yaml:"messages"`
+	Pools    map[string]*MessagePool `yaml:"pools"`
+}
+
+var translations = map[string]*translation{}
+
+func init() {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		code := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		t, err := parseTranslation(code, data)
+		if err != nil {
+			panic(err)
+		}
+		translations[code] = t
+	}
+}
+
+// parseTranslation reads a locale file and checks every line fits the
+// display.
+func parseTranslation(code string, data []byte) (*translation, error) {
+	if !locale.Valid(code) {
+		return nil, fmt.Errorf("locale %q: unsupported", code)
+	}
+	var t translation
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("locale %q: %w", code, err)
+	}
+
+	var problems []string
+	for key, text := range t.Messages {
+		if _, ok := systemMessages[key]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown message %q", key))
+		} else if !locale.FitsDisplay(text) {
+			problems = append(problems, fmt.Sprintf("message %q does not fit the display", key))
+		}
+	}
+	if t.Pools["generic"] == nil {
+		problems = append(problems, "no generic pool")
+	}
+	for name, pool := range t.Pools {
+		if name != "generic" && !isPersonality(Personality(name)) {
+			problems = append(problems, fmt.Sprintf("unknown pool %q", name))
+			continue
+		}
+		v := reflect.ValueOf(pool).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			category := v.Type().Field(i).Tag.Get("yaml")
+			lines := v.Field(i).Interface().([]string)
+			if name == "generic" && len(lines) == 0 {
+				problems = append(problems, fmt.Sprintf("generic pool has no %s", category))
+			}
+			for _, line := range lines {
+				if !locale.FitsDisplay(line) {
+					problems = append(problems, fmt.Sprintf("%s %s line %q does not fit the display", name, category, line))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("locale %q: %s", code, strings.Join(problems, "; "))
+	}
+	return &t, nil
+}
+
+func isPersonality(p Personality) bool {
+	_, ok := fallbackPools[p]
+	return ok
+}
+
+// FallbackPools returns the built-in pools for a locale: one for the
+// personality, and a generic one to fill its gaps. Locales without
+// translated pools get the English ones.
+func FallbackPools(code string, p Personality) (fallback, generic *MessagePool) {
+	t := translations[locale.Normalize(code)]
+	if t == nil || t.Pools["generic"] == nil {
+		return GetFallbackPool(p), GetFallbackPool(PersonalityStoic)
+	}
+	return t.Pools[string(p)], t.Pools["generic"]
+}
+
+// Text returns a system message in the locale, or in English when it has
+// no translation.
+func Text(code, key string) string {
+	if t := translations[locale.Normalize(code)]; t != nil {
+		if text, ok := t.Messages[key]; ok {
+			return text
+		}
+	}
+	return systemMessages[key]
+}
+
+// PoolSelector picks from the pet's generated pool, then the built-in pools
+// for its personality and locale.
+func (s *ZiggyState) PoolSelector() *PoolSelector {
+	fallback, generic := FallbackPools(s.Locale, s.Personality)
+	return NewPoolSelector(s.RuntimePool, fallback, generic)
+}
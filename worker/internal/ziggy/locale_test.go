@@ -0,0 +1,63 @@
+package ziggy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFallbackPoolsByLocale(t *testing.T) {
+	for _, code := range []string{"", "en", "fr"} {
+		fallback, generic := FallbackPools(code, PersonalitySassy)
+		if fallback != poolSassy || generic != poolStoic {
+			t.Errorf("FallbackPools(%q) are not the English pools", code)
+		}
+	}
+
+	fallback, generic := FallbackPools("es", PersonalitySassy)
+	if fallback == nil || fallback.FeedSuccess[0] != "Ya era hora.\nMe estaba\nconsumiendo." {
+		t.Errorf("es sassy pool = %+v", fallback)
+	}
+
+	// Categories the personality pool lacks come from the locale's generic
+	// pool, never from English
+	state := NewState("UTC")
+	state.Locale = "ja"
+	state.Personality = PersonalityShy
+	_, generic = FallbackPools("ja", PersonalityShy)
+	got := state.PoolSelector().Pick("needsFood")
+	if !strings.Contains(strings.Join(generic.NeedsFood, "|"), got) || got == "" {
+		t.Errorf("ja needsFood = %q", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	if got := Text("", MessageWake); got != "*yawn*\nI was having\nsuch a nice dream..." {
+		t.Errorf("Text(en) = %q", got)
+	}
+	if got := Text("es", MessageHatching); !strings.Contains(got, "Eclosionando") {
+		t.Errorf("Text(es) = %q", got)
+	}
+	if got := Text("xx", MessageHatching); got != systemMessages[MessageHatching] {
+		t.Errorf("Text(xx) = %q", got)
+	}
+}
+
+func TestParseTranslation(t *testing.T) {
+	tests := []struct {
+		name, code, data, want string
+	}{
+		{"unsupported locale", "xx", "pools: {}", "unsupported"},
+		{"unknown category", "es", "pools:\n  generic:\n    feedSucess: [hola]", "feedSucess"},
+		{"no generic pool", "es", "messages:\n  wake: hola", "no generic pool"},
+		{"unknown message", "es", "messages:\n  sneeze: achís", `unknown message "sneeze"`},
+		{"unknown personality", "es", "pools:\n  grumpy:\n    feedSuccess: [hola]", `unknown pool "grumpy"`},
+		// Twelve kanji fill 24 columns; thirteen overflow them
+		{"wide line", "ja", "messages:\n  wake: 一二三四五六七八九十一二三", `message "wake" does not fit`},
+	}
+	for _, tt := range tests {
+		_, err := parseTranslation(tt.code, []byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ziggy
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CurrentSchemaVersion is the ZiggyState layout written by this build.
+// Bump it and register a migration whenever a field is renamed, removed or
+// needs a non-zero default for payloads written by older builds.
+const CurrentSchemaVersion = 1
+
+// Migration upgrades a raw JSON state from version N to N+1 in place.
+type Migration func(raw map[string]any) error
+
+var migrations = map[int]Migration{
+	0: migrateV0,
+}
+
+// RegisterMigration adds the upgrade step from version `from` to from+1.
+func RegisterMigration(from int, m Migration) {
+	migrations[from] = m
+}
+
+// MigrateStateJSON upgrades a serialized ZiggyState to CurrentSchemaVersion.
+// Payloads from a newer build are returned unchanged.
+func MigrateStateJSON(data []byte) ([]byte, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	version := schemaVersionOf(raw)
+	if version >= CurrentSchemaVersion {
+		return data, nil
+	}
+
+	for v := version; v < CurrentSchemaVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from schema version %d", v)
+		}
+		if err := m(raw); err != nil {
+			return nil, fmt.Errorf("migrate schema v%d: %w", v, err)
+		}
+		raw["schemaVersion"] = v + 1
+	}
+
+	return json.Marshal(raw)
+}
+
+func schemaVersionOf(raw map[string]any) int {
+	if v, ok := raw["schemaVersion"].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// UnmarshalJSON runs migrations on older payloads so every decode path
+// (queries, activity inputs, continue-as-new input, save files) sees the
+// current layout.
+func (s *ZiggyState) UnmarshalJSON(data []byte) error {
+	type plain ZiggyState
+
+	var probe struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+
+	if probe.SchemaVersion < CurrentSchemaVersion {
+		migrated, err := MigrateStateJSON(data)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// migrateV0 upgrades states written before schema versioning. Those builds
+// predate the personality system and need-based messages, so the fields may
+// be missing entirely.
+func migrateV0(raw map[string]any) error {
+	setDefault(raw, "personality", string(PersonalityShy))
+	setDefault(raw, "generation", 1)
+	setDefault(raw, "timezone", "America/Los_Angeles")
+	setDefault(raw, "stage", string(StageEgg))
+
+	if _, ok := raw["careMetrics"]; !ok {
+		fullness, _ := raw["fullness"].(float64)
+		bond, _ := raw["bond"].(float64)
+		raw["careMetrics"] = map[string]any{
+			"totalInteractions": 0,
+			"lastInteractionAt": raw["lastUpdateTime"],
+			"avgFullness":       fullness,
+			"avgBond":           bond,
+		}
+	}
+	return nil
+}
+
+func setDefault(raw map[string]any, key string, value any) {
+	if v, ok := raw[key]; !ok || v == nil || v == "" {
+		raw[key] = value
+	}
+}
+
+// Normalize reconciles fields the JSON migrations can't fix because they
+// depend on the current time, and stamps the current schema version.
+// Stage is otherwise only updated on transitions, so a restored or
+// continued-as-new state could carry a stale value.
+func (s *ZiggyState) Normalize(now time.Time) {
+	s.Stage = GetStageForAge(now.Sub(s.CreatedAt).Seconds())
+	s.SchemaVersion = CurrentSchemaVersion
+}
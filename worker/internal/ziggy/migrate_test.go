@@ -0,0 +1,101 @@
+package ziggy
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// TestStateMigrationGolden decodes historical payloads and compares the
+// upgraded state against golden files. Run with -update after adding a
+// migration, and review the diff.
+func TestStateMigrationGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/states/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no historical payloads found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var state ZiggyState
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if state.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("schemaVersion = %d, want %d", state.SchemaVersion, CurrentSchemaVersion)
+			}
+
+			got, err := json.MarshalIndent(state, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("migrated state differs from %s\ngot:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestMigrateV0FillsCareMetrics(t *testing.T) {
+	var state ZiggyState
+	err := json.Unmarshal([]byte(`{"fullness": 40, "bond": 30, "lastUpdateTime": "2025-01-01T00:00:00Z"}`), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Personality != PersonalityShy {
+		t.Errorf("personality = %q, want %q", state.Personality, PersonalityShy)
+	}
+	if state.Generation != 1 {
+		t.Errorf("generation = %d, want 1", state.Generation)
+	}
+	if state.CareMetrics.AvgFullness != 40 || state.CareMetrics.AvgBond != 30 {
+		t.Errorf("care metrics not seeded from stats: %+v", state.CareMetrics)
+	}
+}
+
+func TestCurrentStateRoundTrips(t *testing.T) {
+	state := NewState("UTC")
+	state.Personality = PersonalityDramatic
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded ZiggyState
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Personality != PersonalityDramatic || decoded.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("round trip changed state: %+v", decoded)
+	}
+}
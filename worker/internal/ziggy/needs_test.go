@@ -2,6 +2,7 @@ package ziggy
 
 import (
 	"testing"
+	"time"
 )
 
 func TestGetMostUrgentNeed(t *testing.T) {
@@ -99,6 +100,36 @@ func TestGetMostUrgentNeed(t *testing.T) {
 	}
 }
 
+func TestNextChange(t *testing.T) {
+	born := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	s := ZiggyState{
+		Fullness:       62,
+		Happiness:      80,
+		Bond:           80,
+		HP:             80,
+		CreatedAt:      born.Add(-time.Hour),
+		LastUpdateTime: born,
+	}
+	now := born.Add(15 * time.Second)
+	limit := now.Add(time.Hour)
+
+	at := s.NextChange(now, limit)
+	if !at.After(now) || !at.Before(limit) {
+		t.Fatalf("NextChange = %v, want a time within the hour", at)
+	}
+	before := s.CalculateCurrentState(at.Add(-time.Duration(DecayIntervalSeconds * float64(time.Second))))
+	after := s.CalculateCurrentState(at)
+	if before.GetMostUrgentNeed() != NeedNone || after.GetMostUrgentNeed() != NeedFood {
+		t.Errorf("need at %v = %v, a tick before = %v", at, after.GetMostUrgentNeed(), before.GetMostUrgentNeed())
+	}
+
+	tun := s
+	tun.HP = 0
+	if got := tun.NextChange(now, limit); !got.Equal(limit) {
+		t.Errorf("tun NextChange = %v, want limit", got)
+	}
+}
+
 func TestPoolSelectorPicksNeedMessages(t *testing.T) {
 	personalities := []Personality{
 		PersonalityStoic,
@@ -3,43 +3,43 @@ package ziggy
 import "math/rand"
 
 type MessagePool struct {
-	FeedSuccess  []string `json:"feedSuccess"`
-	FeedFull     []string `json:"feedFull"`
-	FeedHungry   []string `json:"feedHungry"`
-	FeedSleeping []string `json:"feedSleeping"`
-	FeedTun      []string `json:"feedTun"`
-	FeedCooldown []string `json:"feedCooldown"`
+	FeedSuccess  []string `json:"feedSuccess" yaml:"feedSuccess"`
+	FeedFull     []string `json:"feedFull" yaml:"feedFull"`
+	FeedHungry   []string `json:"feedHungry" yaml:"feedHungry"`
+	FeedSleeping []string `json:"feedSleeping" yaml:"feedSleeping"`
+	FeedTun      []string `json:"feedTun" yaml:"feedTun"`
+	FeedCooldown []string `json:"feedCooldown" yaml:"feedCooldown"`
 
-	PlaySuccess  []string `json:"playSuccess"`
-	PlayTired    []string `json:"playTired"`
-	PlayHappy    []string `json:"playHappy"`
-	PlaySleeping []string `json:"playSleeping"`
-	PlayTun      []string `json:"playTun"`
-	PlayCooldown []string `json:"playCooldown"`
+	PlaySuccess  []string `json:"playSuccess" yaml:"playSuccess"`
+	PlayTired    []string `json:"playTired" yaml:"playTired"`
+	PlayHappy    []string `json:"playHappy" yaml:"playHappy"`
+	PlaySleeping []string `json:"playSleeping" yaml:"playSleeping"`
+	PlayTun      []string `json:"playTun" yaml:"playTun"`
+	PlayCooldown []string `json:"playCooldown" yaml:"playCooldown"`
 
-	PetSuccess   []string `json:"petSuccess"`
-	PetMaxBond   []string `json:"petMaxBond"`
-	PetLowMood   []string `json:"petLowMood"`
-	PetSleeping  []string `json:"petSleeping"`
-	PetTun       []string `json:"petTun"`
-	PetCooldown  []string `json:"petCooldown"`
+	PetSuccess   []string `json:"petSuccess" yaml:"petSuccess"`
+	PetMaxBond   []string `json:"petMaxBond" yaml:"petMaxBond"`
+	PetLowMood   []string `json:"petLowMood" yaml:"petLowMood"`
+	PetSleeping  []string `json:"petSleeping" yaml:"petSleeping"`
+	PetTun       []string `json:"petTun" yaml:"petTun"`
+	PetCooldown  []string `json:"petCooldown" yaml:"petCooldown"`
 
-	Reviving []string `json:"reviving"`
+	Reviving []string `json:"reviving" yaml:"reviving"`
 
-	IdleHappy    []string `json:"idleHappy"`
-	IdleNeutral  []string `json:"idleNeutral"`
-	IdleHungry   []string `json:"idleHungry"`
-	IdleSad      []string `json:"idleSad"`
-	IdleLonely   []string `json:"idleLonely"`
-	IdleCritical []string `json:"idleCritical"`
-	IdleTun      []string `json:"idleTun"`
-	IdleSleeping []string `json:"idleSleeping"`
+	IdleHappy    []string `json:"idleHappy" yaml:"idleHappy"`
+	IdleNeutral  []string `json:"idleNeutral" yaml:"idleNeutral"`
+	IdleHungry   []string `json:"idleHungry" yaml:"idleHungry"`
+	IdleSad      []string `json:"idleSad" yaml:"idleSad"`
+	IdleLonely   []string `json:"idleLonely" yaml:"idleLonely"`
+	IdleCritical []string `json:"idleCritical" yaml:"idleCritical"`
+	IdleTun      []string `json:"idleTun" yaml:"idleTun"`
+	IdleSleeping []string `json:"idleSleeping" yaml:"idleSleeping"`
 
 	// Need-based coaxing messages
-	NeedsFood      []string `json:"needsFood"`
-	NeedsPlay      []string `json:"needsPlay"`
-	NeedsAffection []string `json:"needsAffection"`
-	NeedsCritical  []string `json:"needsCritical"`
+	NeedsFood      []string `json:"needsFood" yaml:"needsFood"`
+	NeedsPlay      []string `json:"needsPlay" yaml:"needsPlay"`
+	NeedsAffection []string `json:"needsAffection" yaml:"needsAffection"`
+	NeedsCritical  []string `json:"needsCritical" yaml:"needsCritical"`
 }
 
 type PoolSelector struct {
@@ -44,6 +44,7 @@ const (
 	ActionPlay Action = "play"
 	ActionPet  Action = "pet"
 	ActionWake Action = "wake"
+	ActionChat Action = "chat"
 )
 
 const (
@@ -69,9 +70,19 @@ const (
 	CooldownFeed = 30 * time.Second
 	CooldownPlay = 60 * time.Second
 	CooldownPet  = 10 * time.Second
+	CooldownChat = 60 * time.Second
+
+	// Chat interactions: a neutral message gives the base gains, and the
+	// model's sentiment score (-1 rude to 1 kind) moves each by up to
+	// ChatSentimentSwing.
+	ChatBondGain       = 2.0
+	ChatHappinessGain  = 3.0
+	ChatSentimentSwing = 5.0
 )
 
 type ZiggyState struct {
+	SchemaVersion int `json:"schemaVersion"`
+
 	Fullness  float64 `json:"fullness"`
 	Happiness float64 `json:"happiness"`
 	Bond      float64 `json:"bond"`
@@ -88,6 +99,9 @@ type ZiggyState struct {
 
 	Timezone   string `json:"timezone"`
 	Generation int    `json:"generation"`
+	// Locale is the owner's language for pools, chat and mysteries; empty
+	// means English.
+	Locale string `json:"locale,omitempty"`
 
 	Personality     Personality  `json:"personality"`
 	CareMetrics     CareMetrics  `json:"careMetrics"`
@@ -98,6 +112,7 @@ type ZiggyState struct {
 	LastFeedTime time.Time `json:"lastFeedTime,omitempty"`
 	LastPlayTime time.Time `json:"lastPlayTime,omitempty"`
 	LastPetTime  time.Time `json:"lastPetTime,omitempty"`
+	LastChatTime time.Time `json:"lastChatTime,omitempty"`
 }
 
 type ZiggyStateResponse struct {
@@ -116,6 +131,7 @@ type ZiggyStateResponse struct {
 
 	Age        float64 `json:"age"`
 	Generation int     `json:"generation"`
+	Locale     string  `json:"locale,omitempty"`
 
 	// Cooldown remaining in seconds (0 = ready)
 	FeedCooldown float64 `json:"feedCooldown"`
@@ -128,6 +144,7 @@ func NewZiggyState(timezone string) ZiggyState {
 	timeOfDay := GetTimeOfDay(now, timezone)
 
 	return ZiggyState{
+		SchemaVersion:  CurrentSchemaVersion,
 		Fullness:       70,
 		Happiness:      70,
 		Bond:           50,
@@ -343,6 +360,7 @@ func (s *ZiggyState) ToResponse(now time.Time) ZiggyStateResponse {
 		LastAction:   s.LastAction,
 		Age:          age,
 		Generation:   s.Generation,
+		Locale:       s.Locale,
 		FeedCooldown: cooldownRemaining(s.LastFeedTime, s.GetEffectiveCooldown(ActionFeed), now),
 		PlayCooldown: cooldownRemaining(s.LastPlayTime, s.GetEffectiveCooldown(ActionPlay), now),
 		PetCooldown:  cooldownRemaining(s.LastPetTime, s.GetEffectiveCooldown(ActionPet), now),
@@ -381,6 +399,8 @@ func (s *ZiggyState) GetEffectiveCooldown(action Action) time.Duration {
 		return time.Duration(float64(CooldownPlay) * cooldownMultiplier(s.Happiness))
 	case ActionPet:
 		return time.Duration(float64(CooldownPet) * cooldownMultiplier(s.Bond))
+	case ActionChat:
+		return CooldownChat
 	default:
 		return 0
 	}
@@ -425,6 +445,34 @@ func (s *ZiggyState) GetMostUrgentNeed() NeedType {
 	return NeedNone
 }
 
+// NextChange returns the first decay tick after now at which decay alone
+// changes the mood or the most urgent need, or limit if nothing changes
+// before then. At a tick boundary CalculateCurrentState holds no partial
+// tick, so the change is already visible at the returned time.
+func (s *ZiggyState) NextChange(now, limit time.Time) time.Time {
+	if s.HP == 0 {
+		return limit
+	}
+	current := s.CalculateCurrentState(now)
+	mood, need := current.GetMood(), current.GetMostUrgentNeed()
+
+	tick := time.Duration(DecayIntervalSeconds * float64(time.Second))
+	walk := *s
+	for k := 1; ; k++ {
+		walk.applyDecayTick()
+		at := s.LastUpdateTime.Add(time.Duration(k) * tick)
+		if !at.After(now) {
+			continue
+		}
+		if !at.Before(limit) {
+			return limit
+		}
+		if walk.GetMood() != mood || walk.GetMostUrgentNeed() != need {
+			return at
+		}
+	}
+}
+
 // GetMostRecentActionTime returns the most recent time an action was performed
 func (s *ZiggyState) GetMostRecentActionTime() time.Time {
 	latest := s.LastFeedTime